@@ -1,175 +1,57 @@
 package fluentbitconfig
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"regexp"
 	"slices"
 	"strings"
 	"text/tabwriter"
-	"unicode/utf8"
 
 	"github.com/calyptia/go-fluentbit-config/v2/property"
 )
 
 func (c *Config) UnmarshalClassic(text []byte) error {
-	lines := strings.Split(string(text), "\n")
-
-	type Section struct {
-		Kind  SectionKind
-		Props property.Properties
-	}
-
-	var currentSection *Section
-	flushCurrentSection := func() {
-		if currentSection == nil {
-			return
-		}
-
-		c.AddSection(currentSection.Kind, currentSection.Props)
-
-		currentSection = nil
+	doc, err := ParseClassicDocument(text)
+	if err != nil {
+		return err
 	}
 
-	for i, line := range lines {
-		lineNumber := uint(i + 1)
-		if !utf8.ValidString(line) {
-			return NewLinedError("invalid utf8 string", lineNumber)
-		}
-
-		line = strings.TrimSpace(line)
-
-		// ignore empty line
-		if line == "" {
-			continue
-		}
-
-		// ignore comment
-		if strings.HasPrefix(line, "#") {
-			continue
-		}
-
-		// command
-		if strings.HasPrefix(line, "@") {
-			flushCurrentSection()
-
-			line := strings.TrimPrefix(line, "@")
-			cmd, instruction, ok := spaceCut(line)
-			if !ok {
-				return NewLinedError("expected at least two strings separated by a space", lineNumber)
-			}
-
-			switch {
-			case strings.EqualFold(cmd, "INCLUDE"):
-				c.Include(instruction)
-			case strings.EqualFold(cmd, "SET"):
-				key, strValue, _ := strings.Cut(instruction, "=")
-				c.SetEnv(key, anyFromString(strValue))
-			}
-
-			continue
-		}
-
-		// beginning of section
-		if strings.HasPrefix(line, "[") {
-			flushCurrentSection()
-
-			if !strings.HasSuffix(line, "]") {
-				return NewLinedError(`expected section to end with "]"`, lineNumber)
-			}
-
-			sectionName := strings.Trim(line, "[]")
-			sectionName = strings.TrimSpace(sectionName)
-
-			if sectionName == "" {
-				return NewLinedError("expected section name to not be empty", lineNumber)
-			}
-
-			currentSection = &Section{
-				Kind:  SectionKind(strings.ToLower(sectionName)),
-				Props: property.Properties{},
-			}
-
-			continue
-		}
-
-		if currentSection == nil {
-			return NewLinedError(fmt.Sprintf("unexpected entry %q", line), lineNumber)
-		}
+	return doc.decode(c)
+}
 
-		key, strVal, ok := spaceCut(line)
-		if !ok {
-			return NewLinedError("expected at least two strings separated by a space", lineNumber)
-		}
+// classicIndent is the default indentation of classic section entries.
+const classicIndent = "    "
 
-		value := anyFromString(strVal)
-
-		// Case when the property could be repeated. Example:
-		// 	[FILTER]
-		// 		Name record_modifier
-		// 		Match *
-		// 		Record hostname ${HOSTNAME}
-		// 		Record product Awesome_Tool
-		if v, ok := currentSection.Props.Get(key); ok {
-			if s, ok := v.([]any); ok {
-				s = append(s, value)
-				currentSection.Props.Set(key, s)
-			} else {
-				currentSection.Props.Set(key, []any{v, value})
-			}
-		} else {
-			currentSection.Props.Add(key, value)
-		}
+func (c Config) MarshalClassic() ([]byte, error) {
+	var sb strings.Builder
+	if err := c.writeClassic(&sb, classicIndent); err != nil {
+		return nil, err
 	}
 
-	flushCurrentSection()
-
-	return nil
+	return []byte(sb.String()), nil
 }
 
-func (c Config) MarshalClassic() ([]byte, error) {
-	var sb strings.Builder
-
+// writeClassic writes the config in classic format,
+// indenting section entries with the given indent.
+func (c Config) writeClassic(w io.Writer, indent string) error {
 	for _, p := range c.Env {
-		_, err := fmt.Fprintf(&sb, "@SET %s=%s\n", p.Key, stringFromAny(p.Value))
+		_, err := fmt.Fprintf(w, "@SET %s=%s\n", p.Key, stringFromAny(p.Value))
 		if err != nil {
-			return nil, err
+			return err
 		}
 	}
 
 	for _, include := range c.Includes {
-		_, err := fmt.Fprintf(&sb, "@INCLUDE %s\n", include)
+		_, err := fmt.Fprintf(w, "@INCLUDE %s\n", include)
 		if err != nil {
-			return nil, err
+			return err
 		}
 	}
 
 	writeProps := func(kind string, props property.Properties) error {
-		if len(props) == 0 {
-			return nil
-		}
-
-		_, err := fmt.Fprintf(&sb, "[%s]\n", kind)
-		if err != nil {
-			return err
-		}
-
-		tw := tabwriter.NewWriter(&sb, 0, 4, 1, ' ', 0)
-		for _, p := range props {
-			if s, ok := p.Value.([]any); ok {
-				for _, v := range s {
-					_, err := fmt.Fprintf(tw, "    %s\t%s\n", p.Key, stringFromAny(v))
-					if err != nil {
-						return err
-					}
-				}
-			} else {
-				_, err := fmt.Fprintf(tw, "    %s\t%s\n", p.Key, stringFromAny(p.Value))
-				if err != nil {
-					return err
-				}
-			}
-		}
-		return tw.Flush()
+		return writeClassicSection(w, kind, props, indent)
 	}
 
 	writePlugins := func(kind string, plugins Plugins) error {
@@ -187,31 +69,107 @@ func (c Config) MarshalClassic() ([]byte, error) {
 	}
 
 	if err := writeProps("SERVICE", c.Service); err != nil {
-		return nil, err
+		return err
+	}
+
+	if len(c.ExternalPlugins) != 0 {
+		if err := writeProps("PLUGINS", externalPluginsProperties(c.ExternalPlugins)); err != nil {
+			return err
+		}
 	}
 
 	if err := writePlugins("CUSTOM", c.Customs); err != nil {
-		return nil, err
+		return err
 	}
 
 	if err := writePlugins("INPUT", c.Pipeline.Inputs); err != nil {
-		return nil, err
+		return err
 	}
 
 	if err := writePlugins("FILTER", c.Pipeline.Filters); err != nil {
-		return nil, err
+		return err
 	}
 
 	if err := writePlugins("OUTPUT", c.Pipeline.Outputs); err != nil {
-		return nil, err
+		return err
 	}
 
 	// Note: Parsers cannot be combined with other configuration in classic format.
 	if err := writePlugins("PARSER", c.Parsers); err != nil {
-		return nil, err
+		return err
 	}
 
-	return []byte(sb.String()), nil
+	for _, parser := range c.MultilineParsers {
+		props, err := parser.allProperties(true /* classic */)
+		if err != nil {
+			return fmt.Errorf("multiline parser %q: %w", parser.ID, err)
+		}
+		if err := writeProps("MULTILINE_PARSER", props); err != nil {
+			return err
+		}
+	}
+
+	for _, task := range c.StreamTasks {
+		if err := writeProps("STREAM_TASK", task.classicProperties()); err != nil {
+			return err
+		}
+	}
+
+	for _, upstream := range c.Upstreams {
+		kinds, sections, err := upstream.classicSections()
+		if err != nil {
+			return err
+		}
+		for i, props := range sections {
+			if err := writeProps(strings.ToUpper(string(kinds[i])), props); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// externalPluginsProperties of the `[PLUGINS]` section,
+// with one `Path` entry per plugin.
+func externalPluginsProperties(paths []string) property.Properties {
+	values := make([]any, len(paths))
+	for i, path := range paths {
+		values[i] = path
+	}
+	return property.Properties{{Key: "Path", Value: values}}
+}
+
+func writeClassicSection(w io.Writer, kind string, props property.Properties, indent string) error {
+	if len(props) == 0 {
+		return nil
+	}
+
+	_, err := fmt.Fprintf(w, "[%s]\n", kind)
+	if err != nil {
+		return err
+	}
+
+	// The indent is escaped so tabs on it are not taken as cell separators.
+	escape := string([]byte{tabwriter.Escape})
+	indent = escape + indent + escape
+	tw := tabwriter.NewWriter(w, 0, 4, 1, ' ', tabwriter.StripEscape)
+	for _, p := range props {
+		if s, ok := p.Value.([]any); ok {
+			for _, v := range s {
+				_, err := fmt.Fprintf(tw, "%s%s\t%s\n", indent, p.Key, stringFromAny(v))
+				if err != nil {
+					return err
+				}
+			}
+		} else {
+			_, err := fmt.Fprintf(tw, "%s%s\t%s\n", indent, p.Key, stringFromAny(p.Value))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return tw.Flush()
 }
 
 // reSpaces matches more than one consecutive spaces.
@@ -228,7 +186,24 @@ func spaceCut(s string) (before, after string, found bool) {
 }
 
 // allPluginProperties returns a copy of the plugin properties with the name property also included.
+// Processors are included as the `processors` property,
+// which the classic format does not support.
 func allPluginProperties(plugin Plugin, classic bool) (property.Properties, error) {
+	props, err := pluginPropertiesWithName(plugin, classic)
+	if err != nil || plugin.Processors.IsEmpty() {
+		return props, err
+	}
+
+	if classic {
+		return nil, errors.New("processors are not supported in classic format")
+	}
+
+	out := make(property.Properties, 0, len(props)+1)
+	out = append(out, props...)
+	return append(out, property.Property{Key: processorsKey, Value: plugin.Processors}), nil
+}
+
+func pluginPropertiesWithName(plugin Plugin, classic bool) (property.Properties, error) {
 	if plugin.Name == "" {
 		return plugin.Properties, nil
 	}
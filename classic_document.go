@@ -0,0 +1,1126 @@
+package fluentbitconfig
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+// ClassicDocument is the syntax tree of a classic config.
+// Unlike Config, it keeps comments, blank lines, key casing
+// and alignment, so it can be written back byte for byte.
+//
+// Edits are made through the Config API:
+//
+//	doc, err := ParseClassicDocument(text)
+//	cfg := doc.Config()
+//	cfg.Pipeline.Inputs[0].Properties.Set("tag", "foo")
+//	err = doc.SetConfig(cfg)
+//	text = doc.Bytes()
+//
+// Only the lines affected by the edits are rewritten.
+type ClassicDocument struct {
+	// Head holds the lines before the first section.
+	Head     []ClassicLine
+	Sections []*ClassicSection
+	// FinalNewline tells whether the text ends with a new line.
+	FinalNewline bool
+}
+
+// ClassicSection with its header and body lines.
+type ClassicSection struct {
+	Kind SectionKind
+	// Leading lines right before the section header,
+	// usually comments describing the section.
+	Leading []ClassicLine
+	Header  ClassicLine
+	// Body lines after the header up to the next section.
+	// Includes entries, and also the comments, blank lines and
+	// commands in between.
+	Body []ClassicLine
+}
+
+type ClassicLineKind int
+
+const (
+	ClassicLineBlank ClassicLineKind = iota
+	ClassicLineComment
+	ClassicLineCommand
+	ClassicLineHeader
+	ClassicLineEntry
+)
+
+// ClassicLine as found in the document.
+// Key and Value are only set for entries and commands.
+// For commands the key is the command name without the `@` prefix,
+// for example `INCLUDE`.
+type ClassicLine struct {
+	Kind  ClassicLineKind
+	Raw   string
+	Key   string
+	Value string
+	// Line number where it was found when parsed.
+	// Lines added through SetConfig have no line number.
+	Line int
+}
+
+// ParseClassicDocument parses a classic config keeping its layout.
+// It reports the same errors as Config.UnmarshalClassic.
+func ParseClassicDocument(text []byte) (*ClassicDocument, error) {
+	doc, err := parseClassicSections(text)
+	if err != nil {
+		return nil, err
+	}
+
+	// Nodes belong to the upstream defined before them.
+	var upstream bool
+	for _, section := range doc.Sections {
+		switch section.Kind {
+		case SectionKindUpstream:
+			upstream = true
+		case SectionKindNode:
+			if !upstream {
+				return nil, WrapLinedError(errNodeWithoutUpstream, uint(section.Header.Line))
+			}
+		}
+	}
+
+	return doc, nil
+}
+
+// parseClassicSections parses the lines and sections of a classic config
+// without checking how sections relate to each other.
+func parseClassicSections(text []byte) (*ClassicDocument, error) {
+	doc := &ClassicDocument{}
+	inSection := false
+
+	rawLines := strings.Split(string(text), "\n")
+	if len(rawLines) > 1 && rawLines[len(rawLines)-1] == "" {
+		rawLines = rawLines[:len(rawLines)-1]
+		doc.FinalNewline = true
+	}
+
+	for i, raw := range rawLines {
+		lineNumber := uint(i + 1)
+		if !utf8.ValidString(raw) {
+			return nil, NewLinedError("invalid utf8 string", lineNumber)
+		}
+
+		line, err := parseClassicLine(raw, inSection)
+		if err != nil {
+			return nil, WrapLinedError(err, lineNumber)
+		}
+
+		line.Line = int(lineNumber)
+
+		switch line.Kind {
+		case ClassicLineHeader:
+			section := &ClassicSection{
+				Kind:   classicSectionKind(line.Value),
+				Header: line,
+			}
+
+			// Comments right before the header belong to the section.
+			lines := doc.lastLines()
+			n := len(*lines)
+			for n > 0 && (*lines)[n-1].Kind == ClassicLineComment {
+				n--
+			}
+			section.Leading = slices.Clone((*lines)[n:])
+			*lines = (*lines)[:n]
+
+			doc.Sections = append(doc.Sections, section)
+			inSection = true
+			continue
+		case ClassicLineCommand:
+			inSection = false
+		case ClassicLineEntry:
+			// Multiline parser rules are made of multiple quoted strings,
+			// so they are validated here and kept as is. Example:
+			// 	[MULTILINE_PARSER]
+			// 		Name multiline-regex-test
+			// 		Type regex
+			// 		Rule "start_state" "/^\d+/" "cont"
+			section := doc.Sections[len(doc.Sections)-1]
+			if section.Kind == SectionKindMultilineParser && strings.EqualFold(line.Key, "rule") {
+				if _, err := ParseMultilineRule(line.Value); err != nil {
+					return nil, WrapLinedError(err, lineNumber)
+				}
+			}
+		}
+
+		lines := doc.lastLines()
+		*lines = append(*lines, line)
+	}
+
+	return doc, nil
+}
+
+func parseClassicLine(raw string, inSection bool) (ClassicLine, error) {
+	out := ClassicLine{Raw: raw}
+	line := strings.TrimSpace(raw)
+
+	switch {
+	case line == "":
+		out.Kind = ClassicLineBlank
+	case strings.HasPrefix(line, "#"):
+		out.Kind = ClassicLineComment
+	case strings.HasPrefix(line, "@"):
+		cmd, instruction, ok := spaceCut(strings.TrimPrefix(line, "@"))
+		if !ok {
+			return out, fmt.Errorf("expected at least two strings separated by a space")
+		}
+
+		out.Kind = ClassicLineCommand
+		out.Key = cmd
+		out.Value = instruction
+	case strings.HasPrefix(line, "["):
+		if !strings.HasSuffix(line, "]") {
+			return out, fmt.Errorf(`expected section to end with "]"`)
+		}
+
+		sectionName := strings.Trim(line, "[]")
+		sectionName = strings.TrimSpace(sectionName)
+
+		if sectionName == "" {
+			return out, fmt.Errorf("expected section name to not be empty")
+		}
+
+		out.Kind = ClassicLineHeader
+		out.Value = sectionName
+	default:
+		if !inSection {
+			return out, fmt.Errorf("unexpected entry %q", line)
+		}
+
+		key, value, ok := spaceCut(line)
+		if !ok {
+			return out, fmt.Errorf("expected at least two strings separated by a space")
+		}
+
+		out.Kind = ClassicLineEntry
+		out.Key = key
+		out.Value = value
+	}
+
+	return out, nil
+}
+
+func classicSectionKind(name string) SectionKind {
+	return SectionKind(strings.ToLower(name))
+}
+
+// lastLines returns the lines where the next line should go.
+func (doc *ClassicDocument) lastLines() *[]ClassicLine {
+	if len(doc.Sections) == 0 {
+		return &doc.Head
+	}
+
+	return &doc.Sections[len(doc.Sections)-1].Body
+}
+
+// Bytes of the document.
+// An untouched document returns the exact same text it was parsed from.
+func (doc *ClassicDocument) Bytes() []byte {
+	return []byte(doc.String())
+}
+
+func (doc *ClassicDocument) String() string {
+	var lines []string
+	add := func(ll []ClassicLine) {
+		for _, l := range ll {
+			lines = append(lines, l.Raw)
+		}
+	}
+
+	add(doc.Head)
+	for _, section := range doc.Sections {
+		add(section.Leading)
+		lines = append(lines, section.Header.Raw)
+		add(section.Body)
+	}
+
+	out := strings.Join(lines, "\n")
+	if doc.FinalNewline {
+		out += "\n"
+	}
+	return out
+}
+
+// Config decoded from the document.
+// Documents from ParseClassicDocument always decode,
+// as it already reports malformed sections.
+func (doc *ClassicDocument) Config() Config {
+	var out Config
+	_ = doc.decode(&out)
+	return out
+}
+
+func (doc *ClassicDocument) decode(c *Config) error {
+	commands := func(lines []ClassicLine) {
+		for _, line := range lines {
+			if line.Kind != ClassicLineCommand {
+				continue
+			}
+
+			switch {
+			case strings.EqualFold(line.Key, "INCLUDE"):
+				c.Include(line.Value)
+			case strings.EqualFold(line.Key, "SET"):
+				key, strValue, _ := strings.Cut(line.Value, "=")
+				if c.Env == nil {
+					c.Env = property.Properties{}
+				}
+				setProperty(&c.Env, property.Property{Key: key, Value: anyFromString(strValue), Pos: line.Pos()})
+			}
+		}
+	}
+
+	commands(doc.Head)
+	for _, section := range doc.Sections {
+		commands(section.Leading)
+		if err := c.addSection(section.Kind, section.Properties(), section.Header.Pos()); err != nil {
+			return WrapLinedError(err, uint(section.Header.Line))
+		}
+		commands(section.Body)
+	}
+
+	return nil
+}
+
+// Properties of the section entries.
+func (s *ClassicSection) Properties() property.Properties {
+	props := property.Properties{}
+	for _, line := range s.Body {
+		if line.Kind != ClassicLineEntry {
+			continue
+		}
+
+		value := s.entryValue(line)
+
+		// Case when the property could be repeated. Example:
+		// 	[FILTER]
+		// 		Name record_modifier
+		// 		Match *
+		// 		Record hostname ${HOSTNAME}
+		// 		Record product Awesome_Tool
+		if v, ok := props.Get(line.Key); ok {
+			if s, ok := v.([]any); ok {
+				s = append(s, value)
+				props.Set(line.Key, s)
+			} else {
+				props.Set(line.Key, []any{v, value})
+			}
+		} else {
+			props = append(props, property.Property{Key: line.Key, Value: value, Pos: line.Pos()})
+		}
+	}
+
+	return props
+}
+
+// Pos of the first non-space character of the line.
+func (line ClassicLine) Pos() property.Position {
+	if line.Line == 0 {
+		return property.Position{}
+	}
+
+	indent, _ := splitIndent(line.Raw)
+	return property.Position{Line: line.Line, Column: len(indent) + 1}
+}
+
+func (s *ClassicSection) entryValue(line ClassicLine) any {
+	if s.Kind == SectionKindMultilineParser && strings.EqualFold(line.Key, "rule") {
+		return line.Value
+	}
+
+	return anyFromString(line.Value)
+}
+
+// classicSectionOrder is the order in which section kinds
+// are written by Config.MarshalClassic.
+// New sections added through ClassicDocument.SetConfig
+// are placed following it.
+var classicSectionOrder = []SectionKind{
+	SectionKindService,
+	SectionKindPlugins,
+	SectionKindCustom,
+	SectionKindInput,
+	SectionKindFilter,
+	SectionKindOutput,
+	SectionKindParser,
+	SectionKindMultilineParser,
+	SectionKindStreamTask,
+	SectionKindUpstream,
+	SectionKindNode,
+}
+
+// SetConfig updates the document so it matches the given config.
+// Sections and lines whose values did not change are kept untouched,
+// including their comments and alignment.
+// Plugin sections are matched against the document ones in order,
+// new ones are written in the same layout as Config.MarshalClassic.
+func (doc *ClassicDocument) SetConfig(cfg Config) error {
+	defer doc.keepTrailingBlank(doc.trailingBlank())
+
+	doc.setEnv(cfg.Env)
+	doc.setIncludes(cfg.Includes)
+
+	for _, kind := range classicSectionOrder {
+		var desired []property.Properties
+		switch kind {
+		case SectionKindService:
+			if len(cfg.Service) != 0 {
+				desired = append(desired, cfg.Service)
+			}
+		case SectionKindPlugins:
+			if len(cfg.ExternalPlugins) != 0 {
+				desired = append(desired, externalPluginsProperties(cfg.ExternalPlugins))
+			}
+		case SectionKindStreamTask:
+			for _, task := range cfg.StreamTasks {
+				desired = append(desired, task.classicProperties())
+			}
+		case SectionKindUpstream:
+			if err := doc.setUpstreamSections(cfg.Upstreams); err != nil {
+				return err
+			}
+			continue
+		case SectionKindNode:
+			continue
+		case SectionKindMultilineParser:
+			for _, parser := range cfg.MultilineParsers {
+				props, err := parser.allProperties(true /* classic */)
+				if err != nil {
+					return fmt.Errorf("multiline parser %q: %w", parser.ID, err)
+				}
+				desired = append(desired, props)
+			}
+		default:
+			for _, plugin := range classicPlugins(cfg, kind) {
+				props, err := allPluginProperties(plugin, true /* classic */)
+				if err != nil {
+					return fmt.Errorf("plugin %q: %w", plugin.ID, err)
+				}
+				desired = append(desired, props)
+			}
+		}
+
+		if err := doc.setSections(kind, desired); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func classicPlugins(cfg Config, kind SectionKind) Plugins {
+	switch kind {
+	case SectionKindCustom:
+		return cfg.Customs
+	case SectionKindInput:
+		return cfg.Pipeline.Inputs
+	case SectionKindFilter:
+		return cfg.Pipeline.Filters
+	case SectionKindOutput:
+		return cfg.Pipeline.Outputs
+	case SectionKindParser:
+		return cfg.Parsers
+	}
+	return nil
+}
+
+// setSections updates the sections of the given kind.
+// Sections equal to the desired ones are kept, the ones that changed
+// are patched in place, and the rest are removed or added.
+func (doc *ClassicDocument) setSections(kind SectionKind, desired []property.Properties) error {
+	var slots []int
+	var current []*ClassicSection
+	for i, section := range doc.Sections {
+		if section.Kind == kind {
+			slots = append(slots, i)
+			current = append(current, section)
+		}
+	}
+
+	// Multiple service sections are merged into one,
+	// so they are patched as a whole.
+	if kind == SectionKindService && len(current) > 1 {
+		return doc.setServiceSections(current, desired)
+	}
+
+	currentKeys := make([][]string, len(current))
+	for i, section := range current {
+		currentKeys[i] = classicPropsKey(kind, section.Properties())
+	}
+
+	desiredKeys := make([][]string, len(desired))
+	for i, props := range desired {
+		desiredKeys[i] = classicPropsKey(kind, props)
+	}
+
+	matches := lcsMatches(currentKeys, desiredKeys)
+	used := make([]bool, len(current))
+	for _, i := range matches {
+		if i >= 0 {
+			used[i] = true
+		}
+	}
+
+	next := make([]*ClassicSection, len(desired))
+	for j, i := range matches {
+		if i >= 0 {
+			next[j] = current[i]
+		}
+	}
+
+	// Sections that were moved around are reused as they are.
+	for j := range desired {
+		if next[j] != nil {
+			continue
+		}
+
+		for i := range current {
+			if !used[i] && slices.Equal(currentKeys[i], desiredKeys[j]) {
+				used[i] = true
+				next[j] = current[i]
+				break
+			}
+		}
+	}
+
+	// Sections that changed are patched in place
+	// if they are still between the same neighbours.
+	for j := range desired {
+		if next[j] != nil {
+			continue
+		}
+
+		lo, hi := -1, len(current)
+		for k := j - 1; k >= 0; k-- {
+			if matches[k] >= 0 {
+				lo = matches[k]
+				break
+			}
+		}
+		for k := j + 1; k < len(desired); k++ {
+			if matches[k] >= 0 {
+				hi = matches[k]
+				break
+			}
+		}
+
+		for i := lo + 1; i < hi; i++ {
+			if used[i] || Name(current[i].Properties()) != Name(desired[j]) {
+				continue
+			}
+
+			used[i] = true
+			current[i].patch(desired[j])
+			next[j] = current[i]
+			break
+		}
+	}
+
+	added := map[*ClassicSection]bool{}
+	for j := range desired {
+		if next[j] != nil {
+			continue
+		}
+
+		section, err := newClassicSection(kind, desired[j])
+		if err != nil {
+			return err
+		}
+
+		next[j] = section
+		added[section] = true
+	}
+
+	blankSeparated := doc.blankSeparated()
+	doc.placeSections(kind, slots, next)
+
+	if blankSeparated {
+		doc.separate(added)
+	}
+
+	return nil
+}
+
+// trailingBlank tells whether the last section ends with a blank line.
+func (doc *ClassicDocument) trailingBlank() bool {
+	if len(doc.Sections) == 0 {
+		return false
+	}
+
+	body := doc.Sections[len(doc.Sections)-1].Body
+	return len(body) != 0 && body[len(body)-1].Kind == ClassicLineBlank
+}
+
+// keepTrailingBlank removes the blank lines a section that used to be
+// followed by others leaves at the end of the document.
+func (doc *ClassicDocument) keepTrailingBlank(blank bool) {
+	if blank || len(doc.Sections) == 0 {
+		return
+	}
+
+	last := doc.Sections[len(doc.Sections)-1]
+	for len(last.Body) != 0 && last.Body[len(last.Body)-1].Kind == ClassicLineBlank {
+		last.Body = last.Body[:len(last.Body)-1]
+	}
+}
+
+// blankSeparated tells whether sections are separated by blank lines.
+func (doc *ClassicDocument) blankSeparated() bool {
+	if len(doc.Sections) < 2 {
+		return false
+	}
+
+	for _, section := range doc.Sections[:len(doc.Sections)-1] {
+		if n := len(section.Body); n == 0 || section.Body[n-1].Kind != ClassicLineBlank {
+			return false
+		}
+	}
+
+	return true
+}
+
+// separate the given sections from their neighbours with blank lines.
+func (doc *ClassicDocument) separate(sections map[*ClassicSection]bool) {
+	blank := ClassicLine{Kind: ClassicLineBlank}
+	for i, section := range doc.Sections {
+		if !sections[section] {
+			continue
+		}
+
+		if i > 0 {
+			prev := doc.Sections[i-1]
+			if n := len(prev.Body); n != 0 && prev.Body[n-1].Kind != ClassicLineBlank {
+				section.Leading = append([]ClassicLine{blank}, section.Leading...)
+			}
+		}
+
+		if i < len(doc.Sections)-1 {
+			section.Body = append(section.Body, blank)
+		}
+	}
+}
+
+// setUpstreamSections updates the upstream sections along with their nodes.
+// As nodes belong to the upstream before them, they are not matched
+// on their own: when upstreams changed, all their sections are written again,
+// reusing the ones that did not change.
+func (doc *ClassicDocument) setUpstreamSections(upstreams Upstreams) error {
+	isUpstream := func(s *ClassicSection) bool {
+		return s.Kind == SectionKindUpstream || s.Kind == SectionKindNode
+	}
+
+	var current Config
+	var currentSections []*ClassicSection
+	insertAt := -1
+	for i, section := range doc.Sections {
+		if !isUpstream(section) {
+			continue
+		}
+
+		if insertAt == -1 {
+			insertAt = i
+		}
+		currentSections = append(currentSections, section)
+		current.addSection(section.Kind, section.Properties(), property.Position{})
+	}
+
+	if current.Upstreams.Equal(upstreams) {
+		return nil
+	}
+
+	used := make([]bool, len(currentSections))
+	var next []*ClassicSection
+	added := map[*ClassicSection]bool{}
+	for _, upstream := range upstreams {
+		kinds, sections, err := upstream.classicSections()
+		if err != nil {
+			return err
+		}
+
+		for j, props := range sections {
+			key := classicPropsKey(kinds[j], props)
+			idx := -1
+			for i, s := range currentSections {
+				if !used[i] && s.Kind == kinds[j] && slices.Equal(classicPropsKey(s.Kind, s.Properties()), key) {
+					idx = i
+					break
+				}
+			}
+
+			if idx >= 0 {
+				used[idx] = true
+				next = append(next, currentSections[idx])
+				continue
+			}
+
+			section, err := newClassicSection(kinds[j], props)
+			if err != nil {
+				return err
+			}
+
+			next = append(next, section)
+			added[section] = true
+		}
+	}
+
+	blankSeparated := doc.blankSeparated()
+
+	if insertAt == -1 {
+		insertAt = len(doc.Sections)
+	}
+	rest := slices.DeleteFunc(slices.Clone(doc.Sections), isUpstream)
+	doc.Sections = slices.Insert(rest, insertAt, next...)
+
+	if blankSeparated {
+		doc.separate(added)
+	}
+
+	return nil
+}
+
+func (doc *ClassicDocument) setServiceSections(current []*ClassicSection, desired []property.Properties) error {
+	var props property.Properties
+	if len(desired) != 0 {
+		props = desired[0]
+	}
+
+	// Only the last section gets the new entries.
+	for i, section := range current {
+		section.patchExisting(props)
+		if i == len(current)-1 {
+			section.patchMissing(props)
+		}
+	}
+
+	if len(props) == 0 {
+		doc.Sections = slices.DeleteFunc(doc.Sections, func(s *ClassicSection) bool {
+			return s.Kind == SectionKindService && len(s.Properties()) == 0
+		})
+	}
+
+	return nil
+}
+
+// placeSections puts the given sections in the slots where
+// the previous sections of the same kind were.
+// Extra sections go right after the last slot or, if there are none,
+// after the last section of a kind written before in classicSectionOrder.
+func (doc *ClassicDocument) placeSections(kind SectionKind, slots []int, sections []*ClassicSection) {
+	var out []*ClassicSection
+	var n int
+
+	insertAt := -1
+	if len(slots) != 0 {
+		insertAt = slots[len(slots)-1]
+	} else {
+		order := slices.Index(classicSectionOrder, kind)
+		for i, section := range doc.Sections {
+			if o := slices.Index(classicSectionOrder, section.Kind); o >= 0 && o < order {
+				insertAt = i
+			}
+		}
+	}
+
+	if insertAt == -1 {
+		out = append(out, sections...)
+		n = len(sections)
+	}
+
+	for i, section := range doc.Sections {
+		if section.Kind != kind {
+			out = append(out, section)
+		} else if n < len(sections) {
+			out = append(out, sections[n])
+			n++
+		}
+
+		if i == insertAt {
+			out = append(out, sections[n:]...)
+			n = len(sections)
+		}
+	}
+
+	doc.Sections = out
+}
+
+func newClassicSection(kind SectionKind, props property.Properties) (*ClassicSection, error) {
+	var sb strings.Builder
+	if err := writeClassicSection(&sb, strings.ToUpper(string(kind)), props, classicIndent); err != nil {
+		return nil, err
+	}
+
+	doc, err := parseClassicSections([]byte(strings.TrimSuffix(sb.String(), "\n")))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(doc.Sections) != 1 {
+		return nil, fmt.Errorf("%s: could not write section", kind)
+	}
+
+	return doc.Sections[0], nil
+}
+
+// patch the section entries to match the given properties.
+func (s *ClassicSection) patch(props property.Properties) {
+	s.patchExisting(props)
+	s.patchMissing(props)
+}
+
+// patchExisting updates or removes the existing entries.
+// Repeated entries are matched in order.
+func (s *ClassicSection) patchExisting(props property.Properties) {
+	seen := map[string]int{}
+	body := s.Body[:0:0]
+	for _, line := range s.Body {
+		if line.Kind != ClassicLineEntry {
+			body = append(body, line)
+			continue
+		}
+
+		key := strings.ToLower(line.Key)
+		values := classicValues(props, line.Key)
+		idx := seen[key]
+		seen[key]++
+		if idx >= len(values) {
+			continue
+		}
+
+		if !s.sameValue(line, values[idx]) {
+			line = line.withValue(stringFromAny(values[idx]))
+		}
+		body = append(body, line)
+	}
+	s.Body = body
+}
+
+// patchMissing adds the entries not yet present on the section.
+func (s *ClassicSection) patchMissing(props property.Properties) {
+	for _, p := range props {
+		var count, last int = 0, -1
+		for i, line := range s.Body {
+			if line.Kind != ClassicLineEntry {
+				continue
+			}
+			if strings.EqualFold(line.Key, p.Key) {
+				count++
+				last = i
+			}
+		}
+
+		values := classicValues(props, p.Key)
+		if count >= len(values) {
+			continue
+		}
+
+		if last == -1 {
+			last = s.lastEntry()
+		}
+
+		var lines []ClassicLine
+		for _, v := range values[count:] {
+			lines = append(lines, s.newEntry(p.Key, stringFromAny(v)))
+		}
+
+		s.Body = slices.Insert(s.Body, last+1, lines...)
+	}
+}
+
+func (s *ClassicSection) lastEntry() int {
+	last := -1
+	for i, line := range s.Body {
+		if line.Kind == ClassicLineEntry {
+			last = i
+		}
+	}
+	return last
+}
+
+// newEntry formats a new entry following the indentation of the section
+// and aligning its value with the other entries when they are aligned.
+func (s *ClassicSection) newEntry(key, value string) ClassicLine {
+	indent := "    "
+	column := -1
+	for _, line := range s.Body {
+		if line.Kind != ClassicLineEntry {
+			continue
+		}
+
+		lineIndent, rest := splitIndent(line.Raw)
+		c := len(line.Raw) - len(rest) + len(line.Key) + len(leadingSpace(rest[len(line.Key):]))
+		if column == -1 {
+			indent = lineIndent
+			column = c
+		} else if column != c {
+			column = 0
+		}
+	}
+
+	sep := " "
+	if pad := column - len(indent) - len(key); pad > 0 {
+		sep = strings.Repeat(" ", pad)
+	}
+
+	return ClassicLine{
+		Kind:  ClassicLineEntry,
+		Raw:   indent + key + sep + value,
+		Key:   key,
+		Value: value,
+	}
+}
+
+// sameValue reports whether the entry already holds the given value
+// once written in classic format.
+func (s *ClassicSection) sameValue(line ClassicLine, value any) bool {
+	if s.Kind == SectionKindMultilineParser && strings.EqualFold(line.Key, "rule") {
+		a, errA := ParseMultilineRule(line.Value)
+		b, errB := ParseMultilineRule(stringFromAny(value))
+		return errA == nil && errB == nil && a == b
+	}
+
+	return stringFromAny(s.entryValue(line)) == stringFromAny(value)
+}
+
+// withValue returns the line with its value replaced,
+// keeping everything around it untouched.
+func (line ClassicLine) withValue(value string) ClassicLine {
+	idx := strings.LastIndex(line.Raw, line.Value)
+	line.Raw = line.Raw[:idx] + value + line.Raw[idx+len(line.Value):]
+	line.Value = value
+	return line
+}
+
+func splitIndent(s string) (indent, rest string) {
+	rest = strings.TrimLeft(s, " \t")
+	return s[:len(s)-len(rest)], rest
+}
+
+func leadingSpace(s string) string {
+	indent, _ := splitIndent(s)
+	return indent
+}
+
+// classicValues returns the values of the given key,
+// with slices expanded as they are written as repeated entries.
+func classicValues(props property.Properties, key string) []any {
+	var out []any
+	for _, p := range props {
+		if !strings.EqualFold(p.Key, key) {
+			continue
+		}
+
+		if s, ok := p.Value.([]any); ok {
+			out = append(out, s...)
+		} else {
+			out = append(out, p.Value)
+		}
+	}
+	return out
+}
+
+// classicPropsKey returns a comparable representation of the properties
+// as they would be written in classic format.
+func classicPropsKey(kind SectionKind, props property.Properties) []string {
+	var out []string
+	for _, p := range props {
+		key := strings.ToLower(p.Key)
+		for _, v := range classicValues(property.Properties{p}, p.Key) {
+			s := stringFromAny(v)
+			if kind == SectionKindMultilineParser && key == "rule" {
+				if rule, err := ParseMultilineRule(s); err == nil {
+					s = rule.String()
+				}
+			}
+			out = append(out, key, s)
+		}
+	}
+	return out
+}
+
+// lcsMatches computes the longest common subsequence between a and b,
+// returning for each item of b the index of its match in a, or -1.
+func lcsMatches(a, b [][]string) []int {
+	dp := make([][]int, len(a)+1)
+	for i := range dp {
+		dp[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if slices.Equal(a[i], b[j]) {
+				dp[i][j] = dp[i+1][j+1] + 1
+			} else {
+				dp[i][j] = max(dp[i+1][j], dp[i][j+1])
+			}
+		}
+	}
+
+	out := make([]int, len(b))
+	for j := range out {
+		out[j] = -1
+	}
+
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case slices.Equal(a[i], b[j]):
+			out[j] = i
+			i++
+			j++
+		case dp[i+1][j] >= dp[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	return out
+}
+
+func (doc *ClassicDocument) commandLines() []*ClassicLine {
+	var out []*ClassicLine
+	add := func(lines []ClassicLine) {
+		for i := range lines {
+			if lines[i].Kind == ClassicLineCommand {
+				out = append(out, &lines[i])
+			}
+		}
+	}
+
+	add(doc.Head)
+	for _, section := range doc.Sections {
+		add(section.Leading)
+		add(section.Body)
+	}
+	return out
+}
+
+// removeLines drops the lines for which the function returns true.
+func (doc *ClassicDocument) removeLines(fn func(ClassicLine) bool) {
+	doc.Head = slices.DeleteFunc(doc.Head, fn)
+	for _, section := range doc.Sections {
+		section.Leading = slices.DeleteFunc(section.Leading, fn)
+		section.Body = slices.DeleteFunc(section.Body, fn)
+	}
+}
+
+// insertCommands adds the given lines after the last command
+// matching the function, or at the end of the head.
+func (doc *ClassicDocument) insertCommands(lines []ClassicLine, after func(ClassicLine) bool) {
+	if len(lines) == 0 {
+		return
+	}
+
+	insert := func(dst *[]ClassicLine) bool {
+		for i := len(*dst) - 1; i >= 0; i-- {
+			if (*dst)[i].Kind == ClassicLineCommand && after((*dst)[i]) {
+				*dst = slices.Insert(*dst, i+1, lines...)
+				return true
+			}
+		}
+		return false
+	}
+
+	for i := len(doc.Sections) - 1; i >= 0; i-- {
+		if insert(&doc.Sections[i].Body) || insert(&doc.Sections[i].Leading) {
+			return
+		}
+	}
+
+	if insert(&doc.Head) {
+		return
+	}
+
+	doc.Head = append(doc.Head, lines...)
+}
+
+func isCommand(name string) func(ClassicLine) bool {
+	return func(line ClassicLine) bool {
+		return line.Kind == ClassicLineCommand && strings.EqualFold(line.Key, name)
+	}
+}
+
+func (doc *ClassicDocument) setEnv(env property.Properties) {
+	seen := map[string]bool{}
+	for _, line := range doc.commandLines() {
+		if !isCommand("SET")(*line) {
+			continue
+		}
+
+		key, strValue, _ := strings.Cut(line.Value, "=")
+		value, ok := env.Get(key)
+		if !ok {
+			continue
+		}
+
+		seen[strings.ToLower(key)] = true
+		if stringFromAny(anyFromString(strValue)) == stringFromAny(value) {
+			continue
+		}
+
+		*line = line.withValue(key + "=" + stringFromAny(value))
+	}
+
+	doc.removeLines(func(line ClassicLine) bool {
+		if !isCommand("SET")(line) {
+			return false
+		}
+		key, _, _ := strings.Cut(line.Value, "=")
+		return !env.Has(key)
+	})
+
+	var lines []ClassicLine
+	for _, p := range env {
+		if seen[strings.ToLower(p.Key)] {
+			continue
+		}
+
+		value := p.Key + "=" + stringFromAny(p.Value)
+		lines = append(lines, ClassicLine{
+			Kind:  ClassicLineCommand,
+			Raw:   "@SET " + value,
+			Key:   "SET",
+			Value: value,
+		})
+	}
+
+	doc.insertCommands(lines, isCommand("SET"))
+}
+
+func (doc *ClassicDocument) setIncludes(includes []string) {
+	remaining := slices.Clone(includes)
+	doc.removeLines(func(line ClassicLine) bool {
+		if !isCommand("INCLUDE")(line) {
+			return false
+		}
+
+		if i := slices.Index(remaining, line.Value); i >= 0 {
+			remaining = slices.Delete(remaining, i, i+1)
+			return false
+		}
+
+		return true
+	})
+
+	var lines []ClassicLine
+	for _, include := range remaining {
+		lines = append(lines, ClassicLine{
+			Kind:  ClassicLineCommand,
+			Raw:   "@INCLUDE " + include,
+			Key:   "INCLUDE",
+			Value: include,
+		})
+	}
+
+	doc.insertCommands(lines, func(line ClassicLine) bool {
+		return isCommand("INCLUDE")(line) || isCommand("SET")(line)
+	})
+}
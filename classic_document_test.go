@@ -0,0 +1,202 @@
+package fluentbitconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+const classicDocumentText = `# Global settings.
+@SET   env=prod
+@INCLUDE common.conf
+
+[SERVICE]
+    Flush      5
+    Log_Level  info
+
+# Tail container logs.
+[INPUT]
+    Name   tail
+    Path   /var/log/containers/*.log
+    Tag    kube.*
+    # Keep the database on the host.
+    DB     /var/lib/fluent-bit/tail.db
+
+[INPUT]
+    Name   cpu
+
+[FILTER]
+    Name           record_modifier
+    Match          *
+    Record         hostname ${HOSTNAME}
+    Record         product  Awesome_Tool
+
+[OUTPUT]
+    Name   stdout
+    Match  *
+# trailing comment
+`
+
+func TestParseClassicDocument(t *testing.T) {
+	t.Run("round_trip_testdata", func(t *testing.T) {
+		names, err := filepath.Glob("testdata/*.conf")
+		require.NoError(t, err)
+
+		for _, name := range names {
+			t.Run(makeTestName(name), func(t *testing.T) {
+				text, err := os.ReadFile(name)
+				require.NoError(t, err)
+
+				doc, err := ParseClassicDocument(text)
+				require.NoError(t, err)
+				require.Equal(t, string(text), string(doc.Bytes()))
+
+				var want Config
+				require.NoError(t, want.UnmarshalClassic(text))
+				require.Equal(t, want, doc.Config())
+			})
+		}
+	})
+
+	t.Run("round_trip_comments", func(t *testing.T) {
+		doc, err := ParseClassicDocument([]byte(classicDocumentText))
+		require.NoError(t, err)
+		require.Equal(t, classicDocumentText, doc.String())
+
+		require.Len(t, doc.Sections, 5)
+		require.Equal(t, []ClassicLine{
+			{Kind: ClassicLineComment, Raw: "# Tail container logs.", Line: 9},
+		}, doc.Sections[1].Leading)
+	})
+
+	t.Run("round_trip_crlf", func(t *testing.T) {
+		text := "[INPUT]\r\n    Name  cpu \r\n\r\n"
+		doc, err := ParseClassicDocument([]byte(text))
+		require.NoError(t, err)
+		require.Equal(t, text, doc.String())
+		require.Equal(t, "cpu", doc.Config().Pipeline.Inputs[0].Name)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		_, err := ParseClassicDocument([]byte("[INPUT]\n    Name cpu\n@INCLUDE foo\n    Tag bar\n"))
+		require.EqualError(t, err, `4: unexpected entry "Tag bar"`)
+	})
+}
+
+func TestClassicDocument_SetConfig(t *testing.T) {
+	edit := func(t *testing.T, fn func(cfg *Config)) string {
+		t.Helper()
+
+		doc, err := ParseClassicDocument([]byte(classicDocumentText))
+		require.NoError(t, err)
+
+		cfg := doc.Config()
+		fn(&cfg)
+
+		require.NoError(t, doc.SetConfig(cfg))
+
+		// The resulting document must decode to the edited config.
+		got, err := ParseClassicDocument(doc.Bytes())
+		require.NoError(t, err)
+		require.True(t, cfg.Equal(got.Config()), "edited document does not match config:\n%s", doc.String())
+
+		return doc.String()
+	}
+
+	t.Run("untouched", func(t *testing.T) {
+		got := edit(t, func(cfg *Config) {})
+		require.Equal(t, classicDocumentText, got)
+	})
+
+	t.Run("change_value", func(t *testing.T) {
+		got := edit(t, func(cfg *Config) {
+			cfg.Pipeline.Inputs[0].Properties.Set("tag", "containers.*")
+			cfg.Service.Set("log_level", "debug")
+		})
+		require.Equal(t, replaceOnce(t, classicDocumentText,
+			"    Tag    kube.*\n", "    Tag    containers.*\n",
+			"    Log_Level  info\n", "    Log_Level  debug\n",
+		), got)
+	})
+
+	t.Run("add_and_remove_properties", func(t *testing.T) {
+		got := edit(t, func(cfg *Config) {
+			cfg.Pipeline.Inputs[0].Properties = append(cfg.Pipeline.Inputs[0].Properties[:3], cfg.Pipeline.Inputs[0].Properties[4:]...)
+			cfg.Pipeline.Inputs[0].Properties.Add("Refresh_Interval", int64(10))
+			cfg.Pipeline.Filters[0].Properties.Set("Record", "hostname ${HOSTNAME}")
+		})
+		require.Equal(t, replaceOnce(t, classicDocumentText,
+			"    Tag    kube.*\n    # Keep the database on the host.\n    DB     /var/lib/fluent-bit/tail.db\n",
+			"    Tag    kube.*\n    Refresh_Interval 10\n    # Keep the database on the host.\n",
+			"    Record         product  Awesome_Tool\n", "",
+		), got)
+	})
+
+	t.Run("remove_plugin_with_comments", func(t *testing.T) {
+		got := edit(t, func(cfg *Config) {
+			cfg.Pipeline.Inputs = cfg.Pipeline.Inputs[1:]
+		})
+		require.Equal(t, replaceOnce(t, classicDocumentText,
+			"# Tail container logs.\n[INPUT]\n    Name   tail\n    Path   /var/log/containers/*.log\n    Tag    kube.*\n    # Keep the database on the host.\n    DB     /var/lib/fluent-bit/tail.db\n\n", "",
+		), got)
+	})
+
+	t.Run("add_plugin", func(t *testing.T) {
+		got := edit(t, func(cfg *Config) {
+			cfg.AddSection(SectionKindInput, property.Properties{{Key: "name", Value: "mem"}})
+			cfg.AddSection(SectionKindParser, property.Properties{
+				{Key: "name", Value: "json"},
+				{Key: "format", Value: "json"},
+			})
+		})
+		require.Equal(t, replaceOnce(t, classicDocumentText,
+			"[INPUT]\n    Name   cpu\n\n",
+			"[INPUT]\n    Name   cpu\n\n[INPUT]\n    name mem\n\n",
+		)+"\n[PARSER]\n    name   json\n    format json\n", got)
+	})
+
+	t.Run("move_plugin", func(t *testing.T) {
+		got := edit(t, func(cfg *Config) {
+			inputs := cfg.Pipeline.Inputs
+			cfg.Pipeline.Inputs = Plugins{inputs[1], inputs[0]}
+		})
+		require.Contains(t, got, "[INPUT]\n    Name   cpu\n\n# Tail container logs.\n[INPUT]\n    Name   tail\n")
+	})
+
+	t.Run("commands", func(t *testing.T) {
+		got := edit(t, func(cfg *Config) {
+			cfg.SetEnv("env", "staging")
+			cfg.SetEnv("region", "eu")
+			cfg.Includes = []string{"other.conf"}
+		})
+		require.Equal(t, replaceOnce(t, classicDocumentText,
+			"@SET   env=prod\n@INCLUDE common.conf\n",
+			"@SET   env=staging\n@SET region=eu\n@INCLUDE other.conf\n",
+		), got)
+	})
+
+	t.Run("remove_service", func(t *testing.T) {
+		got := edit(t, func(cfg *Config) {
+			cfg.Service = nil
+		})
+		require.Equal(t, replaceOnce(t, classicDocumentText,
+			"[SERVICE]\n    Flush      5\n    Log_Level  info\n\n", "",
+		), got)
+	})
+}
+
+func replaceOnce(t *testing.T, s string, oldNew ...string) string {
+	t.Helper()
+
+	for i := 0; i < len(oldNew); i += 2 {
+		before := s
+		s = strings.Replace(s, oldNew[i], oldNew[i+1], 1)
+		require.NotEqual(t, before, s, "%q not found", oldNew[i])
+	}
+	return s
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -12,6 +14,21 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "graph":
+			graph(os.Args[0], os.Args[2:])
+			return
+		case "variables":
+			variables(os.Args[0], os.Args[2:])
+			return
+		case "schema-diff":
+			schemaDiff(os.Args[0], os.Args[2:])
+			return
+		}
+	}
+
+	var inputFormat string
 	var outputFormat string
 	var outputFilename string
 	var outFile *os.File
@@ -20,6 +37,8 @@ func main() {
 	var checkSchema bool
 	var dryRun bool
 
+	pflag.StringVarP(&inputFormat, "input-format", "i", "",
+		"input format, one of: json, yaml (yml), ini or conf (optional, default is the input file extension or detected from its content)")
 	pflag.StringVarP(&outputFormat, "format", "f", "yaml",
 		"output format, one of: json, yaml (yml), ini or conf")
 	pflag.StringVarP(&outputFilename, "output", "o", "",
@@ -33,33 +52,21 @@ func main() {
 	pflag.Parse()
 	args := pflag.Args()
 
-	if pflag.NArg() < 1 {
+	if pflag.NArg() > 1 {
 		usage(os.Args[0])
 		os.Exit(1)
 	}
 
-	ext := filepath.Ext(args[0])
-
-	config, err := os.ReadFile(args[0])
-	if err != nil {
-		fmt.Printf("ERROR: %s\n", err)
-		os.Exit(1)
-	}
-
-	inFormat, err := getFormatFromExt(ext[1:])
-	if err != nil {
-		fmt.Printf("ERROR: %s\n", err)
-		os.Exit(1)
+	inputFilename := "-"
+	if pflag.NArg() == 1 {
+		inputFilename = args[0]
 	}
 
-	cfg, err := fluent.ParseAs(string(config), inFormat)
-	if err != nil {
-		fmt.Printf("ERROR: %s\n", err)
-		os.Exit(1)
-	}
+	cfg, inputFilename := readConfig(inputFilename, inputFormat)
 
 	if checkSchema {
 		if schemaVersion != "" {
+			var err error
 			schema, err = fluent.GetSchema(schemaVersion)
 			if err != nil {
 				fmt.Printf("ERROR: %s\n", err)
@@ -80,10 +87,10 @@ func main() {
 	if dryRun {
 		if checkSchema {
 			if schemaVersion == "" {
-				fmt.Printf("file %s has valid fluent-bit config syntax\n", args[0])
+				fmt.Printf("file %s has valid fluent-bit config syntax\n", inputFilename)
 			} else {
 				fmt.Printf("file %s has valid fluent-bit config syntax for version %s\n",
-					args[0], schemaVersion)
+					inputFilename, schemaVersion)
 			}
 		}
 		return
@@ -95,38 +102,278 @@ func main() {
 		os.Exit(1)
 	}
 
-	out, err := cfg.DumpAs(outFormat)
-	if err != nil {
+	outFile = createOutput(outputFilename)
+	defer outFile.Close()
+
+	enc := fluent.NewEncoder(outFile, outFormat)
+	if outFormat == fluent.FormatJSON {
+		enc.SetIndent("\t")
+	}
+	if err := enc.Encode(cfg); err != nil {
 		fmt.Printf("ERROR: %s\n", err)
 		os.Exit(1)
 	}
+}
+
+// graph writes the pipeline graph of the input config.
+func graph(progname string, arguments []string) {
+	var inputFormat string
+	var outputFormat string
+	var outputFilename string
+
+	flags := pflag.NewFlagSet("graph", pflag.ExitOnError)
+	flags.StringVarP(&inputFormat, "input-format", "i", "",
+		"input format, one of: json, yaml (yml), ini or conf (optional, default is the input file extension or detected from its content)")
+	flags.StringVarP(&outputFormat, "format", "f", "dot",
+		"graph format, one of: dot or mermaid")
+	flags.StringVarP(&outputFilename, "output", "o", "",
+		"output file (optional, default is stdout)")
+	flags.Usage = func() {
+		fmt.Printf("%s graph <options> [input|-]\n", progname)
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(arguments)
+
+	if flags.NArg() > 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	inputFilename := "-"
+	if flags.NArg() == 1 {
+		inputFilename = flags.Arg(0)
+	}
+
+	var render func(fluent.Config) string
+	switch strings.ToLower(outputFormat) {
+	case "dot":
+		render = fluent.Config.DOT
+	case "mermaid":
+		render = fluent.Config.Mermaid
+	default:
+		fmt.Printf("ERROR: unknown graph format: %s\n", outputFormat)
+		os.Exit(1)
+	}
+
+	cfg, inputFilename := readConfig(inputFilename, inputFormat)
+	for _, issue := range cfg.RouteGraph().Issues {
+		fmt.Fprintf(os.Stderr, "WARNING: %s: %s\n", inputFilename, issue)
+	}
+
+	out := createOutput(outputFilename)
+	defer out.Close()
+
+	if _, err := io.WriteString(out, render(cfg)); err != nil {
+		fmt.Printf("ERROR: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// variables lists the cloud variables referenced by the input config.
+func variables(progname string, arguments []string) {
+	var inputFormat string
+	var outputFormat string
+	var outputFilename string
+
+	flags := pflag.NewFlagSet("variables", pflag.ExitOnError)
+	flags.StringVarP(&inputFormat, "input-format", "i", "",
+		"input format, one of: json, yaml (yml), ini or conf (optional, default is the input file extension or detected from its content)")
+	flags.StringVarP(&outputFormat, "format", "f", "text",
+		"output format, one of: text or json")
+	flags.StringVarP(&outputFilename, "output", "o", "",
+		"output file (optional, default is stdout)")
+	flags.Usage = func() {
+		fmt.Printf("%s variables <options> [input|-]\n", progname)
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(arguments)
+
+	if flags.NArg() > 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	inputFilename := "-"
+	if flags.NArg() == 1 {
+		inputFilename = flags.Arg(0)
+	}
+
+	outputFormat = strings.ToLower(outputFormat)
+	if outputFormat != "text" && outputFormat != "json" {
+		fmt.Printf("ERROR: unknown variables format: %s\n", outputFormat)
+		os.Exit(1)
+	}
+
+	cfg, _ := readConfig(inputFilename, inputFormat)
+	refs := cfg.CloudVariables()
+
+	out := createOutput(outputFilename)
+	defer out.Close()
+
+	if outputFormat == "json" {
+		if refs == nil {
+			refs = []fluent.CloudVariableRef{}
+		}
+
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "\t")
+		if err := enc.Encode(refs); err != nil {
+			fmt.Printf("ERROR: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	for _, ref := range refs {
+		if _, err := fmt.Fprintln(out, ref); err != nil {
+			fmt.Printf("ERROR: %s\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// schemaDiff lists the changes between two schema versions.
+func schemaDiff(progname string, arguments []string) {
+	var outputFormat string
+	var outputFilename string
+
+	flags := pflag.NewFlagSet("schema-diff", pflag.ExitOnError)
+	flags.StringVarP(&outputFormat, "format", "f", "text",
+		"output format, one of: text or json")
+	flags.StringVarP(&outputFilename, "output", "o", "",
+		"output file (optional, default is stdout)")
+	flags.Usage = func() {
+		fmt.Printf("%s schema-diff <options> <from-version> <to-version>\n", progname)
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(arguments)
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(1)
+	}
 
-	if outputFilename != "" {
-		outFile, err = os.Create(outputFilename)
+	outputFormat = strings.ToLower(outputFormat)
+	if outputFormat != "text" && outputFormat != "json" {
+		fmt.Printf("ERROR: unknown schema-diff format: %s\n", outputFormat)
+		os.Exit(1)
+	}
+
+	var schemas [2]fluent.Schema
+	for i, version := range flags.Args() {
+		var err error
+		schemas[i], err = fluent.GetSchema(version)
 		if err != nil {
+			fmt.Printf("ERROR: %s\n", err)
+			os.Exit(2)
+		}
+	}
+
+	changes := fluent.DiffSchemas(schemas[0], schemas[1])
+
+	out := createOutput(outputFilename)
+	defer out.Close()
+
+	if outputFormat == "json" {
+		if changes == nil {
+			changes = fluent.SchemaChanges{}
+		}
+
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "\t")
+		if err := enc.Encode(changes); err != nil {
 			fmt.Printf("ERROR: %s\n", err)
 			os.Exit(1)
 		}
-		defer outFile.Close()
+		return
+	}
+
+	for _, change := range changes {
+		if _, err := fmt.Fprintln(out, change); err != nil {
+			fmt.Printf("ERROR: %s\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// readConfig decodes the config from the given file, or stdin when "-".
+// The format is taken from the input format if given,
+// or from the file extension, or detected from the content.
+// It returns the config and the name of the input to use on messages.
+func readConfig(inputFilename, inputFormat string) (fluent.Config, string) {
+	// Read from stdin when no input file is given, or when it is "-".
+	var in io.Reader = os.Stdin
+	if inputFilename != "-" {
+		f, err := os.Open(inputFilename)
+		if err != nil {
+			fmt.Printf("ERROR: %s\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		in = f
 	} else {
-		outFile = os.Stdout
+		inputFilename = "<stdin>"
 	}
 
-	switch outFormat {
-	case fluent.FormatJSON:
-		indented, err := indentJSON(out)
+	var inFormat fluent.Format
+	var err error
+	if inputFormat != "" {
+		inFormat, err = getFormatFromExt(inputFormat)
 		if err != nil {
-			fmt.Printf("ERROR: parse error: %s\n", err)
+			fmt.Printf("ERROR: %s\n", err)
 			os.Exit(1)
 		}
-		fmt.Fprintln(outFile, indented)
-	default:
-		fmt.Fprintln(outFile, out)
+	} else if inFormat, err = getFormatFromExt(strings.TrimPrefix(filepath.Ext(inputFilename), ".")); err != nil {
+		// Unknown extension or stdin: sniff the format from the content.
+		raw, err := io.ReadAll(in)
+		if err != nil {
+			fmt.Printf("ERROR: %s\n", err)
+			os.Exit(1)
+		}
+
+		detection := fluent.DetectFormat(string(raw))
+		if detection.Confidence == 0 {
+			fmt.Printf("ERROR: cannot detect format of %s: %s\n", inputFilename, detection.Reason)
+			os.Exit(1)
+		}
+		if detection.Confidence < 1 {
+			fmt.Fprintf(os.Stderr, "WARNING: %s detected as %s with %.0f%% confidence: %s\n",
+				inputFilename, detection.Format, detection.Confidence*100, detection.Reason)
+		}
+
+		inFormat = detection.Format
+		in = bytes.NewReader(raw)
+	}
+
+	var cfg fluent.Config
+	if err := fluent.NewDecoder(in, inFormat).Decode(&cfg); err != nil {
+		fmt.Printf("ERROR: %s\n", err)
+		os.Exit(1)
 	}
+
+	return cfg, inputFilename
+}
+
+// createOutput creates the output file, or returns stdout if not given.
+func createOutput(filename string) *os.File {
+	if filename == "" {
+		return os.Stdout
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		fmt.Printf("ERROR: %s\n", err)
+		os.Exit(1)
+	}
+
+	return f
 }
 
 func usage(progname string) {
-	fmt.Printf("%s <options> [input]\n", progname)
+	fmt.Printf("%s <options> [input|-]\n", progname)
+	fmt.Printf("%s graph <options> [input|-]\n", progname)
+	fmt.Printf("%s variables <options> [input|-]\n", progname)
+	fmt.Printf("%s schema-diff <options> <from-version> <to-version>\n", progname)
 	pflag.CommandLine.PrintDefaults()
 }
 
@@ -141,18 +388,3 @@ func getFormatFromExt(ext string) (fluent.Format, error) {
 	}
 	return "", fmt.Errorf("unknown format extension: %s", ext)
 }
-
-func indentJSON(in string) (string, error) {
-	var obj any
-	if err := json.Unmarshal([]byte(in), &obj); err != nil {
-		return "", err
-	}
-
-	indentedJSON, err := json.MarshalIndent(obj, "", "\t")
-	if err != nil {
-		fmt.Println("Failed to indent JSON:", err)
-		return "", err
-	}
-
-	return string(indentedJSON), nil
-}
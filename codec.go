@@ -0,0 +1,183 @@
+package fluentbitconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrTooLarge is returned by Decoder.Decode when the input
+// exceeds the maximum size set with Decoder.SetMaxSize.
+var ErrTooLarge = errors.New("config too large")
+
+// Decoder reads a config in the given format from an input stream.
+type Decoder struct {
+	r       io.Reader
+	format  Format
+	strict  bool
+	maxSize int64
+}
+
+// NewDecoder returns a new decoder that reads from r.
+// Like ParseAs, the decoder is strict by default.
+func NewDecoder(r io.Reader, format Format) *Decoder {
+	return &Decoder{r: r, format: format, strict: true}
+}
+
+// SetStrict sets whether unknown fields are rejected.
+// This only applies to the YAML and JSON formats,
+// the classic format has no schema to check sections against.
+func (d *Decoder) SetStrict(strict bool) {
+	d.strict = strict
+}
+
+// SetMaxSize limits the amount of bytes read from the input.
+// Zero or less means no limit.
+func (d *Decoder) SetMaxSize(n int64) {
+	d.maxSize = n
+}
+
+// Decode reads the input and stores the config in cfg.
+// YAML is decoded as it is read, while classic and JSON inputs
+// are buffered as their parsing and positions need the whole input.
+func (d *Decoder) Decode(cfg *Config) error {
+	r := d.r
+	var limit *maxSizeReader
+	if d.maxSize > 0 {
+		limit = &maxSizeReader{r: io.LimitReader(r, d.maxSize+1), max: d.maxSize}
+		r = limit
+	}
+
+	var out Config
+	var err error
+	switch strings.ToLower(string(d.format)) {
+	case "", "ini", "conf", "classic":
+		var b []byte
+		b, err = io.ReadAll(r)
+		if err == nil {
+			err = out.UnmarshalClassic(b)
+		}
+	case "yml", "yaml":
+		err = d.decodeYAML(r, &out)
+	case "json":
+		err = d.decodeJSON(r, &out)
+	default:
+		return ErrFormatUnknown
+	}
+
+	if limit != nil && limit.err != nil {
+		return limit.err
+	}
+
+	*cfg = out
+	return err
+}
+
+func (d *Decoder) decodeYAML(r io.Reader, out *Config) error {
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(d.strict)
+	err := dec.Decode(out)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+
+	return err
+}
+
+func (d *Decoder) decodeJSON(r io.Reader, out *Config) error {
+	var buf bytes.Buffer
+	dec := json.NewDecoder(io.TeeReader(r, &buf))
+	if d.strict {
+		dec.DisallowUnknownFields()
+	}
+	err := dec.Decode(out)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+
+	if err == nil {
+		out.setJSONPositions(buf.Bytes())
+	}
+
+	return err
+}
+
+// maxSizeReader fails with ErrTooLarge once more than max bytes are read.
+// The error is kept as decoders may not return read errors as they are.
+type maxSizeReader struct {
+	r   io.Reader
+	n   int64
+	max int64
+	err error
+}
+
+func (l *maxSizeReader) Read(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	if l.n > l.max {
+		l.err = fmt.Errorf("%w: exceeds %d bytes", ErrTooLarge, l.max)
+		return 0, l.err
+	}
+
+	return n, err
+}
+
+// Encoder writes a config in the given format to an output stream.
+type Encoder struct {
+	w      io.Writer
+	format Format
+	indent string
+}
+
+// NewEncoder returns a new encoder that writes to w.
+// By default, YAML and classic are indented with 4 spaces
+// and JSON is written in a single line.
+func NewEncoder(w io.Writer, format Format) *Encoder {
+	return &Encoder{w: w, format: format}
+}
+
+// SetIndent sets the indentation used for nested values.
+// YAML only supports spaces.
+func (e *Encoder) SetIndent(indent string) {
+	e.indent = indent
+}
+
+// Encode writes the config to the output stream.
+func (e *Encoder) Encode(cfg Config) error {
+	switch strings.ToLower(string(e.format)) {
+	case "", "ini", "conf", "classic":
+		indent := e.indent
+		if indent == "" {
+			indent = classicIndent
+		}
+		return cfg.writeClassic(e.w, indent)
+	case "yml", "yaml":
+		enc := yaml.NewEncoder(e.w)
+		if e.indent != "" {
+			if strings.Trim(e.indent, " ") != "" {
+				return fmt.Errorf("invalid YAML indent %q: only spaces are allowed", e.indent)
+			}
+			enc.SetIndent(len(e.indent))
+		}
+		if err := enc.Encode(cfg); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "json":
+		enc := json.NewEncoder(e.w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", e.indent)
+		return enc.Encode(cfg)
+	}
+
+	return ErrFormatUnknown
+}
@@ -0,0 +1,132 @@
+package fluentbitconfig
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+func TestDecoder_Decode(t *testing.T) {
+	t.Run("classic", func(t *testing.T) {
+		var cfg Config
+		err := NewDecoder(strings.NewReader("[INPUT]\n    Name cpu\n"), FormatClassic).Decode(&cfg)
+		require.NoError(t, err)
+		require.Equal(t, []string{"cpu.0"}, cfg.Pipeline.Inputs.IDs())
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		for _, format := range []Format{FormatClassic, FormatYAML, FormatJSON} {
+			var cfg Config
+			require.NoError(t, NewDecoder(strings.NewReader(""), format).Decode(&cfg), format)
+			require.True(t, cfg.Equal(Config{}), format)
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		const text = `{"unknown": true, "pipeline": {"inputs": [{"name": "cpu"}]}}`
+
+		var cfg Config
+		err := NewDecoder(strings.NewReader(text), FormatJSON).Decode(&cfg)
+		require.EqualError(t, err, `json: unknown field "unknown"`)
+
+		dec := NewDecoder(strings.NewReader(text), FormatJSON)
+		dec.SetStrict(false)
+		require.NoError(t, dec.Decode(&cfg))
+		require.Equal(t, property.Position{Line: 1, Column: 43}, cfg.Pipeline.Inputs[0].Pos)
+
+		dec = NewDecoder(strings.NewReader("unknown: true\n"), FormatYAML)
+		dec.SetStrict(false)
+		require.NoError(t, dec.Decode(&cfg))
+	})
+
+	t.Run("max_size", func(t *testing.T) {
+		const text = "[INPUT]\n    Name cpu\n"
+
+		dec := NewDecoder(strings.NewReader(text), FormatClassic)
+		dec.SetMaxSize(int64(len(text)))
+		var cfg Config
+		require.NoError(t, dec.Decode(&cfg))
+
+		dec = NewDecoder(strings.NewReader(text), FormatClassic)
+		dec.SetMaxSize(int64(len(text) - 1))
+		require.ErrorIs(t, dec.Decode(&cfg), ErrTooLarge)
+
+		for format, text := range map[Format]string{
+			FormatYAML: "pipeline:\n    inputs:\n        - name: cpu\n",
+			FormatJSON: `{"pipeline": {"inputs": [{"name": "cpu"}]}}`,
+		} {
+			dec = NewDecoder(strings.NewReader(text), format)
+			dec.SetMaxSize(int64(len(text)))
+			require.NoError(t, dec.Decode(&cfg), format)
+
+			dec = NewDecoder(strings.NewReader(text), format)
+			dec.SetMaxSize(10)
+			err := dec.Decode(&cfg)
+			require.ErrorIs(t, err, ErrTooLarge, format)
+			require.EqualError(t, err, "config too large: exceeds 10 bytes", format)
+		}
+	})
+
+	t.Run("streamed", func(t *testing.T) {
+		// Nothing after the JSON value is read.
+		r := io.MultiReader(strings.NewReader(`{"pipeline": {"inputs": [{"name": "cpu"}]}}`), iotest.ErrReader(errors.New("unexpected read")))
+
+		var cfg Config
+		require.NoError(t, NewDecoder(r, FormatJSON).Decode(&cfg))
+		require.Equal(t, []string{"cpu.0"}, cfg.Pipeline.Inputs.IDs())
+		require.Equal(t, property.Position{Line: 1, Column: 26}, cfg.Pipeline.Inputs[0].Pos)
+	})
+
+	t.Run("unknown_format", func(t *testing.T) {
+		var cfg Config
+		require.ErrorIs(t, NewDecoder(strings.NewReader(""), "toml").Decode(&cfg), ErrFormatUnknown)
+	})
+}
+
+func TestEncoder_Encode(t *testing.T) {
+	cfg := Config{}
+	cfg.AddSection(SectionKindInput, property.Properties{
+		{Key: "name", Value: "tail"},
+		{Key: "path", Value: []any{"/a.log", "/b.log"}},
+	})
+
+	encode := func(t *testing.T, format Format, indent string) string {
+		t.Helper()
+
+		var sb strings.Builder
+		enc := NewEncoder(&sb, format)
+		enc.SetIndent(indent)
+		require.NoError(t, enc.Encode(cfg))
+		return sb.String()
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		for _, format := range []Format{FormatClassic, FormatYAML, FormatJSON} {
+			want, err := cfg.DumpAs(format)
+			require.NoError(t, err)
+			require.Equal(t, want, encode(t, format, ""), format)
+		}
+	})
+
+	t.Run("classic", func(t *testing.T) {
+		require.Equal(t, "[INPUT]\n\tname tail\n\tpath /a.log\n\tpath /b.log\n", encode(t, FormatClassic, "\t"))
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		require.Equal(t, "pipeline:\n  inputs:\n    - name: tail\n      path:\n        - /a.log\n        - /b.log\n", encode(t, FormatYAML, "  "))
+
+		enc := NewEncoder(&strings.Builder{}, FormatYAML)
+		enc.SetIndent("\t")
+		require.EqualError(t, enc.Encode(cfg), `invalid YAML indent "\t": only spaces are allowed`)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		require.Equal(t, "{\n  \"pipeline\": {\n    \"inputs\": [\n      {\n        \"name\": \"tail\",\n        \"path\": [\n          \"/a.log\",\n          \"/b.log\"\n        ]\n      }\n    ]\n  }\n}\n", encode(t, FormatJSON, "  "))
+	})
+}
@@ -16,6 +16,12 @@ type Config struct {
 	Customs  Plugins             `json:"customs,omitempty" yaml:"customs,omitempty"`
 	Pipeline Pipeline            `json:"pipeline,omitempty" yaml:"pipeline,omitempty"`
 	Parsers  Plugins             `json:"parsers,omitempty" yaml:"parsers,omitempty"`
+
+	MultilineParsers MultilineParsers `json:"multiline_parsers,omitempty" yaml:"multiline_parsers,omitempty"`
+	// ExternalPlugins are paths to shared object plugins to load.
+	ExternalPlugins []string    `json:"plugins,omitempty" yaml:"plugins,omitempty"`
+	Upstreams       Upstreams   `json:"upstream_servers,omitempty" yaml:"upstream_servers,omitempty"`
+	StreamTasks     StreamTasks `json:"stream_processor,omitempty" yaml:"stream_processor,omitempty"`
 }
 
 type Pipeline struct {
@@ -35,26 +41,82 @@ func (c *Config) Include(path string) {
 	c.Includes = append(c.Includes, path)
 }
 
-func (c *Config) AddSection(kind SectionKind, props property.Properties) {
+// AddSection adds a section of the given kind from its properties.
+// Sections without a name are ignored.
+// An error is returned when a multiline parser has malformed rules,
+// or when a node is added before any upstream.
+func (c *Config) AddSection(kind SectionKind, props property.Properties) error {
+	return c.addSection(kind, props, property.Position{})
+}
+
+func (c *Config) addSection(kind SectionKind, props property.Properties, pos property.Position) error {
 	if kind == SectionKindService {
 		if c.Service == nil {
 			c.Service = property.Properties{}
 		}
 		for _, p := range props {
-			c.Service.Set(p.Key, p.Value)
+			setProperty(&c.Service, p)
+		}
+		return nil
+	}
+
+	switch kind {
+	case SectionKindMultilineParser:
+		var parser MultilineParser
+		if err := parser.setProperties(props); err != nil {
+			return err
 		}
-		return
+
+		if parser.Name == "" {
+			return nil
+		}
+
+		parser.ID = fmt.Sprintf("%s.%d", parser.Name, len(c.MultilineParsers))
+		parser.Pos = pos
+		c.MultilineParsers = append(c.MultilineParsers, parser)
+		return nil
+	case SectionKindPlugins:
+		for _, v := range classicValues(props, "path") {
+			if path := stringFromAny(v); path != "" {
+				c.ExternalPlugins = append(c.ExternalPlugins, path)
+			}
+		}
+		return nil
+	case SectionKindUpstream:
+		c.Upstreams = append(c.Upstreams, Upstream{
+			Name: upstreamName(props),
+			Pos:  pos,
+		})
+		return nil
+	case SectionKindNode:
+		// Nodes belong to the upstream defined before them.
+		if len(c.Upstreams) == 0 {
+			return errNodeWithoutUpstream
+		}
+
+		c.Upstreams[len(c.Upstreams)-1].addNode(props, pos)
+		return nil
+	case SectionKindStreamTask:
+		name, _ := props.Get("name")
+		exec, _ := props.Get("exec")
+		c.StreamTasks = append(c.StreamTasks, StreamTask{
+			Name: stringFromAny(name),
+			Exec: stringFromAny(exec),
+			Pos:  pos,
+		})
+		return nil
 	}
 
 	name := Name(props)
 	if name == "" {
-		return
+		return nil
 	}
 
 	makePlugin := func(i int) Plugin {
 		return Plugin{
-			ID:         fmt.Sprintf("%s.%d", name, i),
+			ID:         pluginID(name, props, i),
 			Name:       name,
+			Pos:        pos,
 			Properties: props,
 		}
 	}
@@ -71,6 +133,8 @@ func (c *Config) AddSection(kind SectionKind, props property.Properties) {
 	case SectionKindParser:
 		c.Parsers = append(c.Parsers, makePlugin(len(c.Parsers)))
 	}
+
+	return nil
 }
 
 func (c Config) Equal(target Config) bool {
@@ -106,61 +170,158 @@ func (c Config) Equal(target Config) bool {
 		return false
 	}
 
+	if !c.MultilineParsers.Equal(target.MultilineParsers) {
+		return false
+	}
+
+	if !slices.Equal(c.ExternalPlugins, target.ExternalPlugins) {
+		return false
+	}
+
+	if !c.Upstreams.Equal(target.Upstreams) {
+		return false
+	}
+
+	if !c.StreamTasks.Equal(target.StreamTasks) {
+		return false
+	}
+
 	return true
 }
 
+// clone returns a copy of the config whose sections and properties
+// can be modified without affecting c.
+// Property values are not copied.
+func (c Config) clone() Config {
+	out := c
+	out.Env = slices.Clone(c.Env)
+	out.Includes = slices.Clone(c.Includes)
+	out.Service = slices.Clone(c.Service)
+	out.Customs = clonePlugins(c.Customs)
+	out.Pipeline.Inputs = clonePlugins(c.Pipeline.Inputs)
+	out.Pipeline.Filters = clonePlugins(c.Pipeline.Filters)
+	out.Pipeline.Outputs = clonePlugins(c.Pipeline.Outputs)
+	out.Parsers = clonePlugins(c.Parsers)
+	out.ExternalPlugins = slices.Clone(c.ExternalPlugins)
+	out.StreamTasks = slices.Clone(c.StreamTasks)
+
+	if c.MultilineParsers != nil {
+		out.MultilineParsers = make(MultilineParsers, len(c.MultilineParsers))
+		for i, parser := range c.MultilineParsers {
+			parser.Properties = slices.Clone(parser.Properties)
+			parser.Rules = slices.Clone(parser.Rules)
+			out.MultilineParsers[i] = parser
+		}
+	}
+
+	if c.Upstreams != nil {
+		out.Upstreams = make(Upstreams, len(c.Upstreams))
+		for i, upstream := range c.Upstreams {
+			upstream.Nodes = clonePlugins(upstream.Nodes)
+			out.Upstreams[i] = upstream
+		}
+	}
+
+	return out
+}
+
 // IDs namespaced with the section kind and name.
 // For example: input:tail:tail.0
+// Plugins with an `alias` property use it as ID instead of their position.
+// For example: input:tail:app_logs
+// Processors IDs are prefixed with the ID of their plugin and signal.
+// For example: input:tail:tail.0/logs:calyptia:calyptia.0,
+// or tail.0/logs/calyptia.0 when not namespaced.
 func (c Config) IDs(namespaced bool) []string {
 	var ids []string
-	set := func(kind SectionKind, plugins Plugins) {
-		for _, plugin := range plugins {
-			if namespaced {
-				ids = append(ids, fmt.Sprintf("%s:%s:%s", kind, plugin.Name, plugin.ID))
-			} else {
-				ids = append(ids, plugin.ID)
-			}
+	for _, ref := range c.pluginRefs() {
+		if namespaced {
+			ids = append(ids, ref.ID)
+		} else {
+			ids = append(ids, ref.LocalID)
 		}
 	}
 
-	set(SectionKindCustom, c.Customs)
-	set(SectionKindInput, c.Pipeline.Inputs)
-	set(SectionKindFilter, c.Pipeline.Filters)
-	set(SectionKindOutput, c.Pipeline.Outputs)
-	set(SectionKindParser, c.Parsers)
-
 	return ids
 }
 
 // FindByID were the id should be namespaced with the section kind and name.
 // For example: input:tail:tail.0
+// See IDs for the format of processor IDs.
 func (c Config) FindByID(id string) (Plugin, bool) {
-	find := func(kind SectionKind, plugins Plugins, id string) (Plugin, bool) {
-		for _, plugin := range plugins {
-			if fmt.Sprintf("%s:%s:%s", kind, plugin.Name, plugin.ID) == id {
-				return plugin, true
-			}
+	for _, ref := range c.pluginRefs() {
+		if ref.ID == id {
+			return *ref.Plugin, true
 		}
-		return Plugin{}, false
 	}
 
-	if plugin, ok := find(SectionKindCustom, c.Customs, id); ok {
-		return plugin, true
-	}
+	return Plugin{}, false
+}
 
-	if plugin, ok := find(SectionKindInput, c.Pipeline.Inputs, id); ok {
-		return plugin, true
-	}
+// pluginRef points to a plugin of the config.
+type pluginRef struct {
+	Kind SectionKind
+	// ID namespaced with the section kind and name.
+	ID string
+	// LocalID is the ID not namespaced.
+	LocalID string
+	Plugin  *Plugin
+	// List holding the plugin at Index.
+	List  *Plugins
+	Index int
+}
 
-	if plugin, ok := find(SectionKindFilter, c.Pipeline.Filters, id); ok {
-		return plugin, true
+// pluginRefs returns all the customs, inputs, filters, outputs and parsers,
+// each one followed by its processors.
+func (c *Config) pluginRefs() []pluginRef {
+	var out []pluginRef
+
+	var add func(kind SectionKind, plugins *Plugins, parent *pluginRef, signal string)
+	add = func(kind SectionKind, plugins *Plugins, parent *pluginRef, signal string) {
+		for i := range *plugins {
+			plugin := &(*plugins)[i]
+			ref := pluginRef{
+				Kind:    kind,
+				ID:      fmt.Sprintf("%s:%s:%s", kind, plugin.Name, plugin.ID),
+				LocalID: plugin.ID,
+				Plugin:  plugin,
+				List:    plugins,
+				Index:   i,
+			}
+			if parent != nil {
+				ref.ID = fmt.Sprintf("%s/%s:%s:%s", parent.ID, signal, plugin.Name, plugin.ID)
+				ref.LocalID = fmt.Sprintf("%s/%s/%s", parent.LocalID, signal, plugin.ID)
+			}
+			out = append(out, ref)
+
+			for _, signal := range processorSignals {
+				processors, _ := plugin.Processors.Signal(signal)
+				add(SectionKindProcessor, processors, &ref, signal)
+			}
+		}
 	}
 
-	if plugin, ok := find(SectionKindParser, c.Parsers, id); ok {
-		return plugin, true
+	add(SectionKindCustom, &c.Customs, nil, "")
+	add(SectionKindInput, &c.Pipeline.Inputs, nil, "")
+	add(SectionKindFilter, &c.Pipeline.Filters, nil, "")
+	add(SectionKindOutput, &c.Pipeline.Outputs, nil, "")
+	add(SectionKindParser, &c.Parsers, nil, "")
+
+	return out
+}
+
+// setProperty is like property.Properties.Set
+// but it also overrides the position.
+func setProperty(pp *property.Properties, p property.Property) {
+	for i, got := range *pp {
+		if strings.EqualFold(got.Key, p.Key) {
+			(*pp)[i].Value = p.Value
+			(*pp)[i].Pos = p.Pos
+			return
+		}
 	}
 
-	return Plugin{}, false
+	*pp = append(*pp, p)
 }
 
 // Name from properties.
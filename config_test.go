@@ -364,6 +364,89 @@ func TestConfig_IDs(t *testing.T) {
 			"stdout.1",
 		}, conf.IDs(false))
 	})
+
+	t.Run("alias", func(t *testing.T) {
+		conf, err := ParseAs(`
+			[INPUT]
+				name  tail
+				alias app_logs
+			[INPUT]
+				name tail
+		`, FormatClassic)
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"input:tail:app_logs",
+			"input:tail:tail.1",
+		}, conf.IDs(true))
+
+		// Inserting a plugin does not change the ID of aliased ones.
+		conf, err = ParseAs(configLiteral(`
+			pipeline:
+				inputs:
+					- name: tail
+					- name: tail
+					  alias: app_logs
+		`), FormatYAML)
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"input:tail:tail.0",
+			"input:tail:app_logs",
+		}, conf.IDs(true))
+	})
+
+	t.Run("explicit", func(t *testing.T) {
+		conf, err := ParseAs(configLiteral(`
+			pipeline:
+				inputs:
+					- name: tail
+					  $id: app
+					  alias: app_logs
+					- name: tail
+		`), FormatYAML)
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"input:tail:app",
+			"input:tail:tail.1",
+		}, conf.IDs(true))
+		require.NoError(t, conf.Validate())
+
+		// The explicit ID is kept through mutations.
+		_, err = conf.InsertPlugin(SectionKindInput, 0, property.Properties{{Key: "name", Value: "tail"}})
+		require.NoError(t, err)
+		_, err = conf.UpdateProperties("input:tail:app", func(props *property.Properties) error {
+			props.Set("alias", "other")
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"input:tail:tail.0",
+			"input:tail:app",
+			"input:tail:tail.2",
+		}, conf.IDs(true))
+
+		err = conf.ApplyMergePatch([]byte(`{"pipeline": {"inputs": {"app": {"path": "/var/log/app.log"}}}}`))
+		require.NoError(t, err)
+
+		plugin, found := conf.FindByID("input:tail:app")
+		require.True(t, found)
+		require.Equal(t, "app", plugin.ID)
+		v, _ := plugin.Properties.Get("path")
+		require.Equal(t, "/var/log/app.log", v)
+
+		// It is kept when marshaling but not part of dumps.
+		b, err := json.Marshal(conf.Pipeline.Inputs[1])
+		require.NoError(t, err)
+		require.JSONEq(t, `{"name": "tail", "$id": "app", "alias": "other", "path": "/var/log/app.log"}`, string(b))
+
+		yaml, err := conf.DumpAsYAML()
+		require.NoError(t, err)
+		require.NotContains(t, yaml, IDKey)
+
+		classic, err := conf.DumpAsClassic()
+		require.NoError(t, err)
+		require.NotContains(t, classic, IDKey)
+		require.Equal(t, "app", ExplicitID(conf.Pipeline.Inputs[1].Properties))
+	})
 }
 
 func TestConfig_FindByID(t *testing.T) {
@@ -401,7 +484,19 @@ func TestConfig_FindByID(t *testing.T) {
 				{Key: "name", Value: "cpu"},
 				{Key: "proptest", Value: "valuetest"},
 			},
-		}, plugin)
+		}, withoutPositions(plugin))
+	})
+
+	t.Run("output", func(t *testing.T) {
+		conf, err := ParseAs(`
+			[OUTPUT]
+				name  stdout
+				alias debug
+		`, FormatClassic)
+		require.NoError(t, err)
+		plugin, found := conf.FindByID("output:stdout:debug")
+		require.True(t, found)
+		require.Equal(t, "debug", plugin.ID)
 	})
 }
 
@@ -503,3 +598,51 @@ func Example_configWithProcessors() {
 	//                         regex: star
 	//                         regexEngine: pcre2
 }
+
+func ExamplePlugin_processors() {
+	conf := Config{
+		Pipeline: Pipeline{
+			Inputs: Plugins{
+				Plugin{
+					Properties: property.Properties{
+						{Key: "name", Value: "dummy"},
+					},
+					Processors: Processors{
+						Logs: Plugins{
+							Plugin{
+								Properties: property.Properties{
+									{Key: "name", Value: "calyptia"},
+									{Key: "actions", Value: property.Properties{
+										{Key: "type", Value: "block_keys"},
+										{Key: "opts", Value: property.Properties{
+											{Key: "regex", Value: "star"},
+											{Key: "regexEngine", Value: "pcre2"},
+										}},
+									}},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	yaml, err := conf.DumpAsYAML()
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(yaml)
+	// Output:
+	// pipeline:
+	//     inputs:
+	//         - name: dummy
+	//           processors:
+	//             logs:
+	//                 - name: calyptia
+	//                   actions:
+	//                     type: block_keys
+	//                     opts:
+	//                         regex: star
+	//                         regexEngine: pcre2
+}
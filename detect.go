@@ -0,0 +1,154 @@
+package fluentbitconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// reClassicSection matches classic section headers like `[INPUT]`.
+	reClassicSection = regexp.MustCompile(`^\s*\[[A-Za-z_]+\]\s*$`)
+	// reClassicDirective matches classic `@SET` and `@INCLUDE` directives.
+	reClassicDirective = regexp.MustCompile(`(?i)^\s*@(set|include)\s`)
+	// reYAMLMapping matches YAML mapping keys and sequence items.
+	reYAMLMapping = regexp.MustCompile(`^\s*(-\s+)?[A-Za-z_][\w.-]*\s*:(\s|$)`)
+)
+
+// yamlTopLevelKeys are the known top-level sections of the YAML format.
+var yamlTopLevelKeys = []string{
+	"env",
+	"includes",
+	"service",
+	"customs",
+	"pipeline",
+	"parsers",
+	"multiline_parsers",
+	"plugins",
+	"upstream_servers",
+	"stream_processor",
+}
+
+// FormatDetection is the result of DetectFormat.
+type FormatDetection struct {
+	Format Format
+	// Confidence in the range [0, 1].
+	// Zero means the format could not be detected.
+	Confidence float64
+	// Reason explains why the detection is not fully confident.
+	// Empty when Confidence is 1.
+	Reason string
+}
+
+// DetectFormat sniffs the format of the given config from its content,
+// without relying on a filename.
+// Classic configs are detected by their section headers and `@` directives,
+// YAML configs by their mappings, and JSON configs by their top-level object.
+func DetectFormat(raw string) FormatDetection {
+	trimmed := strings.TrimSpace(strings.TrimPrefix(raw, "\ufeff"))
+	if trimmed == "" {
+		return FormatDetection{Reason: "empty input"}
+	}
+
+	if strings.HasPrefix(trimmed, "{") {
+		var v map[string]any
+		if err := json.Unmarshal([]byte(trimmed), &v); err != nil {
+			return FormatDetection{
+				Format:     FormatJSON,
+				Confidence: 0.5,
+				Reason:     fmt.Sprintf("starts like a JSON object but is not valid JSON: %v", err),
+			}
+		}
+		return FormatDetection{Format: FormatJSON, Confidence: 1}
+	}
+
+	var classicLines, yamlLines int
+	for _, line := range strings.Split(trimmed, "\n") {
+		if l := strings.TrimSpace(line); l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+
+		switch {
+		case reClassicSection.MatchString(line), reClassicDirective.MatchString(line):
+			classicLines++
+		case reYAMLMapping.MatchString(line):
+			yamlLines++
+		}
+	}
+
+	switch {
+	case classicLines == 0 && yamlLines == 0:
+		if json.Valid([]byte(trimmed)) {
+			return FormatDetection{
+				Format:     FormatJSON,
+				Confidence: 0.5,
+				Reason:     "valid JSON but not an object",
+			}
+		}
+
+		return FormatDetection{Reason: "no classic section headers, YAML mappings or JSON object found"}
+	case yamlLines == 0:
+		return FormatDetection{Format: FormatClassic, Confidence: 1}
+	case classicLines == 0:
+		return detectYAML(trimmed)
+	}
+
+	// Mixed content: go with the majority.
+	total := float64(classicLines + yamlLines)
+	reason := fmt.Sprintf("found both classic section headers or directives (%d) and YAML mappings (%d)", classicLines, yamlLines)
+	if classicLines >= yamlLines {
+		return FormatDetection{
+			Format:     FormatClassic,
+			Confidence: float64(classicLines) / total,
+			Reason:     reason,
+		}
+	}
+
+	return FormatDetection{
+		Format:     FormatYAML,
+		Confidence: float64(yamlLines) / total,
+		Reason:     reason,
+	}
+}
+
+func detectYAML(raw string) FormatDetection {
+	var v map[string]any
+	if err := yaml.Unmarshal([]byte(raw), &v); err != nil {
+		return FormatDetection{
+			Format:     FormatYAML,
+			Confidence: 0.5,
+			Reason:     fmt.Sprintf("looks like YAML but is not valid YAML: %v", err),
+		}
+	}
+
+	for key := range v {
+		for _, known := range yamlTopLevelKeys {
+			if strings.EqualFold(key, known) {
+				return FormatDetection{Format: FormatYAML, Confidence: 1}
+			}
+		}
+	}
+
+	return FormatDetection{
+		Format:     FormatYAML,
+		Confidence: 0.8,
+		Reason:     "YAML mapping without known top-level sections",
+	}
+}
+
+// ParseAuto detects the format of the given config with DetectFormat
+// and parses it.
+// The detection is returned along the config so callers can decide
+// what to do with low confidence results.
+func ParseAuto(raw string) (Config, FormatDetection, error) {
+	detection := DetectFormat(raw)
+	if detection.Confidence == 0 {
+		return Config{}, detection, fmt.Errorf("%w: %s", ErrFormatUnknown, detection.Reason)
+	}
+
+	cfg, err := ParseAs(raw, detection.Format)
+	return cfg, detection, err
+}
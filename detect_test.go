@@ -0,0 +1,135 @@
+package fluentbitconfig
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tt := []struct {
+		name   string
+		raw    string
+		want   Format
+		conf   float64
+		reason string
+	}{
+		{
+			name:   "empty",
+			raw:    "  \n",
+			reason: "empty input",
+		},
+		{
+			name: "classic",
+			raw:  "# comment\n[INPUT]\n    Name cpu\n",
+			want: FormatClassic,
+			conf: 1,
+		},
+		{
+			name: "classic_directives_only",
+			raw:  "@SET a=b\n@INCLUDE other.conf\n",
+			want: FormatClassic,
+			conf: 1,
+		},
+		{
+			name: "yaml",
+			raw:  "pipeline:\n  inputs:\n    - name: cpu\n",
+			want: FormatYAML,
+			conf: 1,
+		},
+		{
+			name:   "yaml_unknown_keys",
+			raw:    "foo: bar\n",
+			want:   FormatYAML,
+			conf:   0.8,
+			reason: "YAML mapping without known top-level sections",
+		},
+		{
+			name:   "yaml_invalid",
+			raw:    "service:\n  flush: 1\n   log_level: info\n",
+			want:   FormatYAML,
+			conf:   0.5,
+			reason: "looks like YAML but is not valid YAML: yaml: line 3: mapping values are not allowed in this context",
+		},
+		{
+			name: "json",
+			raw:  "\ufeff  {\"pipeline\": {\"inputs\": [{\"name\": \"cpu\"}]}}",
+			want: FormatJSON,
+			conf: 1,
+		},
+		{
+			name:   "json_invalid",
+			raw:    `{"pipeline": }`,
+			want:   FormatJSON,
+			conf:   0.5,
+			reason: "starts like a JSON object but is not valid JSON: invalid character '}' looking for beginning of value",
+		},
+		{
+			name:   "json_array",
+			raw:    `[{"name": "cpu"}]`,
+			want:   FormatJSON,
+			conf:   0.5,
+			reason: "valid JSON but not an object",
+		},
+		{
+			name:   "mixed",
+			raw:    "[INPUT]\n    Name cpu\n[OUTPUT]\n    Name stdout\nservice:\n",
+			want:   FormatClassic,
+			conf:   2.0 / 3.0,
+			reason: "found both classic section headers or directives (2) and YAML mappings (1)",
+		},
+		{
+			name:   "unknown",
+			raw:    "hello world\n",
+			reason: "no classic section headers, YAML mappings or JSON object found",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got := DetectFormat(tc.raw)
+			require.Equal(t, tc.want, got.Format)
+			require.InDelta(t, tc.conf, got.Confidence, 0.001)
+			require.Equal(t, tc.reason, got.Reason)
+		})
+	}
+}
+
+func TestDetectFormat_testdata(t *testing.T) {
+	names, err := filepath.Glob("testdata/*.*")
+	require.NoError(t, err)
+
+	for _, name := range names {
+		format := formatFromPath(name)
+		b, err := os.ReadFile(name)
+		require.NoError(t, err)
+
+		if strings.TrimSpace(string(b)) == "" {
+			continue
+		}
+
+		// JSON is also valid YAML, ex: `{}`.
+		if format == FormatYAML && json.Valid(b) {
+			format = FormatJSON
+		}
+
+		got := DetectFormat(string(b))
+		require.Equal(t, format, got.Format, name)
+		require.Equal(t, 1.0, got.Confidence, "%s: %s", name, got.Reason)
+	}
+}
+
+func TestParseAuto(t *testing.T) {
+	cfg, detection, err := ParseAuto("[INPUT]\n    Name cpu\n")
+	require.NoError(t, err)
+	require.Equal(t, Format(FormatClassic), detection.Format)
+	require.Equal(t, []string{"cpu.0"}, cfg.Pipeline.Inputs.IDs())
+
+	_, _, err = ParseAuto("hello")
+	require.ErrorIs(t, err, ErrFormatUnknown)
+	require.EqualError(t, err, "format unknown: no classic section headers, YAML mappings or JSON object found")
+}
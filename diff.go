@@ -0,0 +1,459 @@
+package fluentbitconfig
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+// ChangeKind of a Change.
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeChanged ChangeKind = "changed"
+	ChangeMoved   ChangeKind = "moved"
+)
+
+// Change between two configs.
+// Sections are "env", "service", "includes", "plugins",
+// and the namespaced IDs of plugins, processors, multiline parsers,
+// upstreams, upstream nodes and stream tasks.
+// For example: input:tail:tail.0
+type Change struct {
+	Kind    ChangeKind `json:"kind"`
+	Section string     `json:"section"`
+	// Key of the changed property.
+	// Empty when the whole section was added, removed or moved.
+	Key string `json:"key,omitempty"`
+	// From and To values of the property,
+	// the properties of added and removed sections,
+	// or the indexes of moved sections.
+	From any `json:"from,omitempty"`
+	To   any `json:"to,omitempty"`
+}
+
+// Changes between two configs as returned by Diff.
+// Use encoding/json for a JSON rendering.
+type Changes []Change
+
+// Diff returns the changes needed to go from config a to config b.
+// Sections with an alias or a name are matched by ID.
+// Sections with positional IDs are matched by content among those
+// of the same kind and name, so they are tracked when moved or when
+// others are added or removed before them.
+// Matched sections are reported with their ID in config a.
+// Values are compared as they would be written in classic format,
+// so "1" and 1 are the same value.
+func Diff(a, b Config) Changes {
+	from, to := a.diffSections(), b.diffSections()
+	matches := matchSections(from, to)
+	moved := movedSections(from, to, matches)
+
+	matched := make([]bool, len(to))
+	var out Changes
+	for i, s := range from {
+		j := matches[i]
+		if j < 0 {
+			out = append(out, Change{Kind: ChangeRemoved, Section: s.ID, From: s.Props})
+			continue
+		}
+
+		matched[j] = true
+		if idx, ok := moved[i]; ok {
+			out = append(out, Change{Kind: ChangeMoved, Section: s.ID, From: idx[0], To: idx[1]})
+		}
+
+		out = append(out, diffProperties(s.ID, s.Props, to[j].Props)...)
+	}
+
+	for j, s := range to {
+		if !matched[j] {
+			out = append(out, Change{Kind: ChangeAdded, Section: s.ID, To: s.Props})
+		}
+	}
+
+	return out
+}
+
+// Unified renders the changes in a unified diff like format,
+// with a `@@ section @@` header for each changed section.
+func (cc Changes) Unified() string {
+	var sb strings.Builder
+
+	writeProps := func(prefix string, v any) {
+		props, _ := v.(property.Properties)
+		for _, p := range props {
+			writeDiffValue(&sb, prefix, p.Key, p.Value)
+		}
+	}
+
+	var section string
+	for _, c := range cc {
+		if c.Section != section {
+			header := c.Section
+			if c.Key == "" {
+				if c.Kind == ChangeMoved {
+					header += fmt.Sprintf(" (moved %v -> %v)", c.From, c.To)
+				} else {
+					header += " (" + string(c.Kind) + ")"
+				}
+			}
+
+			fmt.Fprintf(&sb, "@@ %s @@\n", header)
+			section = c.Section
+		}
+
+		switch {
+		case c.Kind == ChangeAdded && c.Key == "":
+			writeProps("+", c.To)
+		case c.Kind == ChangeRemoved && c.Key == "":
+			writeProps("-", c.From)
+		case c.Kind == ChangeAdded:
+			writeDiffValue(&sb, "+", c.Key, c.To)
+		case c.Kind == ChangeRemoved:
+			writeDiffValue(&sb, "-", c.Key, c.From)
+		case c.Kind == ChangeChanged:
+			writeDiffValue(&sb, "-", c.Key, c.From)
+			writeDiffValue(&sb, "+", c.Key, c.To)
+		}
+	}
+
+	return sb.String()
+}
+
+func writeDiffValue(sb *strings.Builder, prefix, key string, v any) {
+	values, ok := v.([]any)
+	if !ok {
+		values = []any{v}
+	}
+
+	for _, v := range values {
+		fmt.Fprintf(sb, "%s%s: %s\n", prefix, key, strings.TrimSuffix(stringFromAny(v), "\n"))
+	}
+}
+
+// diffSection is a section with properties that can be compared.
+type diffSection struct {
+	// Group of sections whose order matters.
+	// Empty when the order does not matter.
+	Group string
+	ID    string
+	// Parent ID of processors, which is part of their ID.
+	Parent string
+	// Key of sections with a positional ID,
+	// which are matched by content among those with the same key.
+	// For example: input:tail
+	Key   string
+	Props property.Properties
+}
+
+func (c Config) diffSections() []diffSection {
+	var out []diffSection
+
+	if len(c.Env) != 0 {
+		out = append(out, diffSection{ID: "env", Props: c.Env})
+	}
+
+	if len(c.Includes) != 0 {
+		out = append(out, diffSection{ID: "includes", Props: property.Properties{{Key: "include", Value: anySlice(c.Includes)}}})
+	}
+
+	if len(c.Service) != 0 {
+		out = append(out, diffSection{ID: string(SectionKindService), Props: c.Service})
+	}
+
+	if len(c.ExternalPlugins) != 0 {
+		out = append(out, diffSection{ID: string(SectionKindPlugins), Props: externalPluginsProperties(c.ExternalPlugins)})
+	}
+
+	for _, ref := range c.pluginRefs() {
+		s := diffSection{Group: string(ref.Kind), ID: ref.ID, Props: ref.Plugin.Properties}
+		if ref.Kind == SectionKindProcessor {
+			// Processors are ordered within the signal of their plugin.
+			i := strings.LastIndex(ref.ID, "/")
+			signal, _, _ := strings.Cut(ref.ID[i+1:], ":")
+			s.Group = ref.ID[:i+1] + signal
+			s.Parent = ref.ID[:i]
+		}
+		if ExplicitID(ref.Plugin.Properties) == "" && Alias(ref.Plugin.Properties) == "" {
+			s.Key = strings.TrimSuffix(ref.ID, ":"+ref.Plugin.ID)
+		}
+		out = append(out, s)
+	}
+
+	for _, parser := range c.MultilineParsers {
+		props, err := parser.allProperties(false /* classic */)
+		if err != nil {
+			props = parser.Properties
+		}
+		out = append(out, diffSection{
+			Group: string(SectionKindMultilineParser),
+			ID:    fmt.Sprintf("%s:%s:%s", SectionKindMultilineParser, parser.Name, parser.ID),
+			Key:   fmt.Sprintf("%s:%s", SectionKindMultilineParser, parser.Name),
+			Props: props,
+		})
+	}
+
+	for _, upstream := range c.Upstreams {
+		id := fmt.Sprintf("%s:%s", SectionKindUpstream, upstream.Name)
+		out = append(out, diffSection{
+			Group: string(SectionKindUpstream),
+			ID:    id,
+			Props: property.Properties{{Key: "name", Value: upstream.Name}},
+		})
+		for _, node := range upstream.Nodes {
+			out = append(out, diffSection{Group: id, ID: id + ":" + node.ID, Key: id + ":" + node.Name, Props: node.Properties})
+		}
+	}
+
+	for _, task := range c.StreamTasks {
+		out = append(out, diffSection{
+			Group: string(SectionKindStreamTask),
+			ID:    fmt.Sprintf("%s:%s", SectionKindStreamTask, task.Name),
+			Props: property.Properties{{Key: "name", Value: task.Name}, {Key: "exec", Value: task.Exec}},
+		})
+	}
+
+	return out
+}
+
+// matchSections returns for each section of from
+// the index of its match in to, or -1.
+// Plugins are matched before their processors,
+// whose IDs are then translated to those of the matched plugins.
+func matchSections(from, to []diffSection) []int {
+	out := make([]int, len(from))
+	for i := range out {
+		out[i] = -1
+	}
+
+	toByID := map[string]int{}
+	for j, s := range to {
+		if s.Key == "" {
+			toByID[s.ID] = j
+		}
+	}
+
+	parents := map[string]string{}
+	translate := func(s diffSection, v string) (string, bool) {
+		if s.Parent == "" {
+			return v, true
+		}
+
+		parent, ok := parents[s.Parent]
+		return parent + strings.TrimPrefix(v, s.Parent), ok
+	}
+
+	for _, children := range []bool{false, true} {
+		var keys []string
+		fromByKey, toByKey := map[string][]int{}, map[string][]int{}
+		for i, s := range from {
+			if (s.Parent != "") != children {
+				continue
+			}
+
+			if s.Key == "" {
+				if id, ok := translate(s, s.ID); ok {
+					if j, ok := toByID[id]; ok {
+						out[i] = j
+					}
+				}
+				continue
+			}
+
+			if key, ok := translate(s, s.Key); ok {
+				if _, ok := fromByKey[key]; !ok {
+					keys = append(keys, key)
+				}
+				fromByKey[key] = append(fromByKey[key], i)
+			}
+		}
+		for j, s := range to {
+			if s.Key != "" && (s.Parent != "") == children {
+				toByKey[s.Key] = append(toByKey[s.Key], j)
+			}
+		}
+
+		for _, key := range keys {
+			matchByContent(from, to, fromByKey[key], toByKey[key], out)
+		}
+
+		for i, j := range out {
+			if j >= 0 {
+				parents[from[i].ID] = to[j].ID
+			}
+		}
+	}
+
+	return out
+}
+
+// matchByContent matches the given sections of from and to
+// that have the same key.
+// Equal sections are matched first keeping their relative order,
+// then equal ones that moved, and the rest in order as changed ones.
+func matchByContent(from, to []diffSection, fromIdx, toIdx []int, out []int) {
+	content := func(sections []diffSection, idx []int) [][]string {
+		keys := make([][]string, len(idx))
+		for k, i := range idx {
+			for _, p := range sections[i].Props {
+				keys[k] = append(keys[k], strings.ToLower(p.Key)+"="+stringFromAny(p.Value))
+			}
+		}
+		return keys
+	}
+
+	fromContent, toContent := content(from, fromIdx), content(to, toIdx)
+	used := make([]bool, len(fromIdx))
+	done := make([]bool, len(toIdx))
+	for k, m := range lcsMatches(fromContent, toContent) {
+		if m >= 0 {
+			out[fromIdx[m]] = toIdx[k]
+			used[m], done[k] = true, true
+		}
+	}
+
+	pair := func(equal bool) {
+		for k := range toIdx {
+			if done[k] {
+				continue
+			}
+
+			for m := range fromIdx {
+				if !used[m] && (!equal || slices.Equal(fromContent[m], toContent[k])) {
+					out[fromIdx[m]] = toIdx[k]
+					used[m], done[k] = true, true
+					break
+				}
+			}
+		}
+	}
+	pair(true)
+	pair(false)
+}
+
+// movedSections returns the indexes within their group, from and to,
+// of the matched sections whose relative order changed,
+// keyed by their index in from.
+// The longest common subsequence of them is considered in place.
+func movedSections(from, to []diffSection, matches []int) map[int][2]int {
+	groupIndexes := func(sections []diffSection) []int {
+		counts := map[string]int{}
+		out := make([]int, len(sections))
+		for i, s := range sections {
+			out[i] = counts[s.Group]
+			counts[s.Group]++
+		}
+		return out
+	}
+	fromIndexes, toIndexes := groupIndexes(from), groupIndexes(to)
+
+	reverse := make([]int, len(to))
+	for j := range reverse {
+		reverse[j] = -1
+	}
+	for i, j := range matches {
+		if j >= 0 {
+			reverse[j] = i
+		}
+	}
+
+	// Matched sections of each group of to,
+	// in the order of from and in the order of to.
+	var groups []string
+	inFrom, inTo := map[string][][]string{}, map[string][][]string{}
+	for i, s := range from {
+		j := matches[i]
+		if j < 0 || s.Group == "" {
+			continue
+		}
+
+		group := to[j].Group
+		if _, ok := inFrom[group]; !ok {
+			groups = append(groups, group)
+		}
+		inFrom[group] = append(inFrom[group], []string{strconv.Itoa(i)})
+	}
+	for j, s := range to {
+		if i := reverse[j]; i >= 0 && s.Group != "" {
+			inTo[s.Group] = append(inTo[s.Group], []string{strconv.Itoa(i)})
+		}
+	}
+
+	out := map[int][2]int{}
+	for _, group := range groups {
+		for k, m := range lcsMatches(inFrom[group], inTo[group]) {
+			if m < 0 {
+				i, _ := strconv.Atoi(inTo[group][k][0])
+				out[i] = [2]int{fromIndexes[i], toIndexes[matches[i]]}
+			}
+		}
+	}
+
+	return out
+}
+
+// diffProperties compares properties by lowercased key,
+// with repeated keys compared as a list of values.
+func diffProperties(section string, from, to property.Properties) Changes {
+	fromKeys, fromValues := groupProperties(from)
+	toKeys, toValues := groupProperties(to)
+
+	var out Changes
+	for _, key := range fromKeys {
+		a := fromValues[strings.ToLower(key)]
+		b, ok := toValues[strings.ToLower(key)]
+		switch {
+		case !ok:
+			out = append(out, Change{Kind: ChangeRemoved, Section: section, Key: key, From: a})
+		case stringFromAny(a) != stringFromAny(b):
+			out = append(out, Change{Kind: ChangeChanged, Section: section, Key: key, From: a, To: b})
+		}
+	}
+
+	for _, key := range toKeys {
+		if _, ok := fromValues[strings.ToLower(key)]; !ok {
+			out = append(out, Change{Kind: ChangeAdded, Section: section, Key: key, To: toValues[strings.ToLower(key)]})
+		}
+	}
+
+	return out
+}
+
+// groupProperties returns the keys in the order they were first seen,
+// and the values by lowercased key.
+// Repeated keys have their values joined in a list.
+func groupProperties(props property.Properties) ([]string, map[string]any) {
+	var keys []string
+	values := map[string]any{}
+	for _, p := range props {
+		key := strings.ToLower(p.Key)
+		v, ok := values[key]
+		if !ok {
+			keys = append(keys, p.Key)
+			values[key] = p.Value
+			continue
+		}
+
+		if list, ok := v.([]any); ok {
+			values[key] = append(list, p.Value)
+		} else {
+			values[key] = []any{v, p.Value}
+		}
+	}
+
+	return keys, values
+}
+
+func anySlice(ss []string) []any {
+	out := make([]any, len(ss))
+	for i, s := range ss {
+		out[i] = s
+	}
+	return out
+}
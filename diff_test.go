@@ -0,0 +1,201 @@
+package fluentbitconfig
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+func TestDiff(t *testing.T) {
+	a, err := ParseAs(configLiteral(`
+		[SERVICE]
+			Flush     1
+			Log_Level info
+
+		[INPUT]
+			Name  tail
+			Alias app_logs
+			Path  /var/log/app.log
+
+		[FILTER]
+			Name    grep
+			Alias   drop_debug
+			Exclude level debug
+
+		[FILTER]
+			Name   modify
+			Alias  add_env
+			Add    env prod
+
+		[OUTPUT]
+			Name  stdout
+			Match *
+	`), FormatClassic)
+	require.NoError(t, err)
+
+	b, err := ParseAs(configLiteral(`
+		service:
+			flush: 1
+			log_level: debug
+		pipeline:
+			inputs:
+				- name: tail
+				  alias: app_logs
+				  path: /var/log/app.log
+				  path_key: filename
+			filters:
+				- name: modify
+				  alias: add_env
+				  add: env prod
+				- name: grep
+				  alias: drop_debug
+			outputs:
+				- name: forward
+				  match: '*'
+	`), FormatYAML)
+	require.NoError(t, err)
+
+	changes := Diff(a, b)
+	require.Equal(t, Changes{
+		{Kind: ChangeChanged, Section: "service", Key: "Log_Level", From: "info", To: "debug"},
+		{Kind: ChangeAdded, Section: "input:tail:app_logs", Key: "path_key", To: "filename"},
+		{Kind: ChangeMoved, Section: "filter:grep:drop_debug", From: 0, To: 1},
+		{Kind: ChangeRemoved, Section: "filter:grep:drop_debug", Key: "Exclude", From: "level debug"},
+		{Kind: ChangeRemoved, Section: "output:stdout:stdout.0", From: property.Properties{
+			{Key: "Name", Value: "stdout", Pos: property.Position{Line: 21, Column: 5}},
+			{Key: "Match", Value: "*", Pos: property.Position{Line: 22, Column: 5}},
+		}},
+		{Kind: ChangeAdded, Section: "output:forward:forward.0", To: property.Properties{
+			{Key: "name", Value: "forward", Pos: property.Position{Line: 17, Column: 11}},
+			{Key: "match", Value: "*", Pos: property.Position{Line: 18, Column: 11}},
+		}},
+	}, changes)
+
+	require.Equal(t, configLiteral(`
+		@@ service @@
+		-Log_Level: info
+		+Log_Level: debug
+		@@ input:tail:app_logs @@
+		+path_key: filename
+		@@ filter:grep:drop_debug (moved 0 -> 1) @@
+		-Exclude: level debug
+		@@ output:stdout:stdout.0 (removed) @@
+		-Name: stdout
+		-Match: *
+		@@ output:forward:forward.0 (added) @@
+		+name: forward
+		+match: *
+	`), changes.Unified())
+
+	got, err := json.Marshal(changes[2:4])
+	require.NoError(t, err)
+	require.JSONEq(t, `[
+		{"kind": "moved", "section": "filter:grep:drop_debug", "from": 0, "to": 1},
+		{"kind": "removed", "section": "filter:grep:drop_debug", "key": "Exclude", "from": "level debug"}
+	]`, string(got))
+
+	require.Empty(t, Diff(a, a))
+	require.Empty(t, Diff(b, b))
+}
+
+func TestDiff_repeatedKeys(t *testing.T) {
+	a, err := ParseAs(configLiteral(`
+		[PLUGINS]
+			Path /a.so
+		[INPUT]
+			Name dummy
+	`), FormatClassic)
+	require.NoError(t, err)
+
+	b := a.clone()
+	b.ExternalPlugins = append(b.ExternalPlugins, "/b.so")
+
+	changes := Diff(a, b)
+	require.Equal(t, Changes{{
+		Kind:    ChangeChanged,
+		Section: "plugins",
+		Key:     "Path",
+		From:    []any{"/a.so"},
+		To:      []any{"/a.so", "/b.so"},
+	}}, changes)
+	require.Equal(t, "@@ plugins @@\n-Path: /a.so\n+Path: /a.so\n+Path: /b.so\n", changes.Unified())
+}
+
+func TestDiff_positional(t *testing.T) {
+	a, err := ParseAs(configLiteral(`
+		pipeline:
+			inputs:
+				- name: tail
+				  path: /var/log/a.log
+				- name: tail
+				  path: /var/log/b.log
+				  processors:
+					logs:
+						- name: content_modifier
+						  action: insert
+			filters:
+				- name: grep
+				  exclude: level debug
+				- name: modify
+				  add: env prod
+	`), FormatYAML)
+	require.NoError(t, err)
+
+	t.Run("swap", func(t *testing.T) {
+		b := a.clone()
+		require.NoError(t, b.MoveByID("filter:modify:modify.1", 0))
+
+		require.Equal(t, Changes{
+			{Kind: ChangeMoved, Section: "filter:grep:grep.0", From: 0, To: 1},
+		}, Diff(a, b))
+	})
+
+	t.Run("leading_removal", func(t *testing.T) {
+		b := a.clone()
+		require.NoError(t, b.RemoveByID("input:tail:tail.0"))
+
+		changes := Diff(a, b)
+		require.Len(t, changes, 1)
+		require.Equal(t, ChangeRemoved, changes[0].Kind)
+		require.Equal(t, "input:tail:tail.0", changes[0].Section)
+	})
+
+	t.Run("same_name_swap", func(t *testing.T) {
+		b := a.clone()
+		require.NoError(t, b.MoveByID("input:tail:tail.1", 0))
+		_, err := b.UpdateProperties("input:tail:tail.1", func(props *property.Properties) error {
+			props.Set("path", "/var/log/c.log")
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, Changes{
+			{Kind: ChangeMoved, Section: "input:tail:tail.0", From: 0, To: 1},
+			{Kind: ChangeChanged, Section: "input:tail:tail.0", Key: "path", From: "/var/log/a.log", To: "/var/log/c.log"},
+		}, Diff(a, b))
+	})
+
+	t.Run("processor_of_moved_plugin", func(t *testing.T) {
+		b := a.clone()
+		require.NoError(t, b.RemoveByID("input:tail:tail.0"))
+		_, err := b.UpdateProperties("input:tail:tail.0/logs:content_modifier:content_modifier.0", func(props *property.Properties) error {
+			props.Set("action", "upsert")
+			return nil
+		})
+		require.NoError(t, err)
+
+		changes := Diff(a, b)
+		require.Len(t, changes, 2)
+		require.Equal(t, "input:tail:tail.0", changes[0].Section)
+		require.Equal(t, Change{
+			Kind:    ChangeChanged,
+			Section: "input:tail:tail.1/logs:content_modifier:content_modifier.0",
+			Key:     "action",
+			From:    "insert",
+			To:      "upsert",
+		}, changes[1])
+	})
+}
@@ -1,13 +1,8 @@
 package fluentbitconfig
 
 import (
-	"bytes"
-	"encoding/json"
 	"errors"
-	"io"
 	"strings"
-
-	"gopkg.in/yaml.v3"
 )
 
 var ErrFormatUnknown = errors.New("format unknown")
@@ -40,25 +35,13 @@ func ParseAsClassic(raw string) (Config, error) {
 
 func ParseAsYAML(raw string) (Config, error) {
 	var out Config
-	dec := yaml.NewDecoder(strings.NewReader(raw))
-	dec.KnownFields(true)
-	err := dec.Decode(&out)
-	if errors.Is(err, io.EOF) {
-		return out, nil
-	}
-
+	err := NewDecoder(strings.NewReader(raw), FormatYAML).Decode(&out)
 	return out, err
 }
 
 func ParseAsJSON(raw string) (Config, error) {
 	var out Config
-	dec := json.NewDecoder(strings.NewReader(raw))
-	dec.DisallowUnknownFields()
-	err := dec.Decode(&out)
-	if errors.Is(err, io.EOF) {
-		return out, nil
-	}
-
+	err := NewDecoder(strings.NewReader(raw), FormatJSON).Decode(&out)
 	return out, err
 }
 
@@ -75,7 +58,7 @@ func (c Config) DumpAs(format Format) (string, error) {
 }
 
 func (c Config) DumpAsClassic() (string, error) {
-	b, err := c.MarshalClassic()
+	b, err := c.withoutExplicitIDs().MarshalClassic()
 	if err != nil {
 		return "", err
 	}
@@ -84,22 +67,33 @@ func (c Config) DumpAsClassic() (string, error) {
 }
 
 func (c Config) DumpAsYAML() (string, error) {
-	b, err := yaml.Marshal(c)
-	if err != nil {
+	var sb strings.Builder
+	if err := NewEncoder(&sb, FormatYAML).Encode(c.withoutExplicitIDs()); err != nil {
 		return "", err
 	}
 
-	return string(b), nil
+	return sb.String(), nil
 }
 
 func (c Config) DumpAsJSON() (string, error) {
-	var buff bytes.Buffer
-	enc := json.NewEncoder(&buff)
-	enc.SetEscapeHTML(false)
-	err := enc.Encode(c)
-	if err != nil {
+	var sb strings.Builder
+	if err := NewEncoder(&sb, FormatJSON).Encode(c.withoutExplicitIDs()); err != nil {
 		return "", err
 	}
 
-	return buff.String(), nil
+	return sb.String(), nil
+}
+
+// withoutExplicitIDs returns a copy of the config
+// without the IDKey property on plugins,
+// as Fluent Bit rejects unknown properties.
+func (c Config) withoutExplicitIDs() Config {
+	c = c.clone()
+	for _, ref := range c.pluginRefs() {
+		if ExplicitID(ref.Plugin.Properties) != "" {
+			ref.Plugin.Properties = withoutKey(ref.Plugin.Properties, IDKey)
+		}
+	}
+
+	return c
 }
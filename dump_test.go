@@ -41,6 +41,40 @@ func TestParseAsYAML(t *testing.T) {
 		require.EqualError(t, err, "yaml: unmarshal errors:\n  line 2: field foos not found in type fluentbitconfig.Pipeline")
 	})
 
+	t.Run("top_level_sections", func(t *testing.T) {
+		cfg, err := ParseAsYAML(configLiteral(`
+			multiline_parsers:
+				- name: multiline-regex-test
+				  type: regex
+				  rules:
+					- state: start_state
+					  regex: /^\d+/
+					  next_state: cont
+			plugins:
+				- /opt/plugins/out_gstdout.so
+			upstream_servers:
+				- name: forward-balancing
+				  nodes:
+					- name: node-1
+					  host: 127.0.0.1
+					  port: 43000
+			stream_processor:
+				- name: results
+				  exec: SELECT * FROM TAG:'cpu.*';
+		`))
+		require.NoError(t, err)
+		require.Equal(t, []string{"multiline-regex-test.0"}, cfg.MultilineParsers.IDs())
+		require.Equal(t, []string{"/opt/plugins/out_gstdout.so"}, cfg.ExternalPlugins)
+		require.Len(t, cfg.Upstreams, 1)
+		require.Equal(t, "forward-balancing", cfg.Upstreams[0].Name)
+		require.Equal(t, []string{"node-1.0"}, cfg.Upstreams[0].Nodes.IDs())
+		require.Equal(t, StreamTasks{{
+			Name: "results",
+			Exec: "SELECT * FROM TAG:'cpu.*';",
+			Pos:  property.Position{Line: 17, Column: 7},
+		}}, cfg.StreamTasks)
+	})
+
 	t.Run("empty", func(t *testing.T) {
 		got, err := ParseAsYAML("")
 		require.NoError(t, err)
@@ -71,7 +105,7 @@ func TestParseAsYAML(t *testing.T) {
 					},
 				},
 			},
-		}, cfg)
+		}, withoutPositions(cfg))
 	})
 }
 
@@ -3,10 +3,15 @@ package fluentbitconfig
 import (
 	"errors"
 	"fmt"
+	"strings"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
 )
 
 var ErrMissingName = errors.New("missing name property")
 
+var errNodeWithoutUpstream = errors.New("node section without a preceding upstream section")
+
 // LinedError with information about the line number
 // where the error was found while parsing.
 type LinedError struct {
@@ -26,6 +31,57 @@ func WrapLinedError(err error, line uint) *LinedError {
 	return &LinedError{Msg: err.Error(), Line: line}
 }
 
+// ValidationError with the position of the section or property
+// that did not pass validation.
+// The message is the one of the wrapped error
+// prefixed with the position when known.
+// For example: fluent-bit.conf:3:5: input: cpu: unknown property "foo"
+type ValidationError struct {
+	Pos property.Position
+	Err error
+}
+
+// withPos wraps the error with the given position if known,
+// unless it already has one.
+func withPos(err error, pos property.Position) error {
+	if err == nil || !pos.IsValid() {
+		return err
+	}
+
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		return err
+	}
+
+	return &ValidationError{Pos: pos, Err: err}
+}
+
+func (e *ValidationError) Error() string {
+	if !e.Pos.IsValid() {
+		return e.Err.Error()
+	}
+
+	return fmt.Sprintf("%s: %v", e.Pos, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// UndefinedVariableError is reported by Config.Expand
+// for each `${VAR}` reference that could not be resolved.
+type UndefinedVariableError struct {
+	Name string
+	// Section referencing the variable. For example: input:tail:tail.0
+	Section string
+	Key     string
+	Pos     property.Position
+}
+
+func (e *UndefinedVariableError) Error() string {
+	return fmt.Sprintf("%s: %s: undefined variable %q", e.Section, e.Key, e.Name)
+}
+
 type UnknownPluginError struct {
 	Kind SectionKind
 	Name string
@@ -38,3 +94,41 @@ func NewUnknownPluginError(kind SectionKind, name string) *UnknownPluginError {
 func (e *UnknownPluginError) Error() string {
 	return fmt.Sprintf("%s: unknown plugin %q", e.Kind, e.Name)
 }
+
+// PluginNotFoundError is returned by the Config mutation methods
+// when no plugin or processor has the given ID.
+type PluginNotFoundError struct {
+	ID string
+}
+
+func (e *PluginNotFoundError) Error() string {
+	if !strings.Contains(e.ID, ":") {
+		return fmt.Sprintf("plugin %q not found: IDs must be namespaced, for example input:tail:tail.0", e.ID)
+	}
+
+	return fmt.Sprintf("plugin %q not found", e.ID)
+}
+
+// UnresolvedVariableError is reported by Config.ResolveVariables
+// for each cloud variable reference the resolver could not resolve.
+type UnresolvedVariableError struct {
+	// Name of the variable. For example: secrets.api_key
+	Name string
+	// Section referencing the variable. For example: input:tail:tail.0
+	Section string
+	Key     string
+	Pos     property.Position
+	Err     error
+}
+
+func (e *UnresolvedVariableError) Error() string {
+	if errors.Is(e.Err, ErrVariableNotFound) {
+		return fmt.Sprintf("%s: %s: undefined variable %q", e.Section, e.Key, e.Name)
+	}
+
+	return fmt.Sprintf("%s: %s: resolve variable %q: %v", e.Section, e.Key, e.Name, e.Err)
+}
+
+func (e *UnresolvedVariableError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,187 @@
+package fluentbitconfig
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+// reEnvVariable matches `${VAR}` references.
+var reEnvVariable = regexp.MustCompile(`\$\{([^${}]+)\}`)
+
+func isEnvVariable(val any) bool {
+	s, ok := val.(string)
+	if !ok {
+		return false
+	}
+
+	return reEnvVariable.MatchString(s)
+}
+
+// Expand returns a copy of the config with the `${VAR}` references
+// on property values replaced.
+// Like fluent-bit does, variables defined on the config env (`@SET`)
+// take precedence over the given environment, which usually comes from
+// the process one.
+// Undefined variables are replaced with an empty string and reported
+// as UndefinedVariableError, joined on the returned error.
+// The expanded config is returned even when there are undefined variables,
+// so it can still be validated with ValidateWithSchema.
+func (c Config) Expand(env map[string]string) (Config, error) {
+	out := c.clone()
+
+	lookup := func(name string) (string, bool) {
+		for _, p := range out.Env {
+			if p.Key == name {
+				if s, ok := p.Value.(string); ok {
+					return s, true
+				}
+				return stringFromAny(p.Value), true
+			}
+		}
+
+		v, ok := env[name]
+		return v, ok
+	}
+
+	var errs []error
+	for _, ref := range out.propertiesRefs() {
+		for i, p := range *ref.Props {
+			var undefined []string
+			(*ref.Props)[i].Value = expandValue(p.Value, func(name string) string {
+				v, ok := lookup(name)
+				if !ok && !slices.Contains(undefined, name) {
+					undefined = append(undefined, name)
+				}
+				return v
+			})
+
+			for _, name := range undefined {
+				errs = append(errs, &UndefinedVariableError{
+					Name:    name,
+					Section: ref.String(),
+					Key:     p.Key,
+					Pos:     p.Pos,
+				})
+			}
+		}
+	}
+
+	// The name itself might have been a reference.
+	out.refreshPlugins()
+
+	return out, errors.Join(errs...)
+}
+
+// Variables returns the sorted names of all the `${VAR}` references
+// found on property values.
+// This includes variables also defined on the config env.
+func (c Config) Variables() []string {
+	seen := map[string]bool{}
+	for _, ref := range c.propertiesRefs() {
+		for _, p := range *ref.Props {
+			expandValue(p.Value, func(name string) string {
+				seen[name] = true
+				return ""
+			})
+		}
+	}
+
+	out := make([]string, 0, len(seen))
+	for name := range seen {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+
+	if len(out) == 0 {
+		return nil
+	}
+
+	return out
+}
+
+// expandValue returns a copy of the value with the variable references
+// on strings replaced, recursing into lists and maps.
+func expandValue(v any, fn func(name string) string) any {
+	switch v := v.(type) {
+	case string:
+		if !reEnvVariable.MatchString(v) {
+			return v
+		}
+
+		return reEnvVariable.ReplaceAllStringFunc(v, func(ref string) string {
+			return fn(reEnvVariable.FindStringSubmatch(ref)[1])
+		})
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = expandValue(item, fn)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			out[key] = expandValue(item, fn)
+		}
+		return out
+	}
+
+	return v
+}
+
+// propertiesRef points to the properties of a config section.
+type propertiesRef struct {
+	Kind SectionKind
+	// ID of the section, namespaced with its kind.
+	// Empty for the service section.
+	ID    string
+	Props *property.Properties
+}
+
+func (ref propertiesRef) String() string {
+	if ref.ID == "" {
+		return string(ref.Kind)
+	}
+
+	return ref.ID
+}
+
+// propertiesRefs returns all the sections with properties,
+// in the same order they are written.
+// Env is not included as fluent-bit does not expand its values.
+func (c *Config) propertiesRefs() []propertiesRef {
+	var out []propertiesRef
+
+	if len(c.Service) != 0 {
+		out = append(out, propertiesRef{Kind: SectionKindService, Props: &c.Service})
+	}
+
+	for _, ref := range c.pluginRefs() {
+		out = append(out, propertiesRef{Kind: ref.Kind, ID: ref.ID, Props: &ref.Plugin.Properties})
+	}
+
+	for i := range c.MultilineParsers {
+		parser := &c.MultilineParsers[i]
+		out = append(out, propertiesRef{
+			Kind:  SectionKindMultilineParser,
+			ID:    fmt.Sprintf("%s:%s:%s", SectionKindMultilineParser, parser.Name, parser.ID),
+			Props: &parser.Properties,
+		})
+	}
+
+	for _, upstream := range c.Upstreams {
+		for i := range upstream.Nodes {
+			out = append(out, propertiesRef{
+				Kind:  SectionKindNode,
+				ID:    fmt.Sprintf("%s:%s:%s", SectionKindUpstream, upstream.Name, upstream.Nodes[i].ID),
+				Props: &upstream.Nodes[i].Properties,
+			})
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,116 @@
+package fluentbitconfig
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+func TestConfig_Expand(t *testing.T) {
+	cfg, err := ParseAs(configLiteral(`
+		@SET HTTP_PORT=2021
+		[SERVICE]
+			http_port ${HTTP_PORT}
+		[INPUT]
+			name ${INPUT_NAME}
+			port ${PORT}
+			tag  ${PREFIX}.${PORT}
+		[OUTPUT]
+			name  stdout
+			match ${PREFIX}.*
+			format ${FORMAT}
+	`), FormatClassic)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"FORMAT", "HTTP_PORT", "INPUT_NAME", "PORT", "PREFIX"}, cfg.Variables())
+
+	t.Run("precedence", func(t *testing.T) {
+		got, err := cfg.Expand(map[string]string{
+			"HTTP_PORT":  "8080",
+			"INPUT_NAME": "tcp",
+			"PORT":       "5170",
+			"PREFIX":     "app",
+			"FORMAT":     "json_lines",
+		})
+		require.NoError(t, err)
+
+		v, _ := got.Service.Get("http_port")
+		require.Equal(t, "2021", v)
+
+		input := got.Pipeline.Inputs[0]
+		require.Equal(t, "tcp", input.Name)
+		require.Equal(t, []string{"input:tcp:tcp.0", "output:stdout:stdout.0"}, got.IDs(true))
+		require.Equal(t, property.Properties{
+			{Key: "name", Value: "tcp", Pos: property.Position{Line: 5, Column: 5}},
+			{Key: "port", Value: "5170", Pos: property.Position{Line: 6, Column: 5}},
+			{Key: "tag", Value: "app.5170", Pos: property.Position{Line: 7, Column: 5}},
+		}, input.Properties)
+
+		require.NoError(t, got.Validate())
+
+		// The original config is left untouched.
+		require.Equal(t, "${input_name}", cfg.Pipeline.Inputs[0].Name)
+		require.Equal(t, "${input_name}.0", cfg.Pipeline.Inputs[0].ID)
+		v, _ = cfg.Pipeline.Inputs[0].Properties.Get("port")
+		require.Equal(t, "${PORT}", v)
+	})
+
+	t.Run("undefined", func(t *testing.T) {
+		got, err := cfg.Expand(map[string]string{
+			"INPUT_NAME": "tcp",
+			"PORT":       "5170",
+		})
+		require.EqualError(t, err, "input:${input_name}:${input_name}.0: tag: undefined variable \"PREFIX\"\n"+
+			"output:stdout:stdout.0: match: undefined variable \"PREFIX\"\n"+
+			"output:stdout:stdout.0: format: undefined variable \"FORMAT\"")
+
+		var undefinedErr *UndefinedVariableError
+		require.True(t, errors.As(err, &undefinedErr))
+		require.Equal(t, &UndefinedVariableError{
+			Name:    "PREFIX",
+			Section: "input:${input_name}:${input_name}.0",
+			Key:     "tag",
+			Pos:     property.Position{Line: 7, Column: 5},
+		}, undefinedErr)
+
+		v, _ := got.Pipeline.Outputs[0].Properties.Get("match")
+		require.Equal(t, ".*", v)
+	})
+
+	t.Run("validate_expanded", func(t *testing.T) {
+		err := cfg.Validate()
+		require.EqualError(t, err, `5:5: input:${input_name}:${input_name}.0: name: undefined variable "INPUT_NAME"`)
+
+		var undefinedErr *UndefinedVariableError
+		require.ErrorAs(t, err, &undefinedErr)
+
+		got, err := cfg.Expand(map[string]string{
+			"INPUT_NAME": "tcp",
+			"PORT":       "not-a-port",
+			"PREFIX":     "app",
+			"FORMAT":     "json_lines",
+		})
+		require.NoError(t, err)
+		require.EqualError(t, got.Validate(), `6:5: input: tcp: expected "port" to be a valid integer, got not-a-port`)
+	})
+
+	t.Run("validate_env", func(t *testing.T) {
+		cfg, err := ParseAs(configLiteral(`
+			@SET HTTP_PORT=not-a-port
+			[INPUT]
+				Name http
+				Port ${HTTP_PORT}
+		`), FormatClassic)
+		require.NoError(t, err)
+		require.EqualError(t, cfg.Validate(), `4:5: input: http: expected "Port" to be a valid integer, got not-a-port`)
+
+		cfg.Env = nil
+		require.EqualError(t, cfg.Validate(), `4:5: input:http:http.0: Port: undefined variable "HTTP_PORT"`)
+
+		err = ValidateSection(SectionKindInput, cfg.Pipeline.Inputs[0].Properties)
+		require.EqualError(t, err, `4:5: input:http: Port: undefined variable "HTTP_PORT"`)
+	})
+}
@@ -0,0 +1,213 @@
+package fluentbitconfig
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DOT renders the pipeline as a Graphviz DOT digraph,
+// with inputs, filters in order, outputs and stream tasks,
+// their processors, and the routes between them labeled with their tags.
+// Nodes with route issues are highlighted.
+// See Config.RouteGraph.
+func (c Config) DOT() string {
+	g := c.pipelineGraph()
+
+	var sb strings.Builder
+	sb.WriteString("digraph pipeline {\n")
+	sb.WriteString("    rankdir=LR;\n")
+	sb.WriteString("    node [shape=box];\n")
+
+	for _, group := range g.groups {
+		if len(group.nodes) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&sb, "    subgraph cluster_%s {\n", strings.ReplaceAll(group.label, " ", "_"))
+		fmt.Fprintf(&sb, "        label=%s;\n", dotQuote(group.label))
+		for _, node := range group.nodes {
+			attrs := "label=" + dotQuote(strings.Join(node.lines, "\n"))
+			if node.issue {
+				attrs += ", color=red"
+			}
+			fmt.Fprintf(&sb, "        %s [%s];\n", dotQuote(node.id), attrs)
+		}
+		sb.WriteString("    }\n")
+	}
+
+	// Filters run in order.
+	for i := 1; i < len(g.filters); i++ {
+		fmt.Fprintf(&sb, "    %s -> %s [style=invis];\n", dotQuote(g.filters[i-1]), dotQuote(g.filters[i]))
+	}
+
+	for _, e := range g.edges {
+		fmt.Fprintf(&sb, "    %s -> %s [label=%s];\n", dotQuote(e.from), dotQuote(e.to), dotQuote(e.label))
+	}
+
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// Mermaid renders the pipeline as a Mermaid flowchart.
+// See Config.DOT.
+func (c Config) Mermaid() string {
+	g := c.pipelineGraph()
+
+	ids := map[string]string{}
+	var sb strings.Builder
+	sb.WriteString("flowchart LR\n")
+
+	var issues []string
+	for _, group := range g.groups {
+		if len(group.nodes) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&sb, "    subgraph %s\n", strings.ReplaceAll(group.label, " ", "_"))
+		for _, node := range group.nodes {
+			id := fmt.Sprintf("n%d", len(ids))
+			ids[node.id] = id
+			fmt.Fprintf(&sb, "        %s[%s]\n", id, mermaidQuote(strings.Join(node.lines, "<br/>")))
+			if node.issue {
+				issues = append(issues, id)
+			}
+		}
+		sb.WriteString("    end\n")
+	}
+
+	for _, e := range g.edges {
+		fmt.Fprintf(&sb, "    %s -->|%s| %s\n", ids[e.from], mermaidQuote(e.label), ids[e.to])
+	}
+
+	if len(issues) != 0 {
+		sb.WriteString("    classDef issue stroke:#f00\n")
+		fmt.Fprintf(&sb, "    class %s issue\n", strings.Join(issues, ","))
+	}
+
+	return sb.String()
+}
+
+type pipelineGraph struct {
+	groups  []graphGroup
+	filters []string
+	edges   []graphEdge
+}
+
+type graphGroup struct {
+	label string
+	nodes []graphNode
+}
+
+type graphNode struct {
+	id    string
+	lines []string
+	issue bool
+}
+
+type graphEdge struct {
+	from, to string
+	label    string
+}
+
+// pipelineGraph lays out the route graph by section kind,
+// with edges between the same nodes merged.
+func (c Config) pipelineGraph() pipelineGraph {
+	routes := c.RouteGraph()
+
+	plugins := map[string]Plugin{}
+	for _, ref := range c.pluginRefs() {
+		if ref.Kind != SectionKindProcessor {
+			plugins[ref.ID] = *ref.Plugin
+		}
+	}
+
+	issues := map[string]bool{}
+	for _, issue := range routes.Issues {
+		issues[issue.ID] = true
+	}
+
+	kinds := []SectionKind{SectionKindInput, SectionKindFilter, SectionKindOutput, SectionKindStreamTask}
+
+	var out pipelineGraph
+	for _, kind := range kinds {
+		out.groups = append(out.groups, graphGroup{label: strings.ReplaceAll(string(kind), "_", " ") + "s"})
+	}
+
+	for _, node := range routes.Nodes {
+		group := &out.groups[slices.Index(kinds, node.Kind)]
+		group.nodes = append(group.nodes, graphNode{
+			id:    node.ID,
+			lines: graphNodeLines(node, plugins[node.ID]),
+			issue: issues[node.ID],
+		})
+
+		if node.Kind == SectionKindFilter {
+			out.filters = append(out.filters, node.ID)
+		}
+	}
+
+	index := map[[2]string]int{}
+	for _, e := range routes.Edges {
+		key := [2]string{e.From, e.To}
+		if i, ok := index[key]; ok {
+			out.edges[i].label += ", " + e.Tag
+			continue
+		}
+
+		index[key] = len(out.edges)
+		out.edges = append(out.edges, graphEdge{from: e.From, to: e.To, label: e.Tag})
+	}
+
+	return out
+}
+
+// graphNodeLines returns the label of a node:
+// its ID, tags or match pattern, and processors by signal.
+func graphNodeLines(node RouteNode, plugin Plugin) []string {
+	_, id, _ := strings.Cut(node.ID, ":")
+	if plugin.Name != "" {
+		id = plugin.ID
+		if ExplicitID(plugin.Properties) != "" || Alias(plugin.Properties) != "" {
+			id = fmt.Sprintf("%s (%s)", plugin.ID, plugin.Name)
+		}
+	}
+
+	lines := []string{id}
+
+	switch {
+	case node.Match != "":
+		lines = append(lines, "match: "+node.Match)
+	case node.MatchRegex != "":
+		lines = append(lines, "match_regex: "+node.MatchRegex)
+	}
+
+	if len(node.Tags) != 0 {
+		lines = append(lines, "tag: "+strings.Join(node.Tags, ", "))
+	}
+
+	for _, signal := range processorSignals {
+		processors, _ := plugin.Processors.Signal(signal)
+		if len(*processors) == 0 {
+			continue
+		}
+
+		var names []string
+		for _, processor := range *processors {
+			names = append(names, processor.ID)
+		}
+		lines = append(lines, signal+": "+strings.Join(names, " -> "))
+	}
+
+	return lines
+}
+
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + strings.ReplaceAll(s, "\n", `\n`) + `"`
+}
+
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
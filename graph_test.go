@@ -0,0 +1,91 @@
+package fluentbitconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_DOT(t *testing.T) {
+	cfg := graphTestConfig(t)
+	require.Equal(t, configLiteral(`
+		digraph pipeline {
+		    rankdir=LR;
+		    node [shape=box];
+		    subgraph cluster_inputs {
+		        label="inputs";
+		        "input:tail:app_logs" [label="app_logs (tail)\ntag: app.*\nlogs: content_modifier.0 -> sql.1"];
+		        "input:cpu:cpu.1" [label="cpu.1\ntag: cpu.1"];
+		    }
+		    subgraph cluster_filters {
+		        label="filters";
+		        "filter:grep:grep.0" [label="grep.0\nmatch: app.*"];
+		        "filter:modify:modify.1" [label="modify.1\nmatch: *"];
+		    }
+		    subgraph cluster_outputs {
+		        label="outputs";
+		        "output:stdout:stdout.0" [label="stdout.0\nmatch: *"];
+		        "output:forward:forward.1" [label="forward.1\nmatch: nothing", color=red];
+		    }
+		    "filter:grep:grep.0" -> "filter:modify:modify.1" [style=invis];
+		    "input:tail:app_logs" -> "filter:grep:grep.0" [label="app.*"];
+		    "filter:grep:grep.0" -> "filter:modify:modify.1" [label="app.*"];
+		    "filter:modify:modify.1" -> "output:stdout:stdout.0" [label="app.*, cpu.1"];
+		    "input:cpu:cpu.1" -> "filter:modify:modify.1" [label="cpu.1"];
+		}
+	`), cfg.DOT())
+}
+
+func TestConfig_Mermaid(t *testing.T) {
+	cfg := graphTestConfig(t)
+	require.Equal(t, configLiteral(`
+		flowchart LR
+		    subgraph inputs
+		        n0["app_logs (tail)<br/>tag: app.*<br/>logs: content_modifier.0 -> sql.1"]
+		        n1["cpu.1<br/>tag: cpu.1"]
+		    end
+		    subgraph filters
+		        n2["grep.0<br/>match: app.*"]
+		        n3["modify.1<br/>match: *"]
+		    end
+		    subgraph outputs
+		        n4["stdout.0<br/>match: *"]
+		        n5["forward.1<br/>match: nothing"]
+		    end
+		    n0 -->|"app.*"| n2
+		    n2 -->|"app.*"| n3
+		    n3 -->|"app.*, cpu.1"| n4
+		    n1 -->|"cpu.1"| n3
+		    classDef issue stroke:#f00
+		    class n5 issue
+	`), cfg.Mermaid())
+}
+
+func graphTestConfig(t *testing.T) Config {
+	t.Helper()
+
+	cfg, err := ParseAs(configLiteral(`
+		pipeline:
+			inputs:
+				- name: tail
+				  alias: app_logs
+				  tag: app.*
+				  processors:
+					logs:
+						- name: content_modifier
+						- name: sql
+				- name: cpu
+			filters:
+				- name: grep
+				  match: app.*
+				- name: modify
+				  match: '*'
+			outputs:
+				- name: stdout
+				  match: '*'
+				- name: forward
+				  match: nothing
+	`), FormatYAML)
+	require.NoError(t, err)
+	return cfg
+}
@@ -0,0 +1,325 @@
+package fluentbitconfig
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+// IncludeCycleError is returned by LoadFile when a file
+// ends up including itself, directly or through other files.
+type IncludeCycleError struct {
+	// Chain of files starting and ending with the same file.
+	Chain []string
+}
+
+func (e *IncludeCycleError) Error() string {
+	return fmt.Sprintf("include cycle: %s", strings.Join(e.Chain, " -> "))
+}
+
+// LoadFile reads the config at name from fsys and resolves all its includes
+// into a single merged config.
+// Includes are resolved relative to the directory of the including file
+// and can be glob patterns like `inputs/*.conf`. Absolute paths are resolved
+// from the root of fsys.
+// The format of each file is taken from its extension: `.yaml` and `.yml` for
+// YAML, `.json` for JSON and classic otherwise, so trees mixing formats are supported.
+// Upstream files referenced by outputs and the service `streams_file`
+// are loaded too, relative to the directory of the main file.
+// Plugins and properties on the returned config record the file they came
+// from on their position.
+func LoadFile(fsys fs.FS, name string) (Config, error) {
+	l := &loader{fsys: fsys}
+
+	var out Config
+	if err := l.load(&out, cleanFSPath(name)); err != nil {
+		return Config{}, err
+	}
+
+	dir := path.Dir(cleanFSPath(name))
+	if err := l.loadUpstreamFiles(&out, dir); err != nil {
+		return Config{}, err
+	}
+
+	if err := l.loadStreamsFile(&out, dir); err != nil {
+		return Config{}, err
+	}
+
+	return out, nil
+}
+
+type loader struct {
+	fsys  fs.FS
+	stack []string
+}
+
+func (l *loader) load(dst *Config, name string) error {
+	for i, visiting := range l.stack {
+		if visiting == name {
+			chain := append([]string{}, l.stack[i:]...)
+			return &IncludeCycleError{Chain: append(chain, name)}
+		}
+	}
+
+	l.stack = append(l.stack, name)
+	defer func() { l.stack = l.stack[:len(l.stack)-1] }()
+
+	b, err := fs.ReadFile(l.fsys, name)
+	if err != nil {
+		return err
+	}
+
+	if format := formatFromPath(name); format != FormatClassic {
+		return l.loadStructured(dst, name, string(b), format)
+	}
+
+	return l.loadClassic(dst, name, string(b))
+}
+
+// formatFromPath returns the format of a file from its extension.
+func formatFromPath(name string) Format {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".json":
+		return FormatJSON
+	}
+
+	return FormatClassic
+}
+
+// loadStructured loads a YAML or JSON file.
+// Its includes are merged before the sections of the file itself.
+func (l *loader) loadStructured(dst *Config, name, raw string, format Format) error {
+	cfg, err := ParseAs(raw, format)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	for _, include := range cfg.Includes {
+		if err := l.include(dst, name, include); err != nil {
+			return err
+		}
+	}
+
+	cfg.Includes = nil
+	dst.merge(cfg, name)
+	return nil
+}
+
+// loadClassic loads a classic file.
+// Classic includes are processed inline, so the sections before an
+// `@INCLUDE` are merged before the included ones, and the ones after it, after.
+func (l *loader) loadClassic(dst *Config, name, raw string) error {
+	lines := strings.Split(raw, "\n")
+
+	var start int
+	flush := func(end int) error {
+		// Keep the preceding lines empty so line numbers on errors
+		// still match the ones from the file.
+		chunk := strings.Repeat("\n", start) + strings.Join(lines[start:end], "\n")
+		start = end + 1
+
+		var cfg Config
+		if err := cfg.UnmarshalClassic([]byte(chunk)); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		dst.merge(cfg, name)
+		return nil
+	}
+
+	for i, line := range lines {
+		cmd, instruction, ok := spaceCut(strings.TrimSpace(line))
+		if !ok || !strings.EqualFold(cmd, "@INCLUDE") {
+			continue
+		}
+
+		if err := flush(i); err != nil {
+			return err
+		}
+
+		if err := l.include(dst, name, instruction); err != nil {
+			return err
+		}
+	}
+
+	return flush(len(lines))
+}
+
+func (l *loader) include(dst *Config, from, pattern string) error {
+	pattern = resolveFSPath(path.Dir(from), strings.TrimSpace(pattern))
+
+	if !hasGlobMeta(pattern) {
+		if err := l.load(dst, pattern); err != nil {
+			return fmt.Errorf("%s: include %q: %w", from, pattern, err)
+		}
+		return nil
+	}
+
+	// fs.Glob returns the matches in lexical order.
+	names, err := fs.Glob(l.fsys, pattern)
+	if err != nil {
+		return fmt.Errorf("%s: include %q: %w", from, pattern, err)
+	}
+
+	for _, name := range names {
+		if err := l.load(dst, name); err != nil {
+			return fmt.Errorf("%s: include %q: %w", from, name, err)
+		}
+	}
+
+	return nil
+}
+
+// loadUpstreamFiles loads the upstream files referenced
+// by the `upstream` property of outputs.
+// Like fluent-bit does, relative paths are resolved
+// from the directory of the main config file.
+func (l *loader) loadUpstreamFiles(dst *Config, dir string) error {
+	loaded := map[string]bool{}
+	for _, plugin := range dst.Pipeline.Outputs {
+		v, _ := plugin.Properties.Get("upstream")
+		ref, ok := v.(string)
+		if !ok || isEnvVariable(ref) || !dst.upstreamFileRef(plugin, ref) {
+			continue
+		}
+
+		name := resolveFSPath(dir, ref)
+		if loaded[name] {
+			continue
+		}
+		loaded[name] = true
+
+		cfg, err := l.parseFile(name)
+		if err != nil {
+			return fmt.Errorf("%s: output %q: upstream %q: %w", plugin.Pos.File, plugin.ID, ref, err)
+		}
+
+		dst.merge(Config{Upstreams: cfg.Upstreams}, name)
+	}
+
+	return nil
+}
+
+// loadStreamsFile loads the stream tasks from the file
+// referenced by the `streams_file` service property.
+// Relative paths are resolved from the directory of the main config file.
+func (l *loader) loadStreamsFile(dst *Config, dir string) error {
+	for _, p := range dst.Service {
+		ref, ok := p.Value.(string)
+		if !strings.EqualFold(p.Key, "streams_file") || !ok || ref == "" || isEnvVariable(ref) {
+			continue
+		}
+
+		name := resolveFSPath(dir, ref)
+		cfg, err := l.parseFile(name)
+		if err != nil {
+			return fmt.Errorf("%s: service: streams_file %q: %w", p.Pos.File, ref, err)
+		}
+
+		dst.merge(Config{StreamTasks: cfg.StreamTasks}, name)
+	}
+
+	return nil
+}
+
+// parseFile parses a file without resolving its includes.
+func (l *loader) parseFile(name string) (Config, error) {
+	b, err := fs.ReadFile(l.fsys, name)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg, err := ParseAs(string(b), formatFromPath(name))
+	if err != nil {
+		return Config{}, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return cfg, nil
+}
+
+// merge the given config into c.
+// Service and env properties are overridden,
+// while plugins are appended with their IDs re-assigned.
+func (c *Config) merge(other Config, file string) {
+	setFile(other.Env, file)
+	setFile(other.Service, file)
+
+	for _, p := range other.Env {
+		setProperty(&c.Env, p)
+	}
+
+	c.Includes = append(c.Includes, other.Includes...)
+
+	if len(other.Service) != 0 {
+		c.AddSection(SectionKindService, other.Service)
+	}
+
+	appendPlugins := func(dst *Plugins, src Plugins) {
+		for _, plugin := range src {
+			plugin.ID = pluginID(plugin.Name, plugin.Properties, len(*dst))
+			*dst = append(*dst, plugin)
+		}
+	}
+
+	for _, ref := range other.pluginRefs() {
+		ref.Plugin.Pos.File = file
+		setFile(ref.Plugin.Properties, file)
+	}
+
+	appendPlugins(&c.Customs, other.Customs)
+	appendPlugins(&c.Pipeline.Inputs, other.Pipeline.Inputs)
+	appendPlugins(&c.Pipeline.Filters, other.Pipeline.Filters)
+	appendPlugins(&c.Pipeline.Outputs, other.Pipeline.Outputs)
+	appendPlugins(&c.Parsers, other.Parsers)
+
+	for _, parser := range other.MultilineParsers {
+		parser.ID = fmt.Sprintf("%s.%d", parser.Name, len(c.MultilineParsers))
+		parser.Pos.File = file
+		setFile(parser.Properties, file)
+		c.MultilineParsers = append(c.MultilineParsers, parser)
+	}
+
+	c.ExternalPlugins = append(c.ExternalPlugins, other.ExternalPlugins...)
+
+	for _, upstream := range other.Upstreams {
+		upstream.Pos.File = file
+		for i := range upstream.Nodes {
+			upstream.Nodes[i].Pos.File = file
+			setFile(upstream.Nodes[i].Properties, file)
+		}
+		c.Upstreams = append(c.Upstreams, upstream)
+	}
+
+	for _, task := range other.StreamTasks {
+		task.Pos.File = file
+		c.StreamTasks = append(c.StreamTasks, task)
+	}
+}
+
+func setFile(props property.Properties, file string) {
+	for i := range props {
+		props[i].Pos.File = file
+	}
+}
+
+// resolveFSPath resolves name relative to dir,
+// unless it is absolute.
+func resolveFSPath(dir, name string) string {
+	if !strings.HasPrefix(name, "/") {
+		name = path.Join(dir, name)
+	}
+	return cleanFSPath(name)
+}
+
+func cleanFSPath(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+func hasGlobMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
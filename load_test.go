@@ -0,0 +1,242 @@
+package fluentbitconfig
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+func TestLoadFile(t *testing.T) {
+	t.Run("classic_inline_includes", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"etc/fluent-bit.conf": {Data: []byte(configLiteral(`
+				[SERVICE]
+					log_level info
+				@INCLUDE inputs/*.conf
+				[FILTER]
+					name  grep
+					match *
+				@INCLUDE outputs.yaml
+			`))},
+			"etc/inputs/b.conf": {Data: []byte(configLiteral(`
+				[INPUT]
+					name mem
+			`))},
+			"etc/inputs/a.conf": {Data: []byte(configLiteral(`
+				@SET FOO=bar
+				[INPUT]
+					name cpu
+			`))},
+			"etc/outputs.yaml": {Data: []byte(configLiteral(`
+				service:
+					log_level: debug
+				pipeline:
+					outputs:
+						- name: stdout
+						  match: "*"
+			`))},
+		}
+
+		cfg, err := LoadFile(fsys, "etc/fluent-bit.conf")
+		require.NoError(t, err)
+		require.Equal(t, property.Properties{{
+			Key:   "FOO",
+			Value: "bar",
+			Pos:   property.Position{File: "etc/inputs/a.conf", Line: 1, Column: 1},
+		}}, cfg.Env)
+		require.Equal(t, property.Properties{{
+			Key:   "log_level",
+			Value: "debug",
+			Pos:   property.Position{File: "etc/outputs.yaml", Line: 2, Column: 5},
+		}}, cfg.Service)
+		require.Nil(t, cfg.Includes)
+		require.Equal(t, []string{
+			"input:cpu:cpu.0",
+			"input:mem:mem.1",
+			"filter:grep:grep.0",
+			"output:stdout:stdout.0",
+		}, cfg.IDs(true))
+
+		require.Equal(t, "etc/inputs/a.conf", cfg.Pipeline.Inputs[0].Pos.File)
+		require.Equal(t, "etc/inputs/b.conf", cfg.Pipeline.Inputs[1].Pos.File)
+		require.Equal(t, "etc/fluent-bit.conf", cfg.Pipeline.Filters[0].Pos.File)
+		require.Equal(t, "etc/outputs.yaml", cfg.Pipeline.Outputs[0].Pos.File)
+	})
+
+	t.Run("yaml_includes_classic", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"fluent-bit.yaml": {Data: []byte(configLiteral(`
+				includes:
+					- /conf.d/inputs.conf
+				pipeline:
+					outputs:
+						- name: stdout
+			`))},
+			"conf.d/inputs.conf": {Data: []byte(configLiteral(`
+				[INPUT]
+					name dummy
+			`))},
+		}
+
+		cfg, err := LoadFile(fsys, "fluent-bit.yaml")
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"input:dummy:dummy.0",
+			"output:stdout:stdout.0",
+		}, cfg.IDs(true))
+		require.Equal(t, "conf.d/inputs.conf", cfg.Pipeline.Inputs[0].Pos.File)
+	})
+
+	t.Run("upstream_files", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"etc/fluent-bit.conf": {Data: []byte(configLiteral(`
+				@INCLUDE outputs/*.conf
+			`))},
+			"etc/outputs/a.conf": {Data: []byte(configLiteral(`
+				[OUTPUT]
+					name     forward
+					upstream upstream.conf
+			`))},
+			"etc/outputs/b.conf": {Data: []byte(configLiteral(`
+				[OUTPUT]
+					name     forward
+					upstream upstream.conf
+			`))},
+			"etc/upstream.conf": {Data: []byte(configLiteral(`
+				[UPSTREAM]
+					name balancing
+				[NODE]
+					name node-1
+					host 127.0.0.1
+			`))},
+		}
+
+		cfg, err := LoadFile(fsys, "etc/fluent-bit.conf")
+		require.NoError(t, err)
+		require.Len(t, cfg.Upstreams, 1)
+		require.Equal(t, "balancing", cfg.Upstreams[0].Name)
+		require.Equal(t, property.Position{File: "etc/upstream.conf", Line: 1, Column: 1}, cfg.Upstreams[0].Pos)
+		require.Equal(t, []string{"node-1.0"}, cfg.Upstreams[0].Nodes.IDs())
+		require.NoError(t, cfg.Validate())
+	})
+
+	t.Run("upstream_file_without_extension", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"etc/fluent-bit.conf": {Data: []byte(configLiteral(`
+				[OUTPUT]
+					name     forward
+					upstream upstream_nodes
+			`))},
+			"etc/upstream_nodes": {Data: []byte(configLiteral(`
+				[UPSTREAM]
+					name balancing
+				[NODE]
+					name node-1
+					host 127.0.0.1
+			`))},
+		}
+
+		cfg, err := LoadFile(fsys, "etc/fluent-bit.conf")
+		require.NoError(t, err)
+		require.Len(t, cfg.Upstreams, 1)
+		require.Equal(t, "balancing", cfg.Upstreams[0].Name)
+		require.NoError(t, cfg.Validate())
+	})
+
+	t.Run("upstream_name_not_defined", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"fluent-bit.yaml": {Data: []byte(configLiteral(`
+				pipeline:
+					outputs:
+						- name: forward
+						  upstream: upstream_nodes
+			`))},
+		}
+
+		cfg, err := LoadFile(fsys, "fluent-bit.yaml")
+		require.NoError(t, err)
+		require.EqualError(t, cfg.Validate(), `fluent-bit.yaml:4:11: output: forward: upstream "upstream_nodes" not defined`)
+	})
+
+	t.Run("missing_upstream_file", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"fluent-bit.yaml": {Data: []byte(configLiteral(`
+				pipeline:
+					outputs:
+						- name: forward
+						  upstream: /etc/upstream.conf
+			`))},
+		}
+
+		_, err := LoadFile(fsys, "fluent-bit.yaml")
+		require.EqualError(t, err, `fluent-bit.yaml: output "forward.0": upstream "/etc/upstream.conf": open etc/upstream.conf: file does not exist`)
+	})
+
+	t.Run("streams_file", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"etc/fluent-bit.yaml": {Data: []byte(configLiteral(`
+				service:
+					streams_file: streams.conf
+			`))},
+			"etc/streams.conf": {Data: []byte(configLiteral(`
+				[STREAM_TASK]
+					Name results
+					Exec SELECT * FROM TAG:'cpu.*';
+			`))},
+		}
+
+		cfg, err := LoadFile(fsys, "etc/fluent-bit.yaml")
+		require.NoError(t, err)
+		require.Equal(t, StreamTasks{{
+			Name: "results",
+			Exec: "SELECT * FROM TAG:'cpu.*';",
+			Pos:  property.Position{File: "etc/streams.conf", Line: 1, Column: 1},
+		}}, cfg.StreamTasks)
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"a.conf": {Data: []byte("@INCLUDE b.conf\n")},
+			"b.conf": {Data: []byte("@INCLUDE a.conf\n")},
+		}
+
+		_, err := LoadFile(fsys, "a.conf")
+		var cycleErr *IncludeCycleError
+		require.True(t, errors.As(err, &cycleErr))
+		require.Equal(t, []string{"a.conf", "b.conf", "a.conf"}, cycleErr.Chain)
+	})
+
+	t.Run("missing_include", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"a.conf": {Data: []byte("@INCLUDE nope.conf\n")},
+		}
+
+		_, err := LoadFile(fsys, "a.conf")
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `a.conf: include "nope.conf"`)
+	})
+
+	t.Run("unmatched_glob", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"a.conf": {Data: []byte("@INCLUDE conf.d/*.conf\n")},
+		}
+
+		cfg, err := LoadFile(fsys, "a.conf")
+		require.NoError(t, err)
+		require.Equal(t, Config{}, cfg)
+	})
+
+	t.Run("syntax_error_line", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"a.conf": {Data: []byte("@INCLUDE b.conf\n[INPUT\n")},
+			"b.conf": {Data: []byte("")},
+		}
+
+		_, err := LoadFile(fsys, "a.conf")
+		require.EqualError(t, err, `a.conf: 2: expected section to end with "]"`)
+	})
+}
@@ -0,0 +1,401 @@
+package fluentbitconfig
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+// MergeKey is the plugin property overlays use to tell Merge
+// how the plugin should be merged, with one of the MergeStrategy values.
+// It is not included in the merged config.
+const MergeKey = "$merge"
+
+// MergeIDKey is the plugin property overlays use to name the plugin
+// they apply to, with its ID as in the config resulting from the previous
+// layers. For example: cpu.1
+// Plugins with an alias are matched by it when not given.
+// It is not included in the merged config.
+const MergeIDKey = "$id"
+
+// DeleteValue removes the property when used as value on an overlay.
+// This applies to env, service and plugin properties.
+const DeleteValue = "$delete"
+
+// MergeStrategy of an overlay plugin.
+type MergeStrategy string
+
+const (
+	// MergePatch sets the overlay properties on the plugin
+	// with the MergeIDKey ID or the same alias.
+	// This is the default, and when no ID is given
+	// and no plugin has the alias, the overlay plugin is appended.
+	MergePatch MergeStrategy = "patch"
+	// MergeDelete removes the plugin with the MergeIDKey ID or the same alias.
+	MergeDelete MergeStrategy = "delete"
+	// MergeAppend adds the overlay plugin after the existing ones.
+	MergeAppend MergeStrategy = "append"
+	// MergePrepend adds the overlay plugin before the existing ones.
+	MergePrepend MergeStrategy = "prepend"
+)
+
+// Provenance tells which layer contributed each property of a merged config:
+// 0 for the base and i for the i-th overlay.
+// Properties are indexed by section and lowercased key,
+// where sections are "env", "service", and the namespaced IDs
+// of plugins, processors, multiline parsers and upstream nodes.
+type Provenance map[string]map[string]int
+
+// Layer that contributed the given property.
+func (p Provenance) Layer(section, key string) (int, bool) {
+	layer, ok := p[section][strings.ToLower(key)]
+	return layer, ok
+}
+
+// Merge the overlays on top of the base config, in order.
+//
+//   - Env and service properties are overridden.
+//   - Customs, inputs, filters, outputs and parsers are matched
+//     by their MergeIDKey property or alias, and patched.
+//     Their MergeKey property allows to delete them
+//     or to add them as new plugins instead.
+//     Processors given on an overlay plugin replace those of the same signal.
+//   - Multiline parsers, upstreams and stream tasks are replaced by name.
+//   - Includes and external plugins are appended.
+//
+// IDs referenced by overlays are the ones of the config
+// resulting from the previous layers.
+func Merge(base Config, overlays ...Config) (Config, Provenance, error) {
+	m := newMerger(base.clone())
+	for i, overlay := range overlays {
+		if err := m.apply(i+1, overlay.clone()); err != nil {
+			return Config{}, nil, fmt.Errorf("overlay %d: %w", i+1, err)
+		}
+	}
+
+	cfg, prov := m.result()
+	return cfg, prov, nil
+}
+
+// mergeKinds are the kinds of plugins matched by ID.
+var mergeKinds = []SectionKind{
+	SectionKindCustom,
+	SectionKindInput,
+	SectionKindFilter,
+	SectionKindOutput,
+	SectionKindParser,
+}
+
+type merger struct {
+	cfg            Config
+	envLayers      map[string]int
+	serviceLayers  map[string]int
+	plugins        map[SectionKind][]*mergeEntry
+	parserLayers   []int
+	upstreamLayers []int
+}
+
+// mergeEntry is a plugin being merged along the layers of its properties.
+type mergeEntry struct {
+	plugin          Plugin
+	layers          map[string]int
+	processorLayers map[string]int
+}
+
+func newMerger(base Config) *merger {
+	m := &merger{
+		cfg:            base,
+		envLayers:      keyLayers(base.Env, 0),
+		serviceLayers:  keyLayers(base.Service, 0),
+		plugins:        map[SectionKind][]*mergeEntry{},
+		parserLayers:   make([]int, len(base.MultilineParsers)),
+		upstreamLayers: make([]int, len(base.Upstreams)),
+	}
+
+	for _, kind := range mergeKinds {
+		plugins, _ := base.pluginsOf(kind)
+		for _, plugin := range *plugins {
+			m.plugins[kind] = append(m.plugins[kind], newMergeEntry(plugin, 0))
+		}
+	}
+
+	return m
+}
+
+func newMergeEntry(plugin Plugin, layer int) *mergeEntry {
+	entry := &mergeEntry{
+		plugin:          plugin,
+		layers:          keyLayers(plugin.Properties, layer),
+		processorLayers: map[string]int{},
+	}
+	for _, signal := range processorSignals {
+		entry.processorLayers[signal] = layer
+	}
+	return entry
+}
+
+func (m *merger) apply(layer int, overlay Config) error {
+	if err := patchProperties(&m.cfg.Env, m.envLayers, overlay.Env, layer); err != nil {
+		return fmt.Errorf("env: %w", err)
+	}
+
+	if err := patchProperties(&m.cfg.Service, m.serviceLayers, overlay.Service, layer); err != nil {
+		return fmt.Errorf("service: %w", err)
+	}
+
+	for _, include := range overlay.Includes {
+		if !slices.Contains(m.cfg.Includes, include) {
+			m.cfg.Includes = append(m.cfg.Includes, include)
+		}
+	}
+
+	for _, path := range overlay.ExternalPlugins {
+		if !slices.Contains(m.cfg.ExternalPlugins, path) {
+			m.cfg.ExternalPlugins = append(m.cfg.ExternalPlugins, path)
+		}
+	}
+
+	for _, kind := range mergeKinds {
+		plugins, _ := overlay.pluginsOf(kind)
+		if err := m.applyPlugins(kind, *plugins, layer); err != nil {
+			return err
+		}
+	}
+
+	for _, parser := range overlay.MultilineParsers {
+		i := slices.IndexFunc(m.cfg.MultilineParsers, func(p MultilineParser) bool { return p.Name == parser.Name })
+		if i < 0 {
+			parser.ID = fmt.Sprintf("%s.%d", parser.Name, len(m.cfg.MultilineParsers))
+			m.cfg.MultilineParsers = append(m.cfg.MultilineParsers, parser)
+			m.parserLayers = append(m.parserLayers, layer)
+			continue
+		}
+
+		parser.ID = m.cfg.MultilineParsers[i].ID
+		m.cfg.MultilineParsers[i] = parser
+		m.parserLayers[i] = layer
+	}
+
+	for _, upstream := range overlay.Upstreams {
+		i := slices.IndexFunc(m.cfg.Upstreams, func(u Upstream) bool { return u.Name == upstream.Name })
+		if i < 0 {
+			m.cfg.Upstreams = append(m.cfg.Upstreams, upstream)
+			m.upstreamLayers = append(m.upstreamLayers, layer)
+			continue
+		}
+
+		m.cfg.Upstreams[i] = upstream
+		m.upstreamLayers[i] = layer
+	}
+
+	for _, task := range overlay.StreamTasks {
+		i := slices.IndexFunc(m.cfg.StreamTasks, func(t StreamTask) bool { return t.Name == task.Name })
+		if i < 0 {
+			m.cfg.StreamTasks = append(m.cfg.StreamTasks, task)
+			continue
+		}
+
+		m.cfg.StreamTasks[i] = task
+	}
+
+	return nil
+}
+
+func (m *merger) applyPlugins(kind SectionKind, plugins Plugins, layer int) error {
+	// IDs are resolved against the plugins as they were before this layer.
+	current := m.plugins[kind]
+	byID := map[string]*mergeEntry{}
+	for i, entry := range current {
+		byID[pluginID(entry.plugin.Name, entry.plugin.Properties, i)] = entry
+	}
+
+	var prepend, appendEntries []*mergeEntry
+	deleted := map[*mergeEntry]bool{}
+	for _, plugin := range plugins {
+		strategy, err := mergeStrategy(plugin.Properties)
+		if err != nil {
+			return fmt.Errorf("%s: %s: %w", kind, plugin.ID, err)
+		}
+
+		target, explicit := "", false
+		if v, ok := plugin.Properties.Get(MergeIDKey); ok {
+			target, explicit = stringFromAny(v), true
+		} else {
+			target = Alias(plugin.Properties)
+		}
+
+		plugin.Properties = withoutKey(withoutKey(plugin.Properties, MergeKey), MergeIDKey)
+		id := fmt.Sprintf("%s:%s:%s", kind, plugin.Name, target)
+		entry, found := byID[target]
+		if explicit && !found && (strategy == MergePatch || strategy == MergeDelete) {
+			return fmt.Errorf("cannot %s: %w", strategy, &PluginNotFoundError{ID: id})
+		}
+
+		switch strategy {
+		case MergeAppend:
+			appendEntries = append(appendEntries, newMergeEntry(plugin, layer))
+		case MergePrepend:
+			prepend = append(prepend, newMergeEntry(plugin, layer))
+		case MergeDelete:
+			if target == "" {
+				return fmt.Errorf("%s: %s: cannot delete: %s or alias required", kind, plugin.Name, MergeIDKey)
+			}
+			if !found {
+				return fmt.Errorf("cannot delete: %w", &PluginNotFoundError{ID: id})
+			}
+			deleted[entry] = true
+		case MergePatch:
+			if !found {
+				appendEntries = append(appendEntries, newMergeEntry(plugin, layer))
+				continue
+			}
+
+			if err := patchProperties(&entry.plugin.Properties, entry.layers, plugin.Properties, layer); err != nil {
+				return fmt.Errorf("%s: %w", id, err)
+			}
+
+			// The name might have been patched too.
+			// IDs are re-assigned with the result.
+			if name := Name(entry.plugin.Properties); name != "" {
+				entry.plugin.Name = name
+			}
+
+			for _, signal := range processorSignals {
+				src, _ := plugin.Processors.Signal(signal)
+				if len(*src) == 0 {
+					continue
+				}
+
+				dst, _ := entry.plugin.Processors.Signal(signal)
+				*dst = *src
+				entry.processorLayers[signal] = layer
+			}
+		}
+	}
+
+	out := prepend
+	for _, entry := range current {
+		if !deleted[entry] {
+			out = append(out, entry)
+		}
+	}
+	m.plugins[kind] = append(out, appendEntries...)
+
+	return nil
+}
+
+// result builds the merged config with its provenance.
+func (m *merger) result() (Config, Provenance) {
+	out := m.cfg
+	for _, kind := range mergeKinds {
+		var plugins Plugins
+		for _, entry := range m.plugins[kind] {
+			plugins = append(plugins, entry.plugin)
+		}
+		reassignIDs(plugins)
+
+		dst, _ := out.pluginsOf(kind)
+		*dst = plugins
+	}
+
+	prov := Provenance{}
+	if len(m.envLayers) != 0 {
+		prov["env"] = m.envLayers
+	}
+	if len(m.serviceLayers) != 0 {
+		prov[string(SectionKindService)] = m.serviceLayers
+	}
+
+	var parent pluginRef
+	var entry *mergeEntry
+	for _, ref := range out.pluginRefs() {
+		if ref.Kind != SectionKindProcessor {
+			parent, entry = ref, m.plugins[ref.Kind][ref.Index]
+			prov[ref.ID] = entry.layers
+			continue
+		}
+
+		for _, signal := range processorSignals {
+			if list, _ := parent.Plugin.Processors.Signal(signal); list == ref.List {
+				prov[ref.ID] = keyLayers(ref.Plugin.Properties, entry.processorLayers[signal])
+			}
+		}
+	}
+
+	for i, parser := range out.MultilineParsers {
+		prov[fmt.Sprintf("%s:%s:%s", SectionKindMultilineParser, parser.Name, parser.ID)] = keyLayers(parser.Properties, m.parserLayers[i])
+	}
+
+	for i, upstream := range out.Upstreams {
+		for _, node := range upstream.Nodes {
+			prov[fmt.Sprintf("%s:%s:%s", SectionKindUpstream, upstream.Name, node.ID)] = keyLayers(node.Properties, m.upstreamLayers[i])
+		}
+	}
+
+	return out, prov
+}
+
+// patchProperties sets the overlay properties on dst,
+// removing those whose value is DeleteValue.
+func patchProperties(dst *property.Properties, layers map[string]int, overlay property.Properties, layer int) error {
+	for _, p := range overlay {
+		key := strings.ToLower(p.Key)
+		if p.Value == DeleteValue {
+			if !dst.Has(p.Key) {
+				return fmt.Errorf("cannot delete %q: not set", p.Key)
+			}
+
+			*dst = withoutKey(*dst, p.Key)
+			delete(layers, key)
+			continue
+		}
+
+		// Restating a value, like the name and alias of matched plugins,
+		// keeps the layer that contributed it first.
+		if v, ok := dst.Get(p.Key); ok && reflect.DeepEqual(v, p.Value) {
+			continue
+		}
+
+		setProperty(dst, p)
+		layers[key] = layer
+	}
+
+	return nil
+}
+
+func mergeStrategy(props property.Properties) (MergeStrategy, error) {
+	v, ok := props.Get(MergeKey)
+	if !ok {
+		return MergePatch, nil
+	}
+
+	switch strategy := MergeStrategy(strings.ToLower(stringFromAny(v))); strategy {
+	case MergePatch, MergeDelete, MergeAppend, MergePrepend:
+		return strategy, nil
+	}
+
+	return "", fmt.Errorf("unknown %s strategy %q", MergeKey, v)
+}
+
+// keyLayers returns the given layer for each lowercased key of the properties.
+func keyLayers(props property.Properties, layer int) map[string]int {
+	out := map[string]int{}
+	for _, p := range props {
+		out[strings.ToLower(p.Key)] = layer
+	}
+	return out
+}
+
+// withoutKey returns a copy of the properties without the given key.
+func withoutKey(props property.Properties, key string) property.Properties {
+	var out property.Properties
+	for _, p := range props {
+		if !strings.EqualFold(p.Key, key) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,271 @@
+package fluentbitconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge(t *testing.T) {
+	parse := func(t *testing.T, text string) Config {
+		t.Helper()
+
+		cfg, err := ParseAs(configLiteral(text), FormatYAML)
+		require.NoError(t, err)
+		return cfg
+	}
+
+	base := parse(t, `
+		env:
+			region: us-east-1
+		service:
+			flush: 1
+			log_level: info
+		pipeline:
+			inputs:
+				- name: tail
+				  alias: app_logs
+				  path: /var/log/app.log
+				- name: cpu
+			filters:
+				- name: grep
+				  alias: drop_debug
+				  exclude: level debug
+			outputs:
+				- name: stdout
+				  alias: debug
+				  match: '*'
+				- name: forward
+				  alias: upstream
+				  match: '*'
+				  host: 127.0.0.1
+	`)
+
+	prod := parse(t, `
+		env:
+			region: eu-west-1
+		service:
+			log_level: warn
+		pipeline:
+			inputs:
+				- name: tail
+				  alias: app_logs
+				  path: /var/log/prod/app.log
+				- name: tail
+				  alias: audit
+				  $merge: prepend
+				  path: /var/log/audit.log
+			filters:
+				- name: grep
+				  alias: drop_debug
+				  exclude: $delete
+				  regex: level error
+			outputs:
+				- name: stdout
+				  alias: debug
+				  $merge: delete
+				- name: forward
+				  alias: upstream
+				  host: forward.prod
+				  processors:
+					logs:
+						- name: content_modifier
+						  action: insert
+	`)
+
+	local := parse(t, `
+		service:
+			flush: 5
+		pipeline:
+			outputs:
+				- name: forward
+				  alias: upstream
+				  port: 24224
+	`)
+
+	got, prov, err := Merge(base, prod, local)
+	require.NoError(t, err)
+
+	want := parse(t, `
+		env:
+			region: eu-west-1
+		service:
+			flush: 5
+			log_level: warn
+		pipeline:
+			inputs:
+				- name: tail
+				  alias: audit
+				  path: /var/log/audit.log
+				- name: tail
+				  alias: app_logs
+				  path: /var/log/prod/app.log
+				- name: cpu
+			filters:
+				- name: grep
+				  alias: drop_debug
+				  regex: level error
+			outputs:
+				- name: forward
+				  alias: upstream
+				  match: '*'
+				  host: forward.prod
+				  port: 24224
+				  processors:
+					logs:
+						- name: content_modifier
+						  action: insert
+	`)
+	require.True(t, want.Equal(got), "got:\n%s", mustDumpYAML(t, got))
+	require.Equal(t, []string{
+		"input:tail:audit",
+		"input:tail:app_logs",
+		"input:cpu:cpu.2",
+		"filter:grep:drop_debug",
+		"output:forward:upstream",
+		"output:forward:upstream/logs:content_modifier:content_modifier.0",
+	}, got.IDs(true))
+
+	layer := func(section, key string) int {
+		t.Helper()
+
+		layer, ok := prov.Layer(section, key)
+		require.True(t, ok, "%s: %s", section, key)
+		return layer
+	}
+
+	require.Equal(t, 1, layer("env", "region"))
+	require.Equal(t, 2, layer("service", "flush"))
+	require.Equal(t, 1, layer("service", "Log_Level"))
+	require.Equal(t, 0, layer("input:tail:app_logs", "alias"))
+	require.Equal(t, 1, layer("input:tail:app_logs", "path"))
+	require.Equal(t, 1, layer("input:tail:audit", "path"))
+	require.Equal(t, 0, layer("input:cpu:cpu.2", "name"))
+	require.Equal(t, 1, layer("filter:grep:drop_debug", "regex"))
+	require.Equal(t, 0, layer("output:forward:upstream", "match"))
+	require.Equal(t, 2, layer("output:forward:upstream", "port"))
+	require.Equal(t, 1, layer("output:forward:upstream/logs:content_modifier:content_modifier.0", "action"))
+
+	_, ok := prov.Layer("filter:grep:drop_debug", "exclude")
+	require.False(t, ok, "deleted property")
+
+	require.Equal(t, "/var/log/app.log", base.Pipeline.Inputs[0].Properties[2].Value, "base not modified")
+
+	t.Run("errors", func(t *testing.T) {
+		_, _, err := Merge(base, parse(t, `
+			pipeline:
+				outputs:
+					- name: counter
+					  $merge: delete
+		`))
+		require.EqualError(t, err, `overlay 1: output: counter: cannot delete: $id or alias required`)
+
+		_, _, err = Merge(base, parse(t, `
+			pipeline:
+				outputs:
+					- name: stdout
+					  $id: stdout.5
+					  match: app
+		`))
+		require.EqualError(t, err, `overlay 1: cannot patch: plugin "output:stdout:stdout.5" not found`)
+
+		var notFound *PluginNotFoundError
+		require.ErrorAs(t, err, &notFound)
+
+		_, _, err = Merge(base, parse(t, `
+			pipeline:
+				outputs:
+					- name: stdout
+					  alias: debug
+					  $merge: upsert
+		`))
+		require.EqualError(t, err, `overlay 1: output: debug: unknown $merge strategy "upsert"`)
+
+		_, _, err = Merge(base, parse(t, `
+			service:
+				http_server: $delete
+		`))
+		require.EqualError(t, err, `overlay 1: service: cannot delete "http_server": not set`)
+	})
+}
+
+func TestMerge_explicitID(t *testing.T) {
+	base, err := ParseAs(configLiteral(`
+		pipeline:
+			inputs:
+				- name: tail
+				  path: /var/log/app.log
+				- name: cpu
+				  interval_sec: 1
+				- name: mem
+	`), FormatYAML)
+	require.NoError(t, err)
+
+	overlay, err := ParseAs(configLiteral(`
+		pipeline:
+			inputs:
+				- name: cpu
+				  $id: cpu.1
+				  interval_sec: 5
+				- name: mem
+				  $id: mem.2
+				  $merge: delete
+				- name: cpu
+	`), FormatYAML)
+	require.NoError(t, err)
+
+	got, prov, err := Merge(base, overlay)
+	require.NoError(t, err)
+	require.Equal(t, configLiteral(`
+		pipeline:
+		    inputs:
+		        - name: tail
+		          path: /var/log/app.log
+		        - name: cpu
+		          interval_sec: 5
+		        - name: cpu
+	`), mustDumpYAML(t, got))
+
+	layer, ok := prov.Layer("input:cpu:cpu.1", "interval_sec")
+	require.True(t, ok)
+	require.Equal(t, 1, layer)
+}
+
+func TestMerge_rename(t *testing.T) {
+	base, err := ParseAs(configLiteral(`
+		[INPUT]
+			Name         cpu
+			Interval_Sec 1
+	`), FormatClassic)
+	require.NoError(t, err)
+
+	overlay, err := ParseAs(configLiteral(`
+		pipeline:
+			inputs:
+				- $id: cpu.0
+				  name: mem
+	`), FormatYAML)
+	require.NoError(t, err)
+
+	got, prov, err := Merge(base, overlay)
+	require.NoError(t, err)
+	require.Equal(t, "mem", got.Pipeline.Inputs[0].Name)
+	require.Equal(t, []string{"input:mem:mem.0"}, got.IDs(true))
+	require.Equal(t, configLiteral(`
+		[INPUT]
+		    Name         mem
+		    Interval_Sec 1
+	`), mustDumpClassic(t, got))
+
+	layer, ok := prov.Layer("input:mem:mem.0", "name")
+	require.True(t, ok)
+	require.Equal(t, 1, layer)
+}
+
+func mustDumpYAML(t *testing.T, cfg Config) string {
+	t.Helper()
+
+	s, err := cfg.DumpAsYAML()
+	require.NoError(t, err)
+	return s
+}
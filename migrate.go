@@ -0,0 +1,261 @@
+package fluentbitconfig
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+	"golang.org/x/mod/semver"
+)
+
+// MigrationAction of a MigrationRule.
+type MigrationAction string
+
+const (
+	// MigrationRename renames a property keeping its value.
+	MigrationRename MigrationAction = "rename"
+	// MigrationRemove removes a property.
+	MigrationRemove MigrationAction = "remove"
+)
+
+// MigrationRule is a known property rename or removal
+// introduced on a fluent-bit version.
+type MigrationRule struct {
+	// Version introducing the change.
+	// The rule applies when migrating from an older version
+	// to this one or a newer one.
+	Version string      `json:"version"`
+	Kind    SectionKind `json:"kind"`
+	// Plugin name. Empty matches any plugin of the kind.
+	Plugin string          `json:"plugin,omitempty"`
+	Key    string          `json:"key"`
+	Action MigrationAction `json:"action"`
+	// To is the new key of renamed properties.
+	To   string `json:"to,omitempty"`
+	Note string `json:"note,omitempty"`
+}
+
+// DefaultMigrationRules known to Config.Migrate.
+// Append to them to add custom rules.
+var DefaultMigrationRules = []MigrationRule{
+	{
+		Version: "22.9.1",
+		Kind:    SectionKindOutput,
+		Plugin:  "opentelemetry",
+		Key:     "uri",
+		Action:  MigrationRename,
+		To:      "metrics_uri",
+		Note:    "uri was split into metrics_uri and logs_uri",
+	},
+	{
+		Version: "24.10.0",
+		Kind:    SectionKindInput,
+		Plugin:  "kubernetes_events",
+		Key:     "timestamp_key",
+		Action:  MigrationRemove,
+	},
+}
+
+// MigrateOptions for Config.Migrate.
+type MigrateOptions struct {
+	// From and To schema versions. For example: 24.9.2
+	From, To string
+	// Rules to apply. DefaultMigrationRules are used when nil.
+	Rules []MigrationRule
+}
+
+// MigrationChangeKind of a MigrationChange.
+type MigrationChangeKind string
+
+const (
+	MigrationRenamed       MigrationChangeKind = "renamed"
+	MigrationRemoved       MigrationChangeKind = "removed"
+	MigrationTypeChanged   MigrationChangeKind = "type_changed"
+	MigrationUnknownOption MigrationChangeKind = "unknown_option"
+	MigrationUnknownPlugin MigrationChangeKind = "unknown_plugin"
+)
+
+// MigrationChange applied or found by Config.Migrate.
+type MigrationChange struct {
+	Kind MigrationChangeKind `json:"kind"`
+	// Section ID. For example: input:tail:tail.0
+	Section string `json:"section"`
+	Key     string `json:"key,omitempty"`
+	// To is the new key of renamed properties,
+	// or the new type of properties whose type changed.
+	To   string `json:"to,omitempty"`
+	Note string `json:"note,omitempty"`
+	// Manual is set for changes that were not applied
+	// and need manual attention.
+	Manual bool              `json:"manual"`
+	Pos    property.Position `json:"-"`
+}
+
+func (c MigrationChange) String() string {
+	var s string
+	switch c.Kind {
+	case MigrationRenamed:
+		s = fmt.Sprintf("%s: renamed %q to %q", c.Section, c.Key, c.To)
+	case MigrationRemoved:
+		s = fmt.Sprintf("%s: removed %q", c.Section, c.Key)
+	case MigrationTypeChanged:
+		s = fmt.Sprintf("%s: %q type changed to %s", c.Section, c.Key, c.To)
+	case MigrationUnknownOption:
+		s = fmt.Sprintf("%s: unknown property %q", c.Section, c.Key)
+	case MigrationUnknownPlugin:
+		s = fmt.Sprintf("%s: unknown plugin", c.Section)
+	default:
+		s = fmt.Sprintf("%s: %s %q", c.Section, c.Kind, c.Key)
+	}
+
+	if c.Note != "" {
+		s += ": " + c.Note
+	}
+
+	if c.Manual {
+		s = "manual: " + s
+	}
+
+	return s
+}
+
+// MigrationReport with every change applied or found by Config.Migrate.
+type MigrationReport []MigrationChange
+
+// Manual returns the changes that need manual attention.
+func (r MigrationReport) Manual() MigrationReport {
+	var out MigrationReport
+	for _, c := range r {
+		if c.Manual {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Migrate returns a copy of the config migrated from one schema version
+// to another, and a report of the changes.
+//
+// Renames and removals from the rules introduced after the source version
+// and up to the target one are applied.
+// Then, properties are checked against the target schema,
+// and unknown plugins and properties, or properties whose type changed
+// to one their value is not valid for, are reported as manual changes.
+// Properties whose type changed but are still valid are reported too.
+func (c Config) Migrate(opts MigrateOptions) (Config, MigrationReport, error) {
+	from, err := GetSchema(opts.From)
+	if err != nil {
+		return Config{}, nil, fmt.Errorf("source schema: %w", err)
+	}
+
+	to, err := GetSchema(opts.To)
+	if err != nil {
+		return Config{}, nil, fmt.Errorf("target schema: %w", err)
+	}
+
+	rules := opts.Rules
+	if rules == nil {
+		rules = DefaultMigrationRules
+	}
+
+	var applicable []MigrationRule
+	for _, rule := range rules {
+		v := "v" + rule.Version
+		if semver.Compare(v, "v"+opts.From) > 0 && semver.Compare(v, "v"+opts.To) <= 0 {
+			applicable = append(applicable, rule)
+		}
+	}
+
+	out := c.clone()
+
+	var report MigrationReport
+	for _, ref := range out.pluginRefs() {
+		if ref.Kind == SectionKindParser {
+			continue
+		}
+
+		plugin := ref.Plugin
+		report = append(report, migrateProperties(ref.Kind, ref.ID, plugin, applicable)...)
+
+		if isCloudVariable(plugin.Name) || isEnvVariable(plugin.Name) {
+			continue
+		}
+
+		target, ok := to.findSection(ref.Kind, plugin.Name)
+		if !ok {
+			report = append(report, MigrationChange{Kind: MigrationUnknownPlugin, Section: ref.ID, Manual: true, Pos: plugin.Pos})
+			continue
+		}
+
+		source, _ := from.findSection(ref.Kind, plugin.Name)
+		for _, p := range plugin.Properties {
+			if isCommonProperty(p.Key) || isCoreProperty(p.Key) || areProcessors(p.Key) || isCloudVariable(p.Key) {
+				continue
+			}
+
+			targetOpts, ok := target.findOptions(p.Key)
+			if !ok {
+				report = append(report, MigrationChange{Kind: MigrationUnknownOption, Section: ref.ID, Key: p.Key, Manual: true, Pos: p.Pos})
+				continue
+			}
+
+			sourceOpts, ok := source.findOptions(p.Key)
+			if !ok || sourceOpts.Type == targetOpts.Type {
+				continue
+			}
+
+			variable := isCloudVariable(p.Value) || isEnvVariable(p.Value)
+			report = append(report, MigrationChange{
+				Kind:    MigrationTypeChanged,
+				Section: ref.ID,
+				Key:     p.Key,
+				To:      targetOpts.Type,
+				Note:    fmt.Sprintf("was %s", sourceOpts.Type),
+				Manual:  !variable && !valid(targetOpts, p.Value),
+				Pos:     p.Pos,
+			})
+		}
+	}
+
+	return out, report, nil
+}
+
+// migrateProperties applies the rules matching the plugin to its properties.
+func migrateProperties(kind SectionKind, id string, plugin *Plugin, rules []MigrationRule) MigrationReport {
+	var report MigrationReport
+	for _, rule := range rules {
+		if rule.Kind != kind || (rule.Plugin != "" && !strings.EqualFold(rule.Plugin, plugin.Name)) {
+			continue
+		}
+
+		for i := 0; i < len(plugin.Properties); i++ {
+			p := plugin.Properties[i]
+			if !strings.EqualFold(p.Key, rule.Key) {
+				continue
+			}
+
+			change := MigrationChange{Section: id, Key: p.Key, Note: rule.Note, Pos: p.Pos}
+			switch rule.Action {
+			case MigrationRename:
+				change.Kind, change.To = MigrationRenamed, rule.To
+				if plugin.Properties.Has(rule.To) {
+					change.Manual = true
+					change.Note = fmt.Sprintf("%q already set", rule.To)
+					break
+				}
+
+				plugin.Properties[i].Key = rule.To
+			case MigrationRemove:
+				change.Kind = MigrationRemoved
+				plugin.Properties = append(plugin.Properties[:i], plugin.Properties[i+1:]...)
+				i--
+			default:
+				continue
+			}
+
+			report = append(report, change)
+		}
+	}
+
+	return report
+}
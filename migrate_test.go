@@ -0,0 +1,118 @@
+package fluentbitconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Migrate(t *testing.T) {
+	cfg, err := ParseAs(configLiteral(`
+		[INPUT]
+			Name          kubernetes_events
+			Timestamp_Key time
+
+		[INPUT]
+			Name     head
+			File     /proc/meminfo
+			Buf_Size 256
+
+		[INPUT]
+			Name  tail
+			Path  /var/log/app.log
+			Typo  true
+
+		[OUTPUT]
+			Name  opentelemetry
+			Match *
+			Uri   /v1/metrics
+	`), FormatClassic)
+	require.NoError(t, err)
+
+	got, report, err := cfg.Migrate(MigrateOptions{From: "22.8.2", To: "26.2.1"})
+	require.NoError(t, err)
+
+	var changes []string
+	for _, change := range report {
+		changes = append(changes, change.String())
+	}
+	require.Equal(t, []string{
+		`input:kubernetes_events:kubernetes_events.0: removed "Timestamp_Key"`,
+		`input:head:head.1: "Buf_Size" type changed to size: was integer`,
+		`manual: input:tail:tail.2: unknown property "Typo"`,
+		`output:opentelemetry:opentelemetry.0: renamed "Uri" to "metrics_uri": uri was split into metrics_uri and logs_uri`,
+	}, changes)
+
+	require.Equal(t, MigrationReport{report[2]}, report.Manual())
+
+	require.Equal(t, configLiteral(`
+		pipeline:
+		    inputs:
+		        - name: kubernetes_events
+		        - name: head
+		          file: /proc/meminfo
+		          buf_size: 256
+		        - name: tail
+		          path: /var/log/app.log
+		          typo: true
+		    outputs:
+		        - name: opentelemetry
+		          match: '*'
+		          metrics_uri: /v1/metrics
+	`), mustDumpYAML(t, got.Normalize(NormalizeOptions{})))
+
+	v, _ := cfg.Pipeline.Outputs[0].Properties.Get("uri")
+	require.Equal(t, "/v1/metrics", v, "original not modified")
+
+	t.Run("rules_not_applicable", func(t *testing.T) {
+		_, report, err := cfg.Migrate(MigrateOptions{From: "24.10.0", To: "26.2.1"})
+		require.NoError(t, err)
+
+		var manual []string
+		for _, change := range report.Manual() {
+			manual = append(manual, change.String())
+		}
+		require.Equal(t, []string{
+			`manual: input:kubernetes_events:kubernetes_events.0: unknown property "Timestamp_Key"`,
+			`manual: input:tail:tail.2: unknown property "Typo"`,
+			`manual: output:opentelemetry:opentelemetry.0: unknown property "Uri"`,
+		}, manual)
+	})
+
+	t.Run("custom_rules", func(t *testing.T) {
+		cfg, err := ParseAs(configLiteral(`
+			[INPUT]
+				Name       tail
+				Path       /var/log/app.log
+				Old_Path   /var/log/old.log
+				Old_Tag    app
+		`), FormatClassic)
+		require.NoError(t, err)
+
+		got, report, err := cfg.Migrate(MigrateOptions{From: "26.1.6", To: "26.2.1", Rules: []MigrationRule{
+			{Version: "26.2.1", Kind: SectionKindInput, Key: "old_path", Action: MigrationRename, To: "path"},
+			{Version: "26.2.1", Kind: SectionKindInput, Plugin: "tail", Key: "old_tag", Action: MigrationRemove},
+			{Version: "26.1.6", Kind: SectionKindInput, Key: "path", Action: MigrationRemove},
+		}})
+		require.NoError(t, err)
+
+		require.Equal(t, MigrationChange{
+			Kind:    MigrationRenamed,
+			Section: "input:tail:tail.0",
+			Key:     "Old_Path",
+			To:      "path",
+			Note:    `"path" already set`,
+			Manual:  true,
+			Pos:     cfg.Pipeline.Inputs[0].Properties[2].Pos,
+		}, report[0])
+		require.Equal(t, MigrationRemoved, report[1].Kind)
+
+		require.True(t, got.Pipeline.Inputs[0].Properties.Has("path"), "rules before the source version are not applied")
+		require.False(t, got.Pipeline.Inputs[0].Properties.Has("old_tag"))
+	})
+
+	t.Run("invalid_version", func(t *testing.T) {
+		_, _, err := cfg.Migrate(MigrateOptions{From: "nope", To: "26.2.1"})
+		require.EqualError(t, err, "source schema: invalid semantic version: nope")
+	})
+}
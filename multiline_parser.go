@@ -0,0 +1,340 @@
+package fluentbitconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+type MultilineParsers []MultilineParser
+
+// IDs not namespaced.
+// For example: multiline-regex-test.0
+func (parsers MultilineParsers) IDs() []string {
+	var ids []string
+	for _, parser := range parsers {
+		ids = append(ids, parser.ID)
+	}
+	return ids
+}
+
+// FindByID were the id should not be namespaced.
+// For example: multiline-regex-test.0
+func (parsers MultilineParsers) FindByID(id string) (MultilineParser, bool) {
+	for _, parser := range parsers {
+		if parser.ID == id {
+			return parser, true
+		}
+	}
+	return MultilineParser{}, false
+}
+
+func (parsers *MultilineParsers) UnmarshalJSON(data []byte) error {
+	var dest []MultilineParser
+	err := json.Unmarshal(data, &dest)
+	if err != nil {
+		return err
+	}
+
+	*parsers = dest
+
+	for i, parser := range *parsers {
+		parser.ID = fmt.Sprintf("%s.%d", parser.Name, i)
+		(*parsers)[i] = parser
+	}
+
+	return nil
+}
+
+func (parsers *MultilineParsers) UnmarshalYAML(node *yaml.Node) error {
+	var dest []MultilineParser
+	err := node.Decode(&dest)
+	if err != nil {
+		return err
+	}
+
+	*parsers = dest
+
+	for i, parser := range *parsers {
+		parser.ID = fmt.Sprintf("%s.%d", parser.Name, i)
+		(*parsers)[i] = parser
+	}
+
+	return nil
+}
+
+func (parsers MultilineParsers) Equal(target MultilineParsers) bool {
+	return slices.EqualFunc(parsers, target, func(a, b MultilineParser) bool {
+		return a.Equal(b)
+	})
+}
+
+// MultilineParser section.
+// The `rule` entries are kept apart from the rest of the properties,
+// in the same order they were defined.
+type MultilineParser struct {
+	ID   string `json:"-" yaml:"-"`
+	Name string `json:"-" yaml:"-"`
+	// Pos where the parser section starts when parsed.
+	Pos        property.Position   `json:"-" yaml:"-"`
+	Properties property.Properties `json:",inline" yaml:",inline"`
+	Rules      MultilineRules      `json:"rules,omitempty" yaml:"rules,omitempty"`
+}
+
+func (p MultilineParser) MarshalJSON() ([]byte, error) {
+	props, err := p.allProperties(false /* classic */)
+	if err != nil {
+		return nil, fmt.Errorf("multiline parser %q: %w", p.ID, err)
+	}
+
+	return props.MarshalJSON()
+}
+
+func (p MultilineParser) MarshalYAML() (any, error) {
+	props, err := p.allProperties(false /* classic */)
+	if err != nil {
+		return nil, fmt.Errorf("multiline parser %q: %w", p.ID, err)
+	}
+
+	return props.MarshalYAML()
+}
+
+func (p *MultilineParser) UnmarshalJSON(data []byte) error {
+	var props property.Properties
+	if err := props.UnmarshalJSON(data); err != nil {
+		return err
+	}
+
+	return p.setProperties(props)
+}
+
+func (p *MultilineParser) UnmarshalYAML(node *yaml.Node) error {
+	var props property.Properties
+	if err := props.UnmarshalYAML(node); err != nil {
+		return err
+	}
+
+	p.Pos = property.Position{Line: node.Line, Column: node.Column}
+
+	return p.setProperties(props)
+}
+
+// setProperties splits the rules from the given properties.
+// Both the `rules` list from YAML and JSON and the repeated `rule` entries
+// from the classic format are accepted.
+func (p *MultilineParser) setProperties(props property.Properties) error {
+	p.Properties = nil
+	p.Rules = nil
+
+	for _, prop := range props {
+		if !strings.EqualFold(prop.Key, "rules") && !strings.EqualFold(prop.Key, "rule") {
+			p.Properties = append(p.Properties, prop)
+			continue
+		}
+
+		rules, err := multilineRulesFromAny(prop.Value)
+		if err != nil {
+			return fmt.Errorf("multiline parser %q: %w", Name(props), err)
+		}
+
+		p.Rules = append(p.Rules, rules...)
+	}
+
+	p.Name = Name(p.Properties)
+	return nil
+}
+
+// allProperties returns a copy of the parser properties
+// with the name and rules also included.
+func (p MultilineParser) allProperties(classic bool) (property.Properties, error) {
+	props, err := allPluginProperties(Plugin{Name: p.Name, Properties: p.Properties}, classic)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(p.Rules) == 0 {
+		return props, nil
+	}
+
+	props = slices.Clone(props)
+	if !classic {
+		return append(props, property.Property{Key: "rules", Value: p.Rules}), nil
+	}
+
+	for _, rule := range p.Rules {
+		props = append(props, property.Property{Key: "rule", Value: rule.String()})
+	}
+
+	return props, nil
+}
+
+func (p MultilineParser) Equal(target MultilineParser) bool {
+	return p.Properties.Equal(target.Properties) && slices.Equal(p.Rules, target.Rules)
+}
+
+type MultilineRules []MultilineRule
+
+// MultilineRule of a multiline parser state machine.
+// On classic format it is written as:
+//
+//	rule "start_state" "/regex/" "next_state"
+type MultilineRule struct {
+	State     string `json:"state" yaml:"state"`
+	Regex     string `json:"regex" yaml:"regex"`
+	NextState string `json:"next_state" yaml:"next_state"`
+}
+
+// String in classic format.
+func (r MultilineRule) String() string {
+	return quoteToken(r.State) + " " + quoteToken(r.Regex) + " " + quoteToken(r.NextState)
+}
+
+// ParseMultilineRule parses a rule from its classic format.
+// Example:
+//
+//	"start_state" "/([a-zA-Z]+ \d+ \d+\:\d+\:\d+)(.*)/" "cont"
+func ParseMultilineRule(s string) (MultilineRule, error) {
+	tokens, err := splitQuotedTokens(s)
+	if err != nil {
+		return MultilineRule{}, err
+	}
+
+	if len(tokens) != 3 {
+		return MultilineRule{}, fmt.Errorf("expected rule to have 3 entries: state, regex and next state, got %d", len(tokens))
+	}
+
+	return MultilineRule{
+		State:     tokens[0],
+		Regex:     tokens[1],
+		NextState: tokens[2],
+	}, nil
+}
+
+func multilineRulesFromAny(v any) (MultilineRules, error) {
+	switch v := v.(type) {
+	case MultilineRules:
+		return v, nil
+	case []MultilineRule:
+		return v, nil
+	case MultilineRule:
+		return MultilineRules{v}, nil
+	case string:
+		rule, err := ParseMultilineRule(v)
+		if err != nil {
+			return nil, err
+		}
+		return MultilineRules{rule}, nil
+	case map[string]any:
+		rule := MultilineRule{
+			State:     stringFromAny(v["state"]),
+			Regex:     stringFromAny(v["regex"]),
+			NextState: stringFromAny(v["next_state"]),
+		}
+		if v["state"] == nil || v["regex"] == nil || v["next_state"] == nil {
+			return nil, errors.New("expected rule to have state, regex and next_state")
+		}
+		return MultilineRules{rule}, nil
+	case []any:
+		var out MultilineRules
+		for _, item := range v {
+			rules, err := multilineRulesFromAny(item)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, rules...)
+		}
+		return out, nil
+	}
+
+	return nil, fmt.Errorf("invalid rule type %T", v)
+}
+
+// quoteToken double-quotes s so splitQuotedTokens reads it back.
+// Backslashes are only escaped before a double-quote
+// or at the end, so regular expressions stay readable.
+func quoteToken(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	var backslashes int
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			backslashes++
+			continue
+		case '"':
+			b.WriteString(strings.Repeat(`\`, backslashes*2+1))
+		default:
+			b.WriteString(strings.Repeat(`\`, backslashes))
+		}
+		backslashes = 0
+		b.WriteByte(s[i])
+	}
+	b.WriteString(strings.Repeat(`\`, backslashes*2))
+	b.WriteByte('"')
+	return b.String()
+}
+
+// splitQuotedTokens splits s by spaces, while keeping
+// double-quoted strings together.
+// Inside quoted strings, backslashes before a double-quote escape
+// each other and then the quote, if odd.
+// Any other backslash is kept as is, so regular expressions stay untouched.
+func splitQuotedTokens(s string) ([]string, error) {
+	var (
+		tokens  []string
+		current strings.Builder
+		quoted  bool
+		inToken bool
+	)
+
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case quoted && ch == '\\':
+			j := i
+			for j < len(s) && s[j] == '\\' {
+				j++
+			}
+
+			backslashes := j - i
+			if j == len(s) || s[j] != '"' {
+				current.WriteString(s[i:j])
+			} else {
+				current.WriteString(strings.Repeat(`\`, backslashes/2))
+				if backslashes%2 == 1 {
+					current.WriteByte('"')
+					j++
+				}
+			}
+			i = j - 1
+		case ch == '"':
+			quoted = !quoted
+			inToken = true
+		case !quoted && (ch == ' ' || ch == '\t'):
+			if inToken {
+				tokens = append(tokens, current.String())
+				current.Reset()
+				inToken = false
+			}
+		default:
+			current.WriteByte(ch)
+			inToken = true
+		}
+	}
+
+	if quoted {
+		return nil, errors.New("unterminated quoted string")
+	}
+
+	if inToken {
+		tokens = append(tokens, current.String())
+	}
+
+	return tokens, nil
+}
@@ -0,0 +1,182 @@
+package fluentbitconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+func TestParseMultilineRule(t *testing.T) {
+	tt := []struct {
+		name string
+		in   string
+		want MultilineRule
+		err  string
+	}{
+		{
+			name: "ok",
+			in:   `"start_state"   "/(Dec \d+ \d+\:\d+\:\d+)(.*)/"  "cont"`,
+			want: MultilineRule{State: "start_state", Regex: `/(Dec \d+ \d+\:\d+\:\d+)(.*)/`, NextState: "cont"},
+		},
+		{
+			name: "escaped_quote",
+			in:   `"cont" "/^\s+\"at\".*/" "cont"`,
+			want: MultilineRule{State: "cont", Regex: `/^\s+"at".*/`, NextState: "cont"},
+		},
+		{
+			name: "trailing_backslash",
+			in:   `"start_state" "/^C:\\\\" "cont"`,
+			want: MultilineRule{State: "start_state", Regex: `/^C:\\`, NextState: "cont"},
+		},
+		{
+			name: "escaped_backslash_before_quote",
+			in:   `"cont" "/\\\"at\"/" "cont"`,
+			want: MultilineRule{State: "cont", Regex: `/\"at"/`, NextState: "cont"},
+		},
+		{
+			name: "missing_next_state",
+			in:   `"start_state" "/foo/"`,
+			err:  "expected rule to have 3 entries: state, regex and next state, got 2",
+		},
+		{
+			name: "unterminated",
+			in:   `"start_state" "/foo/ "cont"`,
+			err:  "unterminated quoted string",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseMultilineRule(tc.in)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+
+			again, err := ParseMultilineRule(got.String())
+			require.NoError(t, err)
+			require.Equal(t, got, again)
+		})
+	}
+}
+
+func TestConfig_MultilineParsers(t *testing.T) {
+	t.Run("classic", func(t *testing.T) {
+		conf, err := ParseAs(`
+			[PARSER]
+				name json
+				format json
+			[MULTILINE_PARSER]
+				name  java
+				type  regex
+				rule  "start_state" "/^\d{4}/" "cont"
+				rule  "cont" "/^\s+at/" "cont"
+			[MULTILINE_PARSER]
+				name go
+				type regex
+		`, FormatClassic)
+		require.NoError(t, err)
+		require.Equal(t, MultilineParsers{
+			{
+				ID:   "java.0",
+				Name: "java",
+				Pos:  property.Position{Line: 5, Column: 4},
+				Properties: property.Properties{
+					{Key: "name", Value: "java", Pos: property.Position{Line: 6, Column: 5}},
+					{Key: "type", Value: "regex", Pos: property.Position{Line: 7, Column: 5}},
+				},
+				Rules: MultilineRules{
+					{State: "start_state", Regex: `/^\d{4}/`, NextState: "cont"},
+					{State: "cont", Regex: `/^\s+at/`, NextState: "cont"},
+				},
+			},
+			{
+				ID:   "go.1",
+				Name: "go",
+				Pos:  property.Position{Line: 10, Column: 4},
+				Properties: property.Properties{
+					{Key: "name", Value: "go", Pos: property.Position{Line: 11, Column: 5}},
+					{Key: "type", Value: "regex", Pos: property.Position{Line: 12, Column: 5}},
+				},
+			},
+		}, conf.MultilineParsers)
+		require.Equal(t, []string{"json.0"}, conf.Parsers.IDs())
+	})
+
+	t.Run("classic_round_trip", func(t *testing.T) {
+		conf, err := ParseAs(configLiteral(`
+			[MULTILINE_PARSER]
+				name windows
+				type regex
+				rule "start_state" "/^C:\\\\" "cont"
+		`), FormatClassic)
+		require.NoError(t, err)
+		require.Equal(t, MultilineRules{
+			{State: "start_state", Regex: `/^C:\\`, NextState: "cont"},
+		}, conf.MultilineParsers[0].Rules)
+
+		classic, err := conf.DumpAsClassic()
+		require.NoError(t, err)
+		require.Equal(t, configLiteral(`
+			[MULTILINE_PARSER]
+				name windows
+				type regex
+				rule "start_state" "/^C:\\\\" "cont"
+		`), classic)
+
+		again, err := ParseAs(classic, FormatClassic)
+		require.NoError(t, err)
+		require.Equal(t, conf.MultilineParsers[0].Rules, again.MultilineParsers[0].Rules)
+	})
+
+	t.Run("classic_invalid_rule", func(t *testing.T) {
+		_, err := ParseAs(configLiteral(`
+			[MULTILINE_PARSER]
+				name java
+				rule "start_state" "/^\d{4}/"
+		`), FormatClassic)
+		require.EqualError(t, err, "3: expected rule to have 3 entries: state, regex and next state, got 2")
+	})
+
+	t.Run("add_section_invalid_rule", func(t *testing.T) {
+		var conf Config
+		err := conf.AddSection(SectionKindMultilineParser, property.Properties{
+			{Key: "name", Value: "java"},
+			{Key: "rule", Value: `"start_state" "/^\d{4}/"`},
+		})
+		require.EqualError(t, err, `multiline parser "java": expected rule to have 3 entries: state, regex and next state, got 2`)
+		require.Empty(t, conf.MultilineParsers)
+	})
+
+	t.Run("yaml_invalid_rule", func(t *testing.T) {
+		_, err := ParseAs(configLiteral(`
+			multiline_parsers:
+				- name: java
+				  rules:
+					- state: start_state
+		`), FormatYAML)
+		require.EqualError(t, err, `multiline parser "java": expected rule to have state, regex and next_state`)
+	})
+
+	t.Run("find_by_id", func(t *testing.T) {
+		conf, err := ParseAs(configLiteral(`
+			multiline_parsers:
+				- name: java
+				  type: regex
+				  rules:
+					- '"start_state" "/^\d{4}/" "cont"'
+		`), FormatYAML)
+		require.NoError(t, err)
+
+		parser, ok := conf.MultilineParsers.FindByID("java.0")
+		require.True(t, ok)
+		require.Equal(t, MultilineRules{
+			{State: "start_state", Regex: `/^\d{4}/`, NextState: "cont"},
+		}, parser.Rules)
+	})
+}
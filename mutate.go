@@ -0,0 +1,233 @@
+package fluentbitconfig
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+// The methods in this file mutate the plugins of the config by ID,
+// where IDs are namespaced as returned by Config.IDs.
+// IDs of the affected plugin list are re-assigned after each change,
+// so aliased plugins keep their IDs while positional ones follow
+// their new position.
+
+// InsertPlugin inserts a new plugin with the given properties
+// at the given index of the customs, inputs, filters, outputs or parsers.
+// An index equal to the amount of plugins appends it.
+// It returns the ID of the inserted plugin.
+func (c *Config) InsertPlugin(kind SectionKind, index int, props property.Properties) (string, error) {
+	plugins, ok := c.pluginsOf(kind)
+	if !ok {
+		return "", fmt.Errorf("cannot insert plugin of kind %q", kind)
+	}
+
+	return c.insertPlugin(plugins, index, props)
+}
+
+// InsertProcessor inserts a new processor with the given properties
+// at the given index of the logs, metrics or traces processors
+// of the plugin with the given ID.
+// It returns the ID of the inserted processor.
+func (c *Config) InsertProcessor(pluginID, signal string, index int, props property.Properties) (string, error) {
+	ref, err := c.pluginRef(pluginID)
+	if err != nil {
+		return "", err
+	}
+
+	plugins, ok := ref.Plugin.Processors.Signal(signal)
+	if !ok {
+		return "", fmt.Errorf("unknown processor type: %s", signal)
+	}
+
+	return c.insertPlugin(plugins, index, props)
+}
+
+func (c *Config) insertPlugin(plugins *Plugins, index int, props property.Properties) (string, error) {
+	if index < 0 || index > len(*plugins) {
+		return "", fmt.Errorf("index %d out of range [0, %d]", index, len(*plugins))
+	}
+
+	name := Name(props)
+	if name == "" {
+		return "", ErrMissingName
+	}
+
+	*plugins = slices.Insert(*plugins, index, Plugin{Name: name, Properties: cloneProperties(props)})
+	reassignIDs(*plugins)
+
+	return c.idAt(plugins, index), nil
+}
+
+// RemoveByID removes the plugin or processor with the given ID.
+func (c *Config) RemoveByID(id string) error {
+	ref, err := c.pluginRef(id)
+	if err != nil {
+		return err
+	}
+
+	*ref.List = slices.Delete(*ref.List, ref.Index, ref.Index+1)
+	reassignIDs(*ref.List)
+	return nil
+}
+
+// MoveByID moves the plugin or processor with the given ID
+// to the given index within its own list.
+// This is how filters and processors are re-ordered.
+func (c *Config) MoveByID(id string, index int) error {
+	ref, err := c.pluginRef(id)
+	if err != nil {
+		return err
+	}
+
+	list := *ref.List
+	if index < 0 || index >= len(list) {
+		return fmt.Errorf("%s: index %d out of range [0, %d]", id, index, len(list)-1)
+	}
+
+	plugin := list[ref.Index]
+	list = slices.Delete(list, ref.Index, ref.Index+1)
+	*ref.List = slices.Insert(list, index, plugin)
+	reassignIDs(*ref.List)
+	return nil
+}
+
+// ReplaceByID replaces the properties of the plugin or processor
+// with a copy of the given ones.
+// The processors of the replaced plugin are kept.
+// It returns the new ID, which changes when the name or alias does.
+func (c *Config) ReplaceByID(id string, props property.Properties) (string, error) {
+	return c.UpdateProperties(id, func(pp *property.Properties) error {
+		*pp = cloneProperties(props)
+		return nil
+	})
+}
+
+// UpdateProperties calls fn with a copy of the properties of the plugin
+// or processor with the given ID, including nested list and map values,
+// which replaces them if fn succeeds.
+// Nothing is changed if fn fails.
+// It returns the new ID, which changes when the name or alias does.
+func (c *Config) UpdateProperties(id string, fn func(props *property.Properties) error) (string, error) {
+	ref, err := c.pluginRef(id)
+	if err != nil {
+		return "", err
+	}
+
+	props := cloneProperties(ref.Plugin.Properties)
+	if err := fn(&props); err != nil {
+		return "", fmt.Errorf("%s: %w", id, err)
+	}
+
+	name := Name(props)
+	if name == "" {
+		return "", fmt.Errorf("%s: %w", id, ErrMissingName)
+	}
+
+	ref.Plugin.Name = name
+	ref.Plugin.Properties = props
+	reassignIDs(*ref.List)
+
+	return c.idAt(ref.List, ref.Index), nil
+}
+
+// pluginsOf returns the top-level plugins of the given kind.
+func (c *Config) pluginsOf(kind SectionKind) (*Plugins, bool) {
+	switch kind {
+	case SectionKindCustom:
+		return &c.Customs, true
+	case SectionKindInput:
+		return &c.Pipeline.Inputs, true
+	case SectionKindFilter:
+		return &c.Pipeline.Filters, true
+	case SectionKindOutput:
+		return &c.Pipeline.Outputs, true
+	case SectionKindParser:
+		return &c.Parsers, true
+	}
+
+	return nil, false
+}
+
+// pluginRef finds the plugin or processor with the given ID.
+func (c *Config) pluginRef(id string) (pluginRef, error) {
+	for _, ref := range c.pluginRefs() {
+		if ref.ID == id {
+			return ref, nil
+		}
+	}
+
+	return pluginRef{}, &PluginNotFoundError{ID: id}
+}
+
+// idAt returns the ID of the plugin at the given index of the given list.
+func (c *Config) idAt(plugins *Plugins, index int) string {
+	for _, ref := range c.pluginRefs() {
+		if ref.List == plugins && ref.Index == index {
+			return ref.ID
+		}
+	}
+
+	return ""
+}
+
+// refreshPlugins sets the name of every plugin and processor
+// from its properties and re-assigns their IDs,
+// as names and aliases might have been variable references.
+func (c *Config) refreshPlugins() {
+	var lists []*Plugins
+	for _, ref := range c.pluginRefs() {
+		if name := Name(ref.Plugin.Properties); name != "" {
+			ref.Plugin.Name = name
+		}
+		if !slices.Contains(lists, ref.List) {
+			lists = append(lists, ref.List)
+		}
+	}
+
+	for _, list := range lists {
+		reassignIDs(*list)
+	}
+}
+
+// cloneProperties returns a copy of the properties
+// with their list and map values copied too.
+func cloneProperties(props property.Properties) property.Properties {
+	if props == nil {
+		return nil
+	}
+
+	out := make(property.Properties, len(props))
+	for i, p := range props {
+		p.Value = cloneValue(p.Value)
+		out[i] = p
+	}
+	return out
+}
+
+func cloneValue(v any) any {
+	switch v := v.(type) {
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = cloneValue(item)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			out[key] = cloneValue(item)
+		}
+		return out
+	}
+
+	return v
+}
+
+// reassignIDs of the plugins after they were modified.
+func reassignIDs(plugins Plugins) {
+	for i := range plugins {
+		plugins[i].ID = pluginID(plugins[i].Name, plugins[i].Properties, i)
+	}
+}
@@ -0,0 +1,145 @@
+package fluentbitconfig
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+func TestConfig_mutations(t *testing.T) {
+	parse := func(t *testing.T) Config {
+		t.Helper()
+
+		cfg, err := ParseAs(configLiteral(`
+			pipeline:
+				inputs:
+					- name: tail
+					  alias: app_logs
+					- name: tail
+					  path:
+						- /var/log/a.log
+				filters:
+					- name: grep
+					- name: modify
+					- name: lua
+				outputs:
+					- name: stdout
+					  processors:
+						logs:
+							- name: content_modifier
+		`), FormatYAML)
+		require.NoError(t, err)
+		return cfg
+	}
+
+	t.Run("insert", func(t *testing.T) {
+		cfg := parse(t)
+		id, err := cfg.InsertPlugin(SectionKindInput, 0, property.Properties{{Key: "name", Value: "tail"}})
+		require.NoError(t, err)
+		require.Equal(t, "input:tail:tail.0", id)
+		require.Equal(t, []string{"tail.0", "app_logs", "tail.2"}, cfg.Pipeline.Inputs.IDs())
+
+		props := property.Properties{{Key: "name", Value: "cpu"}}
+		_, err = cfg.InsertPlugin(SectionKindInput, 3, props)
+		require.NoError(t, err)
+		props[0].Value = "mem"
+		require.Equal(t, "cpu", cfg.Pipeline.Inputs[3].Properties[0].Value, "properties are copied")
+
+		_, err = cfg.InsertPlugin(SectionKindInput, 5, property.Properties{{Key: "name", Value: "cpu"}})
+		require.EqualError(t, err, "index 5 out of range [0, 4]")
+
+		_, err = cfg.InsertPlugin(SectionKindService, 0, property.Properties{{Key: "flush", Value: 1}})
+		require.EqualError(t, err, `cannot insert plugin of kind "service"`)
+
+		_, err = cfg.InsertPlugin(SectionKindOutput, 0, property.Properties{{Key: "match", Value: "*"}})
+		require.ErrorIs(t, err, ErrMissingName)
+	})
+
+	t.Run("insert_processor", func(t *testing.T) {
+		cfg := parse(t)
+		id, err := cfg.InsertProcessor("output:stdout:stdout.0", "logs", 0, property.Properties{{Key: "name", Value: "sql"}})
+		require.NoError(t, err)
+		require.Equal(t, "output:stdout:stdout.0/logs:sql:sql.0", id)
+		require.Equal(t, []string{"sql.0", "content_modifier.1"}, cfg.Pipeline.Outputs[0].Processors.Logs.IDs())
+
+		_, err = cfg.InsertProcessor("output:stdout:stdout.0", "profiles", 0, property.Properties{{Key: "name", Value: "sql"}})
+		require.EqualError(t, err, "unknown processor type: profiles")
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		cfg := parse(t)
+		require.NoError(t, cfg.RemoveByID("filter:grep:grep.0"))
+		require.Equal(t, []string{"modify.0", "lua.1"}, cfg.Pipeline.Filters.IDs())
+
+		require.NoError(t, cfg.RemoveByID("output:stdout:stdout.0/logs:content_modifier:content_modifier.0"))
+		require.True(t, cfg.Pipeline.Outputs[0].Processors.IsEmpty())
+	})
+
+	t.Run("move", func(t *testing.T) {
+		cfg := parse(t)
+		require.NoError(t, cfg.MoveByID("filter:lua:lua.2", 0))
+		require.Equal(t, []string{"lua.0", "grep.1", "modify.2"}, cfg.Pipeline.Filters.IDs())
+
+		require.NoError(t, cfg.MoveByID("filter:lua:lua.0", 2))
+		require.Equal(t, []string{"grep.0", "modify.1", "lua.2"}, cfg.Pipeline.Filters.IDs())
+
+		err := cfg.MoveByID("filter:lua:lua.2", 3)
+		require.EqualError(t, err, "filter:lua:lua.2: index 3 out of range [0, 2]")
+
+		require.NoError(t, cfg.MoveByID("input:tail:app_logs", 1))
+		require.Equal(t, []string{"tail.0", "app_logs"}, cfg.Pipeline.Inputs.IDs(), "aliased ID is kept")
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		cfg := parse(t)
+		id, err := cfg.ReplaceByID("output:stdout:stdout.0", property.Properties{
+			{Key: "name", Value: "null"},
+			{Key: "match", Value: "*"},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "output:null:null.0", id)
+
+		got, ok := cfg.FindByID(id)
+		require.True(t, ok)
+		require.Equal(t, "null", got.Name)
+		require.Equal(t, []string{"content_modifier.0"}, got.Processors.Logs.IDs(), "processors are kept")
+	})
+
+	t.Run("update", func(t *testing.T) {
+		cfg := parse(t)
+		id, err := cfg.UpdateProperties("input:tail:tail.1", func(props *property.Properties) error {
+			props.Set("alias", "system_logs")
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, "input:tail:system_logs", id)
+
+		_, err = cfg.UpdateProperties(id, func(props *property.Properties) error {
+			v, _ := props.Get("path")
+			v.([]any)[0] = "/var/log/syslog"
+			props.Set("tag", "syslog")
+			return errors.New("nope")
+		})
+		require.EqualError(t, err, "input:tail:system_logs: nope")
+		require.False(t, cfg.Pipeline.Inputs[1].Properties.Has("tag"), "not modified on error")
+
+		v, _ := cfg.Pipeline.Inputs[1].Properties.Get("path")
+		require.Equal(t, []any{"/var/log/a.log"}, v, "nested values not modified on error")
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		cfg := parse(t)
+
+		err := cfg.RemoveByID("input:tail:tail.0")
+		require.EqualError(t, err, `plugin "input:tail:tail.0" not found`)
+
+		var notFound *PluginNotFoundError
+		require.ErrorAs(t, err, &notFound)
+
+		err = cfg.MoveByID("grep.0", 1)
+		require.EqualError(t, err, `plugin "grep.0" not found: IDs must be namespaced, for example input:tail:tail.0`)
+	})
+}
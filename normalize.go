@@ -0,0 +1,222 @@
+package fluentbitconfig
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+// NormalizeOptions for Config.Normalize.
+type NormalizeOptions struct {
+	// Schema used to canonicalize values by their type.
+	// DefaultSchema is used when nil.
+	Schema *Schema
+	// StripDefaults removes plugin properties
+	// whose value is the schema default.
+	StripDefaults bool
+}
+
+// serviceOptions are the types of the service properties,
+// which are not part of the schema.
+var serviceOptions = []SchemaOptions{
+	{Name: "flush", Type: "double"},
+	{Name: "grace", Type: "integer"},
+	{Name: "daemon", Type: "boolean"},
+	{Name: "log_level", Type: "string"},
+	{Name: "http_server", Type: "boolean"},
+	{Name: "http_port", Type: "integer"},
+	{Name: "hot_reload", Type: "boolean"},
+	{Name: "health_check", Type: "boolean"},
+	{Name: "hc_errors_count", Type: "integer"},
+	{Name: "hc_retry_failure_count", Type: "integer"},
+	{Name: "hc_period", Type: "integer"},
+	{Name: "scheduler.cap", Type: "integer"},
+	{Name: "scheduler.base", Type: "integer"},
+	{Name: "coro_stack_size", Type: "integer"},
+	{Name: "storage.metrics", Type: "boolean"},
+	{Name: "storage.checksum", Type: "boolean"},
+	{Name: "storage.max_chunks_up", Type: "integer"},
+	{Name: "storage.backlog.mem_limit", Type: "size"},
+	{Name: "storage.delete_irrecoverable_chunks", Type: "boolean"},
+}
+
+// Normalize returns a canonical copy of the config,
+// so configs that behave the same are equal.
+//
+//   - Property keys are lowercased, except for env variables.
+//   - Values are canonicalized by their schema type:
+//     booleans like On to true, integers and doubles to numbers,
+//     sizes like 5M to bytes and times like 1m to seconds.
+//     Other numeric values are numbers and the rest are kept as they are.
+//   - Plugin, processor and multiline parser properties keep their order,
+//     as filters like modify apply their rules in order.
+//     Env keeps its order too, as variables can reference previous ones.
+//   - Service and upstream node properties are sorted by key,
+//     with the name first. Repeated keys keep their relative order.
+//   - Multiline parsers and upstreams are sorted by name.
+//     Plugins keep their order, which is meaningful.
+//
+// Property positions are kept.
+func (c Config) Normalize(opts NormalizeOptions) Config {
+	schema := DefaultSchema
+	if opts.Schema != nil {
+		schema = *opts.Schema
+	}
+
+	out := c.clone()
+
+	for i, p := range out.Env {
+		out.Env[i].Value = normalizeValue(SchemaOptions{Type: "string"}, p.Value)
+	}
+	out.Service = normalizeProperties(out.Service, func(key string) (SchemaOptions, bool) {
+		opts := SchemaOptionList(serviceOptions).FindOption(key)
+		if opts == nil {
+			return SchemaOptions{}, false
+		}
+		return *opts, true
+	}, false)
+	sortProperties(out.Service)
+
+	for _, ref := range out.pluginRefs() {
+		section, _ := schema.findSection(ref.Kind, ref.Plugin.Name)
+		ref.Plugin.Properties = normalizeProperties(ref.Plugin.Properties, section.findOptions, opts.StripDefaults)
+	}
+
+	slices.SortStableFunc(out.MultilineParsers, func(a, b MultilineParser) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	for i, parser := range out.MultilineParsers {
+		out.MultilineParsers[i].ID = fmt.Sprintf("%s.%d", parser.Name, i)
+		out.MultilineParsers[i].Properties = normalizeProperties(parser.Properties, noSchemaOptions, false)
+	}
+
+	slices.SortStableFunc(out.Upstreams, func(a, b Upstream) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	for _, upstream := range out.Upstreams {
+		for i, node := range upstream.Nodes {
+			upstream.Nodes[i].Properties = normalizeProperties(node.Properties, noSchemaOptions, false)
+			sortProperties(upstream.Nodes[i].Properties)
+		}
+	}
+
+	return out
+}
+
+// Equivalent tells whether both configs behave the same,
+// that is, they are equal once normalized with the default schema
+// and with properties set to their default stripped.
+func (c Config) Equivalent(target Config) bool {
+	opts := NormalizeOptions{StripDefaults: true}
+	return c.Normalize(opts).Equal(target.Normalize(opts))
+}
+
+func noSchemaOptions(string) (SchemaOptions, bool) {
+	return SchemaOptions{}, false
+}
+
+// normalizeProperties returns the properties with lowercased keys
+// and canonical values, in the same order.
+func normalizeProperties(props property.Properties, findOptions func(key string) (SchemaOptions, bool), stripDefaults bool) property.Properties {
+	if props == nil {
+		return nil
+	}
+
+	out := property.Properties{}
+	for _, p := range props {
+		p.Key = strings.ToLower(p.Key)
+
+		opts, ok := findOptions(p.Key)
+		switch {
+		case p.Key == "name":
+			p.Value = Name(property.Properties{p})
+		case isCommonProperty(p.Key):
+			p.Value = normalizeValue(SchemaOptions{Type: "string"}, p.Value)
+		case ok:
+			p.Value = normalizeValue(opts, p.Value)
+			if stripDefaults && opts.Default != nil && reflect.DeepEqual(p.Value, normalizeValue(opts, opts.Default)) {
+				continue
+			}
+		default:
+			p.Value = normalizeValue(SchemaOptions{}, p.Value)
+		}
+
+		out = append(out, p)
+	}
+
+	return out
+}
+
+// sortProperties by key, with the name first.
+// Repeated keys keep their relative order.
+func sortProperties(props property.Properties) {
+	slices.SortStableFunc(props, func(a, b property.Property) int {
+		if a.Key == b.Key {
+			return 0
+		}
+		if a.Key == "name" {
+			return -1
+		}
+		if b.Key == "name" {
+			return 1
+		}
+		return cmp.Compare(a.Key, b.Key)
+	})
+}
+
+// normalizeValue canonicalizes a value by its schema type.
+// Values that are not valid for the type are kept as they are.
+func normalizeValue(opts SchemaOptions, v any) any {
+	switch opts.Type {
+	case "boolean":
+		if b, ok := property.ParseBool(v); ok {
+			return b
+		}
+	case "integer":
+		if i, ok := property.ParseInt(v); ok {
+			return i
+		}
+	case "double":
+		if f, ok := property.ParseFloat(v); ok {
+			return normalizeNumber(f)
+		}
+	case "size":
+		if i, ok := property.ParseSize(v); ok {
+			return i
+		}
+	case "time":
+		if f, ok := property.ParseSeconds(v); ok {
+			return normalizeNumber(f)
+		}
+	case "string", "prefixed string":
+		switch v.(type) {
+		case string, map[string]any, []any, nil:
+			return v
+		}
+		return stringFromAny(v)
+	}
+
+	switch v.(type) {
+	case string, bool:
+		return v
+	}
+
+	if f, ok := property.ParseFloat(v); ok {
+		return normalizeNumber(f)
+	}
+
+	return v
+}
+
+// normalizeNumber returns integer numbers as int64.
+func normalizeNumber(f float64) any {
+	if isFloatInt(f) {
+		return int64(f)
+	}
+
+	return f
+}
@@ -0,0 +1,212 @@
+package fluentbitconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Normalize(t *testing.T) {
+	cfg, err := ParseAs(configLiteral(`
+		[SERVICE]
+			Log_Level   info
+			Flush       1
+			HTTP_Server On
+
+		[INPUT]
+			Name            Tail
+			Path            /var/log/app.log
+			Skip_Long_Lines On
+			Buffer_Max_Size 5M
+			Rotate_Wait     1m
+
+		[OUTPUT]
+			Name          stdout
+			Match         *
+			Format        msgpack
+			Json_Date_Key date
+	`), FormatClassic)
+	require.NoError(t, err)
+
+	got := cfg.Normalize(NormalizeOptions{})
+	require.Equal(t, configLiteral(`
+		service:
+		    flush: 1
+		    http_server: true
+		    log_level: info
+		pipeline:
+		    inputs:
+		        - name: tail
+		          path: /var/log/app.log
+		          skip_long_lines: true
+		          buffer_max_size: 5242880
+		          rotate_wait: 60
+		    outputs:
+		        - name: stdout
+		          match: '*'
+		          format: msgpack
+		          json_date_key: date
+	`), mustDumpYAML(t, got))
+	require.Equal(t, "Tail", cfg.Pipeline.Inputs[0].Properties[0].Value, "original not modified")
+
+	got = cfg.Normalize(NormalizeOptions{StripDefaults: true})
+	require.Equal(t, configLiteral(`
+		service:
+		    flush: 1
+		    http_server: true
+		    log_level: info
+		pipeline:
+		    inputs:
+		        - name: tail
+		          path: /var/log/app.log
+		          skip_long_lines: true
+		          buffer_max_size: 5242880
+		          rotate_wait: 60
+		    outputs:
+		        - name: stdout
+		          match: '*'
+		          format: msgpack
+	`), mustDumpYAML(t, got))
+}
+
+func TestConfig_Equivalent(t *testing.T) {
+	classic, err := ParseAs(configLiteral(`
+		[SERVICE]
+			Flush 1
+
+		[INPUT]
+			Name            tail
+			Path            /var/log/app.log
+			Skip_Long_Lines On
+			Buffer_Max_Size 5M
+
+		[FILTER]
+			Name  grep
+			Match *
+			Regex log error
+
+		[FILTER]
+			Name  modify
+			Match *
+			Add   env prod
+
+		[OUTPUT]
+			Name  stdout
+			Match *
+	`), FormatClassic)
+	require.NoError(t, err)
+
+	yaml, err := ParseAs(configLiteral(`
+		service:
+			flush: '1'
+		pipeline:
+			inputs:
+				- name: tail
+				  path: /var/log/app.log
+				  skip_long_lines: true
+				  buffer_max_size: 5242880
+			filters:
+				- name: grep
+				  match: '*'
+				  regex: log error
+				- name: modify
+				  match: '*'
+				  add: env prod
+			outputs:
+				- name: stdout
+				  match: '*'
+				  json_date_key: date
+	`), FormatYAML)
+	require.NoError(t, err)
+
+	require.False(t, classic.Equal(yaml))
+	require.True(t, classic.Equivalent(yaml))
+	require.True(t, yaml.Equivalent(classic))
+
+	yaml.Pipeline.Filters[0], yaml.Pipeline.Filters[1] = yaml.Pipeline.Filters[1], yaml.Pipeline.Filters[0]
+	reassignIDs(yaml.Pipeline.Filters)
+	require.False(t, classic.Equivalent(yaml), "filters order matters")
+}
+
+func TestConfig_Equivalent_order(t *testing.T) {
+	parse := func(text string) Config {
+		cfg, err := ParseAs(configLiteral(text), FormatClassic)
+		require.NoError(t, err)
+		return cfg
+	}
+
+	a := parse(`
+		@SET A=1
+		@SET B=${A}
+		[FILTER]
+			Name   modify
+			Match  *
+			Rename a b
+			Copy   b c
+	`)
+
+	b := parse(`
+		@SET B=${A}
+		@SET A=1
+		[FILTER]
+			Name   modify
+			Match  *
+			Rename a b
+			Copy   b c
+	`)
+	require.False(t, a.Equivalent(b), "env order matters")
+
+	b = parse(`
+		@SET A=1
+		@SET B=${A}
+		[FILTER]
+			Name   modify
+			Match  *
+			Copy   b c
+			Rename a b
+	`)
+	require.False(t, a.Equivalent(b), "modify rules order matters")
+
+	b = parse(`
+		@SET A=1
+		@SET B=${A}
+		[FILTER]
+			name   modify
+			match  *
+			rename a b
+			copy   b c
+	`)
+	require.True(t, a.Equivalent(b))
+}
+
+func TestConfig_Equivalent_testdata(t *testing.T) {
+	names, err := filepath.Glob("testdata/*.conf")
+	require.NoError(t, err)
+
+	for _, name := range names {
+		base := strings.TrimSuffix(name, ".conf")
+		t.Run(filepath.Base(base), func(t *testing.T) {
+			parse := func(filename string, format Format) Config {
+				b, err := os.ReadFile(filename)
+				require.NoError(t, err)
+
+				cfg, err := ParseAs(string(b), format)
+				require.NoError(t, err)
+				return cfg
+			}
+
+			classic := parse(name, FormatClassic)
+			for _, format := range []Format{FormatYAML, FormatJSON} {
+				filename := base + "." + string(format)
+				if _, err := os.Stat(filename); err != nil {
+					continue
+				}
+
+				require.True(t, classic.Equivalent(parse(filename, format)), filename)
+			}
+		})
+	}
+}
@@ -0,0 +1,824 @@
+package fluentbitconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+// PatchError is returned when a patch could not be applied.
+type PatchError struct {
+	// Index of the failed JSON Patch operation.
+	// Always zero for merge patches.
+	Index int
+	// Op of the failed JSON Patch operation.
+	// Empty for merge patches.
+	Op string
+	// Path where the patch failed.
+	Path string
+	Err  error
+}
+
+func (e *PatchError) Error() string {
+	if e.Op == "" {
+		return fmt.Sprintf("%s: %v", e.Path, e.Err)
+	}
+
+	return fmt.Sprintf("operation %d: %s %s: %v", e.Index, e.Op, e.Path, e.Err)
+}
+
+func (e *PatchError) Unwrap() error {
+	return e.Err
+}
+
+// ApplyPatch applies a RFC 6902 JSON Patch document
+// to the JSON representation of the config.
+//
+// Besides array indexes, items of plugin lists can be addressed by ID.
+// For example: /pipeline/inputs/tail.0/path
+// or /pipeline/outputs/my_alias/processors/logs/content_modifier.0.
+// Object members are matched case-insensitively when there is no exact match,
+// as property keys are.
+//
+// Property ordering is preserved and new properties are appended.
+// The config is not modified if any operation fails.
+func (c *Config) ApplyPatch(patch []byte) error {
+	var ops []struct {
+		Op    string          `json:"op"`
+		Path  *string         `json:"path"`
+		From  *string         `json:"from"`
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return fmt.Errorf("invalid JSON patch: %w", err)
+	}
+
+	doc, err := c.patchDocument()
+	if err != nil {
+		return err
+	}
+
+	for i, op := range ops {
+		if op.Path == nil {
+			return &PatchError{Index: i, Op: op.Op, Err: errors.New("missing path")}
+		}
+
+		fail := func(err error) error {
+			return &PatchError{Index: i, Op: op.Op, Path: *op.Path, Err: err}
+		}
+
+		path, err := parseJSONPointer(*op.Path)
+		if err != nil {
+			return fail(err)
+		}
+
+		var value any
+		switch op.Op {
+		case "add", "replace", "test":
+			if op.Value == nil {
+				return fail(errors.New("missing value"))
+			}
+
+			value, err = decodeOrderedJSON(op.Value)
+			if err != nil {
+				return fail(fmt.Errorf("invalid value: %w", err))
+			}
+		}
+
+		var from []string
+		switch op.Op {
+		case "move", "copy":
+			if op.From == nil {
+				return fail(errors.New("missing from"))
+			}
+
+			from, err = parseJSONPointer(*op.From)
+			if err != nil {
+				return fail(fmt.Errorf("from: %w", err))
+			}
+		}
+
+		switch op.Op {
+		case "add":
+			doc, err = jsonAdd(doc, path, value)
+		case "remove":
+			doc, _, err = jsonRemove(doc, path)
+		case "replace":
+			doc, err = jsonReplace(doc, path, value)
+		case "move":
+			if isPathPrefix(from, path) && len(from) != len(path) {
+				return fail(fmt.Errorf("cannot move %s into itself", *op.From))
+			}
+
+			doc, value, err = jsonRemove(doc, from)
+			if err == nil {
+				doc, err = jsonAdd(doc, path, value)
+			}
+		case "copy":
+			value, err = jsonGet(doc, from)
+			if err == nil {
+				doc, err = jsonAdd(doc, path, cloneOrderedJSON(value))
+			}
+		case "test":
+			var got any
+			got, err = jsonGet(doc, path)
+			if err == nil && !equalOrderedJSON(got, value) {
+				err = errors.New("test failed: value differs")
+			}
+		default:
+			err = fmt.Errorf("unknown operation %q", op.Op)
+		}
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	return c.setPatchDocument(doc)
+}
+
+// ApplyMergePatch applies a RFC 7396 merge patch document
+// to the JSON representation of the config.
+//
+// As an extension, plugin lists can be patched with an object
+// keyed by ID or index instead of being replaced with an array.
+// Null values remove the plugin. For example:
+//
+//	{"pipeline": {"inputs": {"tail.0": {"path": "/var/log/app.log"}, "cpu.1": null}}}
+//
+// Property ordering is preserved and new properties are appended.
+// The config is not modified if the patch fails.
+func (c *Config) ApplyMergePatch(patch []byte) error {
+	value, err := decodeOrderedJSON(patch)
+	if err != nil {
+		return fmt.Errorf("invalid merge patch: %w", err)
+	}
+
+	doc, err := c.patchDocument()
+	if err != nil {
+		return err
+	}
+
+	doc, err = mergePatchJSON(doc, value, "")
+	if err != nil {
+		return err
+	}
+
+	return c.setPatchDocument(doc)
+}
+
+// patchDocument returns the config as an ordered JSON document.
+func (c Config) patchDocument() (any, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeOrderedJSON(b)
+}
+
+// setPatchDocument decodes the patched document into the config.
+// Values and positions of unchanged properties are kept.
+func (c *Config) setPatchDocument(doc any) error {
+	var buf bytes.Buffer
+	if err := encodeOrderedJSON(&buf, doc); err != nil {
+		return err
+	}
+
+	var out Config
+	dec := json.NewDecoder(&buf)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&out); err != nil {
+		return fmt.Errorf("invalid patched config: %w", err)
+	}
+
+	for _, ref := range out.pluginRefs() {
+		if ref.Plugin.Name == "" {
+			return fmt.Errorf("invalid patched config: %s %d: %w", ref.Kind, ref.Index, ErrMissingName)
+		}
+	}
+
+	restoreIntegers(&out.Env)
+	for _, ref := range out.propertiesRefs() {
+		restoreIntegers(ref.Props)
+	}
+
+	out.restoreFrom(*c)
+	*c = out
+	return nil
+}
+
+// restoreIntegers replaces the integral float64 values
+// decoded from JSON with int64 ones,
+// like the classic and YAML parsers decode them.
+func restoreIntegers(props *property.Properties) {
+	for i, p := range *props {
+		(*props)[i].Value = integerFromJSON(p.Value)
+	}
+}
+
+func integerFromJSON(v any) any {
+	switch v := v.(type) {
+	case float64:
+		if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+			return int64(v)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = integerFromJSON(item)
+		}
+	case map[string]any:
+		for k, item := range v {
+			v[k] = integerFromJSON(item)
+		}
+	}
+
+	return v
+}
+
+// restoreFrom sets the positions, and the values when equal in JSON,
+// of the sections and properties found on the given config.
+// Values decoded from JSON lose their original type, like integers
+// decoded from YAML, so unchanged ones are restored.
+func (c *Config) restoreFrom(orig Config) {
+	restoreProperties(c.Env, orig.Env)
+	restoreProperties(c.Service, orig.Service)
+
+	origRefs := map[string]pluginRef{}
+	for _, ref := range orig.pluginRefs() {
+		origRefs[ref.ID] = ref
+	}
+	for _, ref := range c.pluginRefs() {
+		if o, ok := origRefs[ref.ID]; ok {
+			ref.Plugin.Pos = o.Plugin.Pos
+			restoreProperties(ref.Plugin.Properties, o.Plugin.Properties)
+		}
+	}
+
+	for i, parser := range c.MultilineParsers {
+		if o, ok := orig.MultilineParsers.FindByID(parser.ID); ok && o.Name == parser.Name {
+			c.MultilineParsers[i].Pos = o.Pos
+			restoreProperties(parser.Properties, o.Properties)
+		}
+	}
+
+	for i, upstream := range c.Upstreams {
+		o, ok := orig.Upstreams.FindByName(upstream.Name)
+		if !ok {
+			continue
+		}
+
+		c.Upstreams[i].Pos = o.Pos
+		for j, node := range upstream.Nodes {
+			if o, ok := o.Nodes.FindByID(node.ID); ok {
+				upstream.Nodes[j].Pos = o.Pos
+				restoreProperties(node.Properties, o.Properties)
+			}
+		}
+	}
+
+	for i, task := range c.StreamTasks {
+		j := slices.IndexFunc(orig.StreamTasks, func(t StreamTask) bool { return t.Name == task.Name })
+		if j >= 0 {
+			c.StreamTasks[i].Pos = orig.StreamTasks[j].Pos
+		}
+	}
+}
+
+// restoreProperties sets the value and position of each property of dst
+// from the property of src with the same key, if their values are equal in JSON.
+// Repeated keys are matched in order.
+func restoreProperties(dst, src property.Properties) {
+	used := make([]bool, len(src))
+	for i, p := range dst {
+		for j, o := range src {
+			if used[j] || !strings.EqualFold(p.Key, o.Key) {
+				continue
+			}
+
+			used[j] = true
+			a, errA := json.Marshal(p.Value)
+			b, errB := json.Marshal(o.Value)
+			if errA == nil && errB == nil && bytes.Equal(a, b) {
+				dst[i].Value = o.Value
+				dst[i].Pos = o.Pos
+			}
+			break
+		}
+	}
+}
+
+// mergePatchJSON applies a merge patch value at the given path.
+func mergePatchJSON(target, patch any, path string) (any, error) {
+	obj, ok := patch.(orderedObject)
+	if !ok {
+		return patch, nil
+	}
+
+	if list, ok := target.([]any); ok {
+		return mergePatchList(list, obj, path)
+	}
+
+	targetObj, _ := target.(orderedObject)
+	targetObj = slices.Clone(targetObj)
+	for _, m := range obj {
+		i := targetObj.index(m.Key)
+		if m.Value == nil {
+			if i >= 0 {
+				targetObj = slices.Delete(targetObj, i, i+1)
+			}
+			continue
+		}
+
+		if i < 0 {
+			v, err := mergePatchJSON(nil, m.Value, path+"/"+escapeJSONPointer(m.Key))
+			if err != nil {
+				return nil, err
+			}
+
+			targetObj = append(targetObj, orderedMember{Key: m.Key, Value: v})
+			continue
+		}
+
+		v, err := mergePatchJSON(targetObj[i].Value, m.Value, path+"/"+escapeJSONPointer(m.Key))
+		if err != nil {
+			return nil, err
+		}
+
+		targetObj[i].Value = v
+	}
+
+	return targetObj, nil
+}
+
+// mergePatchList patches the items of a list by ID or index.
+// Items are resolved before any of them is removed.
+func mergePatchList(list []any, patch orderedObject, path string) (any, error) {
+	list = slices.Clone(list)
+	removed := map[int]bool{}
+	for _, m := range patch {
+		itemPath := path + "/" + escapeJSONPointer(m.Key)
+		i, err := jsonArrayIndex(list, m.Key, false)
+		if err != nil {
+			return nil, &PatchError{Path: itemPath, Err: err}
+		}
+
+		if m.Value == nil {
+			removed[i] = true
+			continue
+		}
+
+		v, err := mergePatchJSON(list[i], m.Value, itemPath)
+		if err != nil {
+			return nil, err
+		}
+
+		list[i] = v
+	}
+
+	var out []any
+	for i, item := range list {
+		if !removed[i] {
+			out = append(out, item)
+		}
+	}
+
+	return out, nil
+}
+
+func jsonGet(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch v := doc.(type) {
+		case orderedObject:
+			i := v.index(token)
+			if i < 0 {
+				return nil, fmt.Errorf("%q not found", token)
+			}
+
+			doc = v[i].Value
+		case []any:
+			i, err := jsonArrayIndex(v, token, false)
+			if err != nil {
+				return nil, err
+			}
+
+			doc = v[i]
+		default:
+			return nil, fmt.Errorf("%q not found: parent is not an object or array", token)
+		}
+	}
+
+	return doc, nil
+}
+
+func jsonAdd(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return jsonUpdate(doc, path, func(parent any, token string) (any, error) {
+		switch v := parent.(type) {
+		case orderedObject:
+			if i := v.index(token); i >= 0 {
+				v[i].Value = value
+				return v, nil
+			}
+
+			return append(v, orderedMember{Key: token, Value: value}), nil
+		case []any:
+			i, err := jsonArrayIndex(v, token, true)
+			if err != nil {
+				return nil, err
+			}
+
+			return slices.Insert(v, i, value), nil
+		}
+
+		return nil, fmt.Errorf("cannot add %q: parent is not an object or array", token)
+	})
+}
+
+func jsonRemove(doc any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, nil, errors.New("cannot remove the whole document")
+	}
+
+	var removed any
+	doc, err := jsonUpdate(doc, path, func(parent any, token string) (any, error) {
+		switch v := parent.(type) {
+		case orderedObject:
+			i := v.index(token)
+			if i < 0 {
+				return nil, fmt.Errorf("%q not found", token)
+			}
+
+			removed = v[i].Value
+			return slices.Delete(v, i, i+1), nil
+		case []any:
+			i, err := jsonArrayIndex(v, token, false)
+			if err != nil {
+				return nil, err
+			}
+
+			removed = v[i]
+			return slices.Delete(v, i, i+1), nil
+		}
+
+		return nil, fmt.Errorf("%q not found: parent is not an object or array", token)
+	})
+
+	return doc, removed, err
+}
+
+func jsonReplace(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return jsonUpdate(doc, path, func(parent any, token string) (any, error) {
+		switch v := parent.(type) {
+		case orderedObject:
+			i := v.index(token)
+			if i < 0 {
+				return nil, fmt.Errorf("%q not found", token)
+			}
+
+			v[i].Value = value
+			return v, nil
+		case []any:
+			i, err := jsonArrayIndex(v, token, false)
+			if err != nil {
+				return nil, err
+			}
+
+			v[i] = value
+			return v, nil
+		}
+
+		return nil, fmt.Errorf("%q not found: parent is not an object or array", token)
+	})
+}
+
+// jsonUpdate calls fn with the parent of the given path and its last token,
+// and sets the returned value as the new parent.
+func jsonUpdate(doc any, path []string, fn func(parent any, token string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+
+	switch v := doc.(type) {
+	case orderedObject:
+		i := v.index(path[0])
+		if i < 0 {
+			return nil, fmt.Errorf("%q not found", path[0])
+		}
+
+		child, err := jsonUpdate(v[i].Value, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+
+		v[i].Value = child
+		return v, nil
+	case []any:
+		i, err := jsonArrayIndex(v, path[0], false)
+		if err != nil {
+			return nil, err
+		}
+
+		child, err := jsonUpdate(v[i], path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+
+		v[i] = child
+		return v, nil
+	}
+
+	return nil, fmt.Errorf("%q not found: parent is not an object or array", path[0])
+}
+
+// jsonArrayIndex resolves a reference token to an index of the list.
+// Tokens are either indexes, the ID of a plugin,
+// or "-" to refer past the last item when appending.
+func jsonArrayIndex(list []any, token string, appending bool) (int, error) {
+	if token == "-" {
+		if !appending {
+			return 0, errors.New(`"-" can only be used to append`)
+		}
+
+		return len(list), nil
+	}
+
+	if isJSONIndex(token) {
+		i, err := strconv.Atoi(token)
+		if err != nil {
+			return 0, fmt.Errorf("invalid index %q", token)
+		}
+
+		last := len(list) - 1
+		if appending {
+			last = len(list)
+		}
+		if i > last {
+			return 0, fmt.Errorf("index %d out of range [0, %d]", i, last)
+		}
+
+		return i, nil
+	}
+
+	for i, item := range list {
+		obj, ok := item.(orderedObject)
+		if !ok {
+			continue
+		}
+
+		props := obj.properties()
+		if name := Name(props); name != "" && pluginID(name, props, i) == token {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no item with ID %q", token)
+}
+
+// isJSONIndex reports whether the token is an array index
+// as of RFC 6901: digits without leading zeros.
+func isJSONIndex(token string) bool {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return false
+	}
+
+	for _, r := range token {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseJSONPointer returns the unescaped reference tokens
+// of a RFC 6901 JSON pointer.
+func parseJSONPointer(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(s, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q: must start with /", s)
+	}
+
+	tokens := strings.Split(s[1:], "/")
+	for i, token := range tokens {
+		token = strings.ReplaceAll(token, "~1", "/")
+		tokens[i] = strings.ReplaceAll(token, "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+func isPathPrefix(prefix, path []string) bool {
+	return len(prefix) <= len(path) && slices.Equal(prefix, path[:len(prefix)])
+}
+
+// orderedObject is a JSON object that keeps the order of its members.
+type orderedObject []orderedMember
+
+type orderedMember struct {
+	Key   string
+	Value any
+}
+
+// index of the member with the given key,
+// or of the first one matching case-insensitively.
+func (obj orderedObject) index(key string) int {
+	if i := slices.IndexFunc(obj, func(m orderedMember) bool { return m.Key == key }); i >= 0 {
+		return i
+	}
+
+	return slices.IndexFunc(obj, func(m orderedMember) bool { return strings.EqualFold(m.Key, key) })
+}
+
+// properties with the scalar members of the object,
+// enough to get the name and alias of a plugin.
+func (obj orderedObject) properties() property.Properties {
+	var out property.Properties
+	for _, m := range obj {
+		switch v := m.Value.(type) {
+		case string:
+			out = append(out, property.Property{Key: m.Key, Value: v})
+		case json.Number:
+			out = append(out, property.Property{Key: m.Key, Value: v.String()})
+		}
+	}
+	return out
+}
+
+// decodeOrderedJSON decodes a JSON value where objects are orderedObject
+// and numbers are json.Number.
+func decodeOrderedJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	v, err := decodeOrderedValue(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := dec.Token(); err == nil {
+		return nil, errors.New("invalid JSON: unexpected data after value")
+	}
+
+	return v, nil
+}
+
+func decodeOrderedValue(dec *json.Decoder) (any, error) {
+	t, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t {
+	case json.Delim('{'):
+		obj := orderedObject{}
+		for dec.More() {
+			t, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			key, _ := t.(string)
+			v, err := decodeOrderedValue(dec)
+			if err != nil {
+				return nil, err
+			}
+
+			obj = append(obj, orderedMember{Key: key, Value: v})
+		}
+
+		_, err = dec.Token()
+		return obj, err
+	case json.Delim('['):
+		list := []any{}
+		for dec.More() {
+			v, err := decodeOrderedValue(dec)
+			if err != nil {
+				return nil, err
+			}
+
+			list = append(list, v)
+		}
+
+		_, err = dec.Token()
+		return list, err
+	}
+
+	return t, nil
+}
+
+func encodeOrderedJSON(buf *bytes.Buffer, v any) error {
+	switch v := v.(type) {
+	case orderedObject:
+		buf.WriteByte('{')
+		for i, m := range v {
+			if i != 0 {
+				buf.WriteByte(',')
+			}
+
+			key, err := json.Marshal(m.Key)
+			if err != nil {
+				return err
+			}
+
+			buf.Write(key)
+			buf.WriteByte(':')
+			if err := encodeOrderedJSON(buf, m.Value); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	case []any:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i != 0 {
+				buf.WriteByte(',')
+			}
+
+			if err := encodeOrderedJSON(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	buf.Write(b)
+	return nil
+}
+
+func cloneOrderedJSON(v any) any {
+	switch v := v.(type) {
+	case orderedObject:
+		out := make(orderedObject, len(v))
+		for i, m := range v {
+			out[i] = orderedMember{Key: m.Key, Value: cloneOrderedJSON(m.Value)}
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = cloneOrderedJSON(item)
+		}
+		return out
+	}
+
+	return v
+}
+
+// equalOrderedJSON compares JSON values as of RFC 6902 test operation:
+// objects regardless of the order of their members
+// and numbers by their numeric value.
+func equalOrderedJSON(a, b any) bool {
+	switch a := a.(type) {
+	case orderedObject:
+		b, ok := b.(orderedObject)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+
+		for _, m := range a {
+			i := slices.IndexFunc(b, func(o orderedMember) bool { return o.Key == m.Key })
+			if i < 0 || !equalOrderedJSON(m.Value, b[i].Value) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		b, ok := b.([]any)
+		return ok && slices.EqualFunc(a, b, equalOrderedJSON)
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+
+		x, errA := a.Float64()
+		y, errB := b.Float64()
+		return errA == nil && errB == nil && x == y
+	}
+
+	return a == b
+}
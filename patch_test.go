@@ -0,0 +1,242 @@
+package fluentbitconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_ApplyPatch(t *testing.T) {
+	parse := func(t *testing.T) Config {
+		t.Helper()
+
+		cfg, err := ParseAs(configLiteral(`
+			service:
+				flush: 1
+				log_level: info
+			pipeline:
+				inputs:
+					- name: tail
+					  alias: app_logs
+					  path: /var/log/app.log
+					  refresh_interval: 10
+					- name: cpu
+				outputs:
+					- name: stdout
+					  match: '*'
+					  processors:
+						logs:
+							- name: content_modifier
+							  action: insert
+		`), FormatYAML)
+		require.NoError(t, err)
+		return cfg
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		cfg := parse(t)
+		err := cfg.ApplyPatch([]byte(`[
+			{"op": "test", "path": "/pipeline/inputs/app_logs/path", "value": "/var/log/app.log"},
+			{"op": "replace", "path": "/pipeline/inputs/app_logs/path", "value": "/var/log/prod/app.log"},
+			{"op": "add", "path": "/pipeline/inputs/app_logs/Path_Key", "value": "filename"},
+			{"op": "remove", "path": "/pipeline/inputs/cpu.1"},
+			{"op": "add", "path": "/pipeline/inputs/-", "value": {"name": "mem"}},
+			{"op": "replace", "path": "/service/Log_Level", "value": "debug"},
+			{"op": "add", "path": "/pipeline/outputs/stdout.0/processors/logs/0", "value": {"name": "sql", "query": "SELECT * FROM STREAM"}},
+			{"op": "copy", "from": "/pipeline/outputs/0/match", "path": "/pipeline/outputs/0/match_regex"},
+			{"op": "move", "from": "/pipeline/outputs/0/match_regex", "path": "/pipeline/outputs/0/alias"}
+		]`))
+		require.NoError(t, err)
+
+		require.Equal(t, configLiteral(`
+			service:
+			    flush: 1
+			    log_level: debug
+			pipeline:
+			    inputs:
+			        - name: tail
+			          alias: app_logs
+			          path: /var/log/prod/app.log
+			          refresh_interval: 10
+			          Path_Key: filename
+			        - name: mem
+			    outputs:
+			        - name: stdout
+			          match: '*'
+			          alias: '*'
+			          processors:
+			            logs:
+			                - name: sql
+			                  query: SELECT * FROM STREAM
+			                - name: content_modifier
+			                  action: insert
+		`), mustDumpYAML(t, cfg))
+
+		require.Equal(t, []string{
+			"input:tail:app_logs",
+			"input:mem:mem.1",
+			"output:stdout:*",
+			"output:stdout:*/logs:sql:sql.0",
+			"output:stdout:*/logs:content_modifier:content_modifier.1",
+		}, cfg.IDs(true))
+
+		refresh := cfg.Pipeline.Inputs[0].Properties[3]
+		require.Equal(t, 10, refresh.Value, "unchanged values keep their type")
+		require.Equal(t, 9, refresh.Pos.Line, "unchanged properties keep their position")
+		require.Equal(t, 6, cfg.Pipeline.Inputs[0].Pos.Line)
+	})
+
+	t.Run("numbers", func(t *testing.T) {
+		cfg := parse(t)
+		err := cfg.ApplyPatch([]byte(`[
+			{"op": "replace", "path": "/pipeline/inputs/app_logs/refresh_interval", "value": 5},
+			{"op": "add", "path": "/pipeline/inputs/cpu.1/Interval_Sec", "value": 5}
+		]`))
+		require.NoError(t, err)
+		require.NoError(t, cfg.Validate())
+
+		v, _ := cfg.Pipeline.Inputs[1].Properties.Get("interval_sec")
+		require.Equal(t, int64(5), v)
+
+		err = cfg.ApplyPatch([]byte(`[{"op": "replace", "path": "/pipeline/inputs/cpu.1/Interval_Sec", "value": 1.5}]`))
+		require.NoError(t, err)
+		require.EqualError(t, cfg.Validate(), `10:11: input: cpu: expected "Interval_Sec" to be a valid integer, got 1.5`)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			patch string
+			want  string
+		}{
+			{
+				name:  "not_found",
+				patch: `[{"op": "replace", "path": "/pipeline/inputs/tail.0/path", "value": "x"}]`,
+				want:  `operation 0: replace /pipeline/inputs/tail.0/path: no item with ID "tail.0"`,
+			},
+			{
+				name:  "missing_key",
+				patch: `[{"op": "remove", "path": "/pipeline/inputs/cpu.1/interval_sec"}]`,
+				want:  `operation 0: remove /pipeline/inputs/cpu.1/interval_sec: "interval_sec" not found`,
+			},
+			{
+				name:  "out_of_range",
+				patch: `[{"op": "add", "path": "/pipeline/inputs/3", "value": {"name": "mem"}}]`,
+				want:  `operation 0: add /pipeline/inputs/3: index 3 out of range [0, 2]`,
+			},
+			{
+				name:  "test_failed",
+				patch: `[{"op": "remove", "path": "/pipeline/inputs/cpu.1"}, {"op": "test", "path": "/service/flush", "value": 5}]`,
+				want:  `operation 1: test /service/flush: test failed: value differs`,
+			},
+			{
+				name:  "missing_value",
+				patch: `[{"op": "add", "path": "/service/grace"}]`,
+				want:  `operation 0: add /service/grace: missing value`,
+			},
+			{
+				name:  "unknown_op",
+				patch: `[{"op": "upsert", "path": "/service/grace", "value": 5}]`,
+				want:  `operation 0: upsert /service/grace: unknown operation "upsert"`,
+			},
+			{
+				name:  "move_into_itself",
+				patch: `[{"op": "move", "from": "/pipeline", "path": "/pipeline/inputs"}]`,
+				want:  `operation 0: move /pipeline/inputs: cannot move /pipeline into itself`,
+			},
+			{
+				name:  "missing_name",
+				patch: `[{"op": "remove", "path": "/pipeline/inputs/app_logs/name"}]`,
+				want:  `invalid patched config: input 0: missing name property`,
+			},
+		}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				cfg := parse(t)
+				err := cfg.ApplyPatch([]byte(tc.patch))
+				require.EqualError(t, err, tc.want)
+				require.True(t, parse(t).Equal(cfg), "config not modified")
+			})
+		}
+	})
+}
+
+func TestConfig_ApplyMergePatch(t *testing.T) {
+	cfg, err := ParseAs(configLiteral(`
+		[SERVICE]
+			Flush     1
+			Log_Level info
+
+		[INPUT]
+			Name  tail
+			Alias app_logs
+			Path  /var/log/app.log
+
+		[INPUT]
+			Name cpu
+
+		[OUTPUT]
+			Name  stdout
+			Match *
+	`), FormatClassic)
+	require.NoError(t, err)
+
+	original := cfg.clone()
+
+	err = cfg.ApplyMergePatch([]byte(`{
+		"service": {"log_level": "debug", "grace": 5, "flush": null},
+		"pipeline": {
+			"inputs": {"app_logs": {"path": "/var/log/prod/app.log"}, "cpu.1": null},
+			"outputs": [{"name": "forward", "match": "*"}]
+		}
+	}`))
+	require.NoError(t, err)
+
+	require.Equal(t, configLiteral(`
+		[SERVICE]
+		    Log_Level debug
+		    grace     5
+		[INPUT]
+		    Name  tail
+		    Alias app_logs
+		    Path  /var/log/prod/app.log
+		[OUTPUT]
+		    name  forward
+		    match *
+	`), mustDumpClassic(t, cfg))
+	require.Equal(t, 7, cfg.Pipeline.Inputs[0].Properties[1].Pos.Line, "unchanged properties keep their position")
+
+	t.Run("numbers", func(t *testing.T) {
+		cfg := original.clone()
+		err := cfg.ApplyMergePatch([]byte(`{"pipeline": {"inputs": {"cpu.1": {"interval_sec": 5}}}}`))
+		require.NoError(t, err)
+		require.NoError(t, cfg.Validate())
+
+		v, _ := cfg.Pipeline.Inputs[1].Properties.Get("interval_sec")
+		require.Equal(t, int64(5), v)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		cfg := original.clone()
+		err := cfg.ApplyMergePatch([]byte(`{"pipeline": {"inputs": {"cpu.1": null, "mem.0": {"interval_sec": 5}}}}`))
+		require.EqualError(t, err, `/pipeline/inputs/mem.0: no item with ID "mem.0"`)
+		require.True(t, original.Equal(cfg), "config not modified")
+
+		err = cfg.ApplyMergePatch([]byte(`{"pipeline": {"inputs": [{"path": "/var/log/app.log"}]}}`))
+		require.EqualError(t, err, `invalid patched config: input 0: missing name property`)
+
+		err = cfg.ApplyMergePatch([]byte(`{"pipelines": {}}`))
+		require.EqualError(t, err, `invalid patched config: json: unknown field "pipelines"`)
+
+		err = cfg.ApplyMergePatch([]byte(`{`))
+		require.EqualError(t, err, `invalid merge patch: unexpected end of JSON input`)
+	})
+}
+
+func mustDumpClassic(t *testing.T, cfg Config) string {
+	t.Helper()
+
+	s, err := cfg.DumpAsClassic()
+	require.NoError(t, err)
+	return s
+}
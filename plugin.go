@@ -3,6 +3,7 @@ package fluentbitconfig
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"golang.org/x/exp/slices"
 	"gopkg.in/yaml.v3"
@@ -43,7 +44,7 @@ func (plugins *Plugins) UnmarshalJSON(data []byte) error {
 	*plugins = dest
 
 	for i, plugin := range *plugins {
-		plugin.ID = fmt.Sprintf("%s.%d", plugin.Name, i)
+		plugin.ID = pluginID(plugin.Name, plugin.Properties, i)
 		(*plugins)[i] = plugin
 	}
 
@@ -60,17 +61,73 @@ func (plugins *Plugins) UnmarshalYAML(node *yaml.Node) error {
 	*plugins = dest
 
 	for i, plugin := range *plugins {
-		plugin.ID = fmt.Sprintf("%s.%d", plugin.Name, i)
+		plugin.ID = pluginID(plugin.Name, plugin.Properties, i)
 		(*plugins)[i] = plugin
 	}
 
 	return nil
 }
 
+// IDKey is the plugin property that sets an explicit plugin ID.
+// Unlike the alias, it is not part of the Fluent Bit config:
+// it is kept when marshaling the config,
+// but left out by DumpAs and the other dump methods.
+const IDKey = "$id"
+
+// pluginID returns the ID of the plugin at the given index.
+// The IDKey and `alias` properties take precedence, in that order,
+// so IDs remain stable when other plugins are added or removed.
+// Otherwise the positional ID is used.
+// For example: tail.0
+func pluginID(name string, props property.Properties, index int) string {
+	if id := ExplicitID(props); id != "" {
+		return id
+	}
+
+	if alias := Alias(props); alias != "" {
+		return alias
+	}
+
+	return fmt.Sprintf("%s.%d", name, index)
+}
+
+// ExplicitID from properties, set with the IDKey property.
+func ExplicitID(props property.Properties) string {
+	v, ok := props.Get(IDKey)
+	if !ok {
+		return ""
+	}
+
+	return strings.TrimSpace(strings.ToValidUTF8(stringFromAny(v), ""))
+}
+
+// Alias from properties.
+// Unlike Name, it is case sensitive.
+func Alias(props property.Properties) string {
+	v, ok := props.Get("alias")
+	if !ok {
+		return ""
+	}
+
+	return strings.TrimSpace(strings.ToValidUTF8(stringFromAny(v), ""))
+}
+
+// Plugin section.
+// Its ID is either the explicit ID of the plugin, its alias or its position.
+// See Config.IDs and IDKey.
 type Plugin struct {
-	ID         string              `json:"-" yaml:"-"`
-	Name       string              `json:"-" yaml:"-"`
+	// ID is assigned when the plugin is decoded or added,
+	// and re-assigned when its list is modified.
+	// Set the IDKey or `alias` property to keep it.
+	ID   string `json:"-" yaml:"-"`
+	Name string `json:"-" yaml:"-"`
+	// Pos where the plugin section starts when parsed.
+	// It is ignored by Plugin.Equal.
+	Pos        property.Position   `json:"-" yaml:"-"`
 	Properties property.Properties `json:",inline" yaml:",inline"`
+	// Processors are written as the `processors` property
+	// and are not part of Properties.
+	Processors Processors `json:"-" yaml:"-"`
 }
 
 func (p Plugin) MarshalJSON() ([]byte, error) {
@@ -97,6 +154,19 @@ func (p *Plugin) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
+	if i := processorsIndex(p.Properties); i >= 0 {
+		var m map[string]json.RawMessage
+		if err := json.Unmarshal(data, &m); err != nil {
+			return err
+		}
+
+		if err := json.Unmarshal(m[p.Properties[i].Key], &p.Processors); err != nil {
+			return err
+		}
+
+		p.Properties = withoutProperty(p.Properties, i)
+	}
+
 	p.Name = Name(p.Properties)
 	return nil
 }
@@ -107,12 +177,43 @@ func (p *Plugin) UnmarshalYAML(node *yaml.Node) error {
 		return err
 	}
 
+	p.Pos = property.Position{Line: node.Line, Column: node.Column}
+
+	if i := processorsIndex(p.Properties); i >= 0 {
+		if err := node.Content[i*2+1].Decode(&p.Processors); err != nil {
+			return err
+		}
+
+		p.Properties = withoutProperty(p.Properties, i)
+	}
+
 	p.Name = Name(p.Properties)
 	return nil
 }
 
 func (p Plugin) Equal(target Plugin) bool {
-	return p.Properties.Equal(target.Properties)
+	return p.Properties.Equal(target.Properties) && p.Processors.Equal(target.Processors)
+}
+
+// clonePlugins returns a copy of the plugins whose properties
+// and processors can be modified without affecting the given ones.
+// Property values are not copied.
+func clonePlugins(plugins Plugins) Plugins {
+	if plugins == nil {
+		return nil
+	}
+
+	out := make(Plugins, len(plugins))
+	for i, plugin := range plugins {
+		plugin.Properties = slices.Clone(plugin.Properties)
+		plugin.Processors = Processors{
+			Logs:    clonePlugins(plugin.Processors.Logs),
+			Metrics: clonePlugins(plugin.Processors.Metrics),
+			Traces:  clonePlugins(plugin.Processors.Traces),
+		}
+		out[i] = plugin
+	}
+	return out
 }
 
 func (plugins Plugins) Equal(target Plugins) bool {
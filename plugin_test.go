@@ -62,6 +62,6 @@ func TestPlugins_FindByID(t *testing.T) {
 				{Key: "name", Value: "cpu"},
 				{Key: "proptest", Value: "valuetest"},
 			},
-		}, plugin)
+		}, withoutPositions(plugin))
 	})
 }
@@ -0,0 +1,148 @@
+package fluentbitconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+// setJSONPositions sets the positions of plugins and properties
+// from the JSON document the config was decoded from.
+// The JSON decoder only hands slices of the document to the
+// unmarshalers, so positions are set afterwards.
+func (c *Config) setJSONPositions(data []byte) {
+	positions := jsonPositions(data)
+
+	setProps := func(props property.Properties, ptr string) {
+		for i, p := range props {
+			if pos, ok := positions[ptr+"/"+escapeJSONPointer(p.Key)]; ok {
+				props[i].Pos = pos
+			}
+		}
+	}
+
+	var setPlugins func(plugins Plugins, ptr string)
+	setPlugins = func(plugins Plugins, ptr string) {
+		for i := range plugins {
+			itemPtr := ptr + "/" + strconv.Itoa(i)
+			plugins[i].Pos = positions[itemPtr]
+			setProps(plugins[i].Properties, itemPtr)
+
+			for _, signal := range processorSignals {
+				processors, _ := plugins[i].Processors.Signal(signal)
+				setPlugins(*processors, itemPtr+"/"+processorsKey+"/"+signal)
+			}
+		}
+	}
+
+	setProps(c.Env, "/env")
+	setProps(c.Service, "/service")
+	setPlugins(c.Customs, "/customs")
+	setPlugins(c.Pipeline.Inputs, "/pipeline/inputs")
+	setPlugins(c.Pipeline.Filters, "/pipeline/filters")
+	setPlugins(c.Pipeline.Outputs, "/pipeline/outputs")
+	setPlugins(c.Parsers, "/parsers")
+
+	for i := range c.MultilineParsers {
+		itemPtr := "/multiline_parsers/" + strconv.Itoa(i)
+		c.MultilineParsers[i].Pos = positions[itemPtr]
+		setProps(c.MultilineParsers[i].Properties, itemPtr)
+	}
+
+	for i := range c.Upstreams {
+		itemPtr := "/upstream_servers/" + strconv.Itoa(i)
+		c.Upstreams[i].Pos = positions[itemPtr]
+		setPlugins(c.Upstreams[i].Nodes, itemPtr+"/nodes")
+	}
+
+	for i := range c.StreamTasks {
+		c.StreamTasks[i].Pos = positions["/stream_processor/"+strconv.Itoa(i)]
+	}
+}
+
+// jsonPositions returns the position of each object key and array item
+// of the given JSON document, indexed by their JSON pointer.
+// When an object has duplicated keys, the first one is kept.
+func jsonPositions(data []byte) map[string]property.Position {
+	out := map[string]property.Position{}
+
+	var lineStarts []int
+	lineStarts = append(lineStarts, 0)
+	for i, b := range data {
+		if b == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	// nextPos returns the position of the next token.
+	nextPos := func() property.Position {
+		offset := int(dec.InputOffset())
+		for offset < len(data) && strings.IndexByte(" \t\r\n,:", data[offset]) >= 0 {
+			offset++
+		}
+
+		line := sort.Search(len(lineStarts), func(i int) bool {
+			return lineStarts[i] > offset
+		})
+		return property.Position{Line: line, Column: offset - lineStarts[line-1] + 1}
+	}
+
+	var walk func(ptr string) error
+	walk = func(ptr string) error {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		switch t {
+		case json.Delim('{'):
+			for dec.More() {
+				pos := nextPos()
+				t, err := dec.Token()
+				if err != nil {
+					return err
+				}
+
+				key, _ := t.(string)
+				keyPtr := ptr + "/" + escapeJSONPointer(key)
+				if _, ok := out[keyPtr]; !ok {
+					out[keyPtr] = pos
+				}
+
+				if err := walk(keyPtr); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+		case json.Delim('['):
+			for i := 0; dec.More(); i++ {
+				itemPtr := ptr + "/" + strconv.Itoa(i)
+				out[itemPtr] = nextPos()
+				if err := walk(itemPtr); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+		}
+
+		return err
+	}
+
+	// Positions are best effort,
+	// syntax errors are already reported by the decoder.
+	_ = walk("")
+
+	return out
+}
+
+// escapeJSONPointer escapes a JSON pointer reference token as of RFC 6901.
+func escapeJSONPointer(s string) string {
+	s = strings.ReplaceAll(s, "~", "~0")
+	return strings.ReplaceAll(s, "/", "~1")
+}
@@ -0,0 +1,162 @@
+package fluentbitconfig
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+func TestConfig_positions(t *testing.T) {
+	tt := []struct {
+		name   string
+		format Format
+		text   string
+	}{
+		{
+			name:   "classic",
+			format: FormatClassic,
+			text: configLiteral(`
+				[SERVICE]
+					log_level info
+				[INPUT]
+					name dummy
+					rate 5
+			`),
+		},
+		{
+			name:   "yaml",
+			format: FormatYAML,
+			text: configLiteral(`
+				service:
+					log_level: info
+				pipeline:
+					inputs:
+						- name: dummy
+						  rate: 5
+			`),
+		},
+		{
+			name:   "json",
+			format: FormatJSON,
+			text: configLiteral(`
+				{
+					"service": {"log_level": "info"},
+					"pipeline": {"inputs": [
+						{"name": "dummy", "rate": 5}
+					]}
+				}
+			`),
+		},
+	}
+
+	want := map[string]map[string]property.Position{
+		"classic": {
+			"log_level": {Line: 2, Column: 5},
+			"plugin":    {Line: 3, Column: 1},
+			"name":      {Line: 4, Column: 5},
+			"rate":      {Line: 5, Column: 5},
+		},
+		"yaml": {
+			"log_level": {Line: 2, Column: 5},
+			"plugin":    {Line: 5, Column: 11},
+			"name":      {Line: 5, Column: 11},
+			"rate":      {Line: 6, Column: 11},
+		},
+		"json": {
+			"log_level": {Line: 2, Column: 17},
+			"plugin":    {Line: 4, Column: 9},
+			"name":      {Line: 4, Column: 10},
+			"rate":      {Line: 4, Column: 27},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := ParseAs(tc.text, tc.format)
+			require.NoError(t, err)
+
+			input := cfg.Pipeline.Inputs[0]
+			require.Equal(t, want[tc.name], map[string]property.Position{
+				"log_level": cfg.Service[0].Pos,
+				"plugin":    input.Pos,
+				"name":      input.Properties[0].Pos,
+				"rate":      input.Properties[1].Pos,
+			})
+		})
+	}
+}
+
+func TestConfig_ValidateWithSchema_position(t *testing.T) {
+	cfg, err := ParseAs(configLiteral(`
+		[INPUT]
+			name dummy
+			nope 1
+	`), FormatClassic)
+	require.NoError(t, err)
+
+	err = cfg.ValidateWithSchema(DefaultSchema)
+	require.EqualError(t, err, `3:5: input: dummy: unknown property "nope"`)
+
+	var verr *ValidationError
+	require.True(t, errors.As(err, &verr))
+	require.Equal(t, property.Position{Line: 3, Column: 5}, verr.Pos)
+	require.Equal(t, "3:5", verr.Pos.String())
+}
+
+func TestValidationError_Error(t *testing.T) {
+	err := &ValidationError{Pos: property.Position{File: "fluent-bit.conf", Line: 3, Column: 5}, Err: errors.New("input: dummy: unknown property \"nope\"")}
+	require.EqualError(t, err, `fluent-bit.conf:3:5: input: dummy: unknown property "nope"`)
+
+	err.Pos = property.Position{}
+	require.EqualError(t, err, `input: dummy: unknown property "nope"`)
+}
+
+// withoutPositions returns a copy of v with every position cleared,
+// so parsed values can be compared with literals.
+func withoutPositions[T any](v T) T {
+	return clearPositions(reflect.ValueOf(v)).Interface().(T)
+}
+
+func clearPositions(v reflect.Value) reflect.Value {
+	if v.Type() == reflect.TypeOf(property.Position{}) {
+		return reflect.Zero(v.Type())
+	}
+
+	out := reflect.New(v.Type()).Elem()
+	switch v.Kind() {
+	case reflect.Struct:
+		out.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if out.Field(i).CanSet() {
+				out.Field(i).Set(clearPositions(v.Field(i)))
+			}
+		}
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		out.Set(reflect.New(v.Type().Elem()))
+		out.Elem().Set(clearPositions(v.Elem()))
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		out.Set(reflect.MakeSlice(v.Type(), v.Len(), v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(clearPositions(v.Index(i)))
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		out.Set(clearPositions(v.Elem()))
+	default:
+		return v
+	}
+
+	return out
+}
@@ -0,0 +1,131 @@
+package fluentbitconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+// Processors attached to an input or output plugin,
+// grouped by the signal they process.
+// Each group gets its own positional IDs like plugins do.
+type Processors struct {
+	Logs    Plugins `json:"logs,omitempty" yaml:"logs,omitempty"`
+	Metrics Plugins `json:"metrics,omitempty" yaml:"metrics,omitempty"`
+	Traces  Plugins `json:"traces,omitempty" yaml:"traces,omitempty"`
+}
+
+// processorsKey is the plugin property processors are defined on.
+const processorsKey = "processors"
+
+// processorSignals in the order they are written.
+var processorSignals = []string{"logs", "metrics", "traces"}
+
+// IsEmpty tells whether there are no processors for any signal.
+func (p Processors) IsEmpty() bool {
+	return len(p.Logs) == 0 && len(p.Metrics) == 0 && len(p.Traces) == 0
+}
+
+// Signal returns the processors of the given signal: logs, metrics or traces.
+func (p *Processors) Signal(signal string) (*Plugins, bool) {
+	switch strings.ToLower(signal) {
+	case "logs":
+		return &p.Logs, true
+	case "metrics":
+		return &p.Metrics, true
+	case "traces":
+		return &p.Traces, true
+	}
+
+	return nil, false
+}
+
+func (p Processors) Equal(target Processors) bool {
+	return p.Logs.Equal(target.Logs) &&
+		p.Metrics.Equal(target.Metrics) &&
+		p.Traces.Equal(target.Traces)
+}
+
+func (p *Processors) UnmarshalJSON(data []byte) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return fmt.Errorf("not a list of processors")
+	}
+
+	var out Processors
+	for key, raw := range m {
+		plugins, ok := out.Signal(key)
+		if !ok {
+			return fmt.Errorf("unknown processor type: %s", key)
+		}
+
+		if err := json.Unmarshal(raw, plugins); err != nil {
+			return fmt.Errorf("%s: not a list of processors", key)
+		}
+	}
+
+	*p = out
+	return nil
+}
+
+func (p *Processors) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: not a list of processors", node.Line)
+	}
+
+	var out Processors
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		plugins, ok := out.Signal(keyNode.Value)
+		if !ok {
+			return fmt.Errorf("line %d: unknown processor type: %s", keyNode.Line, keyNode.Value)
+		}
+
+		if valueNode.Kind != yaml.SequenceNode {
+			return fmt.Errorf("line %d: %s: not a list of processors", valueNode.Line, keyNode.Value)
+		}
+
+		if err := valueNode.Decode(plugins); err != nil {
+			return err
+		}
+	}
+
+	*p = out
+	return nil
+}
+
+// processorsFromValue converts a raw `processors` property value
+// into Processors.
+func processorsFromValue(v any) (Processors, error) {
+	var out Processors
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return out, fmt.Errorf("not a list of processors")
+	}
+
+	return out, json.Unmarshal(b, &out)
+}
+
+// processorsIndex returns the index of the `processors` property
+// or -1 if not found.
+func processorsIndex(props property.Properties) int {
+	for i, p := range props {
+		if strings.EqualFold(p.Key, processorsKey) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// withoutProperty returns a copy of the properties without the one at index i.
+func withoutProperty(props property.Properties, i int) property.Properties {
+	out := make(property.Properties, 0, len(props)-1)
+	out = append(out, props[:i]...)
+	return append(out, props[i+1:]...)
+}
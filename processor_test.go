@@ -0,0 +1,143 @@
+package fluentbitconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+func TestPlugin_Processors(t *testing.T) {
+	const yamlText = `
+		pipeline:
+			inputs:
+				- name: dummy
+				  processors:
+					logs:
+						- name: content_modifier
+						  action: insert
+						- name: content_modifier
+						  action: delete
+					traces:
+						- name: sampling
+				  tag: test
+	`
+
+	cfg, err := ParseAs(configLiteral(yamlText), FormatYAML)
+	require.NoError(t, err)
+
+	input := cfg.Pipeline.Inputs[0]
+	require.Equal(t, property.Properties{
+		{Key: "name", Value: "dummy", Pos: property.Position{Line: 3, Column: 11}},
+		{Key: "tag", Value: "test", Pos: property.Position{Line: 12, Column: 11}},
+	}, input.Properties, "processors are not part of the properties")
+	require.Equal(t, []string{"content_modifier.0", "content_modifier.1"}, input.Processors.Logs.IDs())
+	require.Empty(t, input.Processors.Metrics)
+	require.Equal(t, []string{"sampling.0"}, input.Processors.Traces.IDs())
+	require.Equal(t, property.Position{Line: 6, Column: 19}, input.Processors.Logs[0].Pos)
+
+	t.Run("ids", func(t *testing.T) {
+		require.Equal(t, []string{
+			"input:dummy:dummy.0",
+			"input:dummy:dummy.0/logs:content_modifier:content_modifier.0",
+			"input:dummy:dummy.0/logs:content_modifier:content_modifier.1",
+			"input:dummy:dummy.0/traces:sampling:sampling.0",
+		}, cfg.IDs(true))
+		require.Equal(t, []string{
+			"dummy.0",
+			"dummy.0/logs/content_modifier.0",
+			"dummy.0/logs/content_modifier.1",
+			"dummy.0/traces/sampling.0",
+		}, cfg.IDs(false))
+
+		got, ok := cfg.FindByID("input:dummy:dummy.0/logs:content_modifier:content_modifier.1")
+		require.True(t, ok)
+		require.Equal(t, input.Processors.Logs[1], got)
+	})
+
+	t.Run("dump", func(t *testing.T) {
+		got, err := cfg.DumpAsYAML()
+		require.NoError(t, err)
+		require.Equal(t, configLiteral(`
+			pipeline:
+				inputs:
+					- name: dummy
+					  tag: test
+					  processors:
+						logs:
+							- name: content_modifier
+							  action: insert
+							- name: content_modifier
+							  action: delete
+						traces:
+							- name: sampling
+		`), got)
+
+		_, err = cfg.DumpAsClassic()
+		require.EqualError(t, err, `plugin "dummy.0": processors are not supported in classic format`)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		got, err := ParseAs(configLiteral(`
+			{"pipeline": {"inputs": [{
+				"name": "dummy",
+				"processors": {"logs": [{"name": "content_modifier", "action": "insert"}]}
+			}]}}
+		`), FormatJSON)
+		require.NoError(t, err)
+		require.Equal(t, []string{"input:dummy:dummy.0", "input:dummy:dummy.0/logs:content_modifier:content_modifier.0"}, got.IDs(true))
+		require.Equal(t, property.Position{Line: 3, Column: 29}, got.Pipeline.Inputs[0].Processors.Logs[0].Pos)
+		require.Equal(t, property.Position{Line: 3, Column: 58}, got.Pipeline.Inputs[0].Processors.Logs[0].Properties[1].Pos)
+	})
+
+	t.Run("unknown_signal", func(t *testing.T) {
+		_, err := ParseAs(configLiteral(`
+			pipeline:
+				inputs:
+					- name: dummy
+					  processors:
+						profiles: []
+		`), FormatYAML)
+		require.EqualError(t, err, "line 5: unknown processor type: profiles")
+
+		_, err = ParseAs(`{"pipeline": {"inputs": [{"name": "dummy", "processors": {"logs": {}}}]}}`, FormatJSON)
+		require.EqualError(t, err, "logs: not a list of processors")
+	})
+}
+
+func TestConfig_Validate_processors(t *testing.T) {
+	cfg, err := ParseAs(configLiteral(`
+		pipeline:
+			inputs:
+				- name: dummy
+				  processors:
+					logs:
+						- name: nope
+	`), FormatYAML)
+	require.NoError(t, err)
+
+	err = cfg.Validate()
+	require.EqualError(t, err, `6:19: processor: unknown plugin "nope"`)
+
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	require.Equal(t, property.Position{Line: 6, Column: 19}, verr.Pos)
+}
+
+func TestConfig_Expand_processors(t *testing.T) {
+	cfg := Config{}
+	cfg.AddSection(SectionKindInput, property.Properties{{Key: "name", Value: "dummy"}})
+	cfg.Pipeline.Inputs[0].Processors.Logs = Plugins{{
+		ID:         "content_modifier.0",
+		Name:       "content_modifier",
+		Properties: property.Properties{{Key: "name", Value: "content_modifier"}, {Key: "value", Value: "${VALUE}"}},
+	}}
+
+	require.Equal(t, []string{"VALUE"}, cfg.Variables())
+
+	got, err := cfg.Expand(map[string]string{"VALUE": "ok"})
+	require.NoError(t, err)
+	require.Equal(t, "ok", got.Pipeline.Inputs[0].Processors.Logs[0].Properties[1].Value)
+	require.Equal(t, "${VALUE}", cfg.Pipeline.Inputs[0].Processors.Logs[0].Properties[1].Value, "original not modified")
+}
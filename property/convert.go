@@ -0,0 +1,280 @@
+package property
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned by the typed getters
+// when the property is not set.
+var ErrNotFound = errors.New("property not found")
+
+// ConversionError is returned by the typed getters
+// when the property value cannot be converted to the requested type.
+type ConversionError struct {
+	Key   string
+	Value any
+	// Type requested. For example: boolean
+	Type string
+}
+
+func (e *ConversionError) Error() string {
+	return fmt.Sprintf("property %q: cannot convert %v to %s", e.Key, e.Value, e.Type)
+}
+
+// GetBool property. See ParseBool.
+func (pp *Properties) GetBool(key string) (bool, error) {
+	return get(pp, key, "boolean", ParseBool)
+}
+
+// GetInt property. See ParseInt.
+func (pp *Properties) GetInt(key string) (int64, error) {
+	return get(pp, key, "integer", ParseInt)
+}
+
+// GetFloat property. See ParseFloat.
+func (pp *Properties) GetFloat(key string) (float64, error) {
+	return get(pp, key, "double", ParseFloat)
+}
+
+// GetDuration property. See ParseSeconds.
+func (pp *Properties) GetDuration(key string) (time.Duration, error) {
+	return get(pp, key, "time", func(v any) (time.Duration, bool) {
+		f, ok := ParseSeconds(v)
+		return time.Duration(f * float64(time.Second)), ok
+	})
+}
+
+// GetSize property in bytes. See ParseSize.
+func (pp *Properties) GetSize(key string) (int64, error) {
+	return get(pp, key, "size", ParseSize)
+}
+
+// GetStrings property. See ParseStrings.
+func (pp *Properties) GetStrings(key string) ([]string, error) {
+	return get(pp, key, "strings", ParseStrings)
+}
+
+func get[T any](pp *Properties, key, typ string, parse func(any) (T, bool)) (T, error) {
+	var zero T
+	v, ok := pp.Get(key)
+	if !ok {
+		return zero, fmt.Errorf("%q: %w", key, ErrNotFound)
+	}
+
+	out, ok := parse(v)
+	if !ok {
+		return zero, &ConversionError{Key: key, Value: v, Type: typ}
+	}
+
+	return out, nil
+}
+
+// ParseBool parses booleans the way Fluent Bit does,
+// accepting true, on and yes, or false, off and no.
+func ParseBool(v any) (bool, bool) {
+	switch v := v.(type) {
+	case bool:
+		return v, true
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "true", "on", "yes":
+			return true, true
+		case "false", "off", "no":
+			return false, true
+		}
+	}
+
+	return false, false
+}
+
+// ParseFloat parses any number, or a string holding one.
+func ParseFloat(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+
+	return 0, false
+}
+
+// ParseInt parses any number with no fractional part,
+// or a string holding one.
+func ParseInt(v any) (int64, bool) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint:
+		return int64(v), int64(v) >= 0
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint64:
+		return int64(v), int64(v) >= 0
+	case string:
+		i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		return i, err == nil
+	}
+
+	f, ok := ParseFloat(v)
+	if !ok || f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, false
+	}
+
+	return int64(f), true
+}
+
+// reSize matches sizes with an optional unit. For example: -45.32kB
+var reSize = regexp.MustCompile(`^(?:\+|-)?[0-9]+(?:\.?[0-9]*)\s?(?:(?:k|K)|(?:m|M)|(?:g|G))?(?:b|B)?$`)
+
+// ParseSize parses a size in bytes the way Fluent Bit does.
+// For example: 512, 32k, 5M or 1.5GB. Units are powers of 1024.
+func ParseSize(v any) (int64, bool) {
+	if i, ok := ParseInt(v); ok {
+		return i, true
+	}
+
+	if f, ok := ParseFloat(v); ok {
+		return int64(f), true
+	}
+
+	s, ok := v.(string)
+	if !ok || !reSize.MatchString(strings.TrimSpace(s)) {
+		return 0, false
+	}
+
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.TrimSuffix(s, "b")
+
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(s, "k"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(s, "m"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(s, "g"):
+		multiplier = 1 << 30
+	}
+
+	f, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimRight(s, "kmg")), 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return int64(f * float64(multiplier)), true
+}
+
+// ParseSeconds parses a time in seconds the way Fluent Bit does.
+// For example: 10, 1.5, 30s, 10m, 1h or 1d.
+func ParseSeconds(v any) (float64, bool) {
+	if f, ok := ParseFloat(v); ok {
+		return f, true
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return 0, false
+	}
+
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return 0, false
+	}
+
+	multiplier := 1.0
+	switch s[len(s)-1] {
+	case 's':
+		s = s[:len(s)-1]
+	case 'm':
+		s, multiplier = s[:len(s)-1], 60
+	case 'h':
+		s, multiplier = s[:len(s)-1], 60*60
+	case 'd':
+		s, multiplier = s[:len(s)-1], 24*60*60
+	}
+
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return f * multiplier, true
+}
+
+// ParseStrings parses a list of strings,
+// or a string with comma or space delimited items.
+// For example: "a, b" or "a b".
+func ParseStrings(v any) ([]string, bool) {
+	switch v := v.(type) {
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			out = append(out, s)
+		}
+		return out, true
+	case []string:
+		return v, true
+	case string:
+		split := strings.Fields
+		if strings.Contains(v, ",") {
+			split = func(s string) []string {
+				return strings.FieldsFunc(s, func(r rune) bool { return r == ',' })
+			}
+		}
+
+		var out []string
+		for _, item := range split(v) {
+			if item = strings.TrimSpace(item); item != "" {
+				out = append(out, item)
+			}
+		}
+		return out, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,139 @@
+package property
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProperties_typedGetters(t *testing.T) {
+	props := Properties{
+		{Key: "HTTP_Server", Value: "On"},
+		{Key: "http_port", Value: int64(2020)},
+		{Key: "flush", Value: "1.5"},
+		{Key: "rotate_wait", Value: "1m"},
+		{Key: "buffer_max_size", Value: "5M"},
+		{Key: "keys", Value: "a, b ,c"},
+		{Key: "fields", Value: []any{"x", "y"}},
+		{Key: "invalid", Value: "nope"},
+	}
+
+	b, err := props.GetBool("http_server")
+	require.NoError(t, err)
+	require.True(t, b)
+
+	i, err := props.GetInt("http_port")
+	require.NoError(t, err)
+	require.Equal(t, int64(2020), i)
+
+	f, err := props.GetFloat("flush")
+	require.NoError(t, err)
+	require.Equal(t, 1.5, f)
+
+	d, err := props.GetDuration("rotate_wait")
+	require.NoError(t, err)
+	require.Equal(t, time.Minute, d)
+
+	size, err := props.GetSize("buffer_max_size")
+	require.NoError(t, err)
+	require.Equal(t, int64(5<<20), size)
+
+	strs, err := props.GetStrings("keys")
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b", "c"}, strs)
+
+	strs, err = props.GetStrings("fields")
+	require.NoError(t, err)
+	require.Equal(t, []string{"x", "y"}, strs)
+
+	_, err = props.GetBool("missing")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	_, err = props.GetInt("invalid")
+	require.EqualError(t, err, `property "invalid": cannot convert nope to integer`)
+
+	var convErr *ConversionError
+	require.True(t, errors.As(err, &convErr))
+	require.Equal(t, &ConversionError{Key: "invalid", Value: "nope", Type: "integer"}, convErr)
+}
+
+func TestParseBool(t *testing.T) {
+	tests := []struct {
+		in     any
+		want   bool
+		wantOk bool
+	}{
+		{true, true, true},
+		{"on", true, true},
+		{"Yes", true, true},
+		{"TRUE", true, true},
+		{"off", false, true},
+		{"no", false, true},
+		{"1", false, false},
+		{int64(1), false, false},
+	}
+	for _, tc := range tests {
+		got, ok := ParseBool(tc.in)
+		require.Equal(t, tc.wantOk, ok, "%v", tc.in)
+		require.Equal(t, tc.want, got, "%v", tc.in)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in     any
+		want   int64
+		wantOk bool
+	}{
+		{int64(512), 512, true},
+		{"512", 512, true},
+		{"32k", 32 << 10, true},
+		{"5M", 5 << 20, true},
+		{"1.5GB", 3 << 29, true},
+		{"5 MB", 5 << 20, true},
+		{"5T", 0, false},
+		{true, 0, false},
+	}
+	for _, tc := range tests {
+		got, ok := ParseSize(tc.in)
+		require.Equal(t, tc.wantOk, ok, "%v", tc.in)
+		require.Equal(t, tc.want, got, "%v", tc.in)
+	}
+}
+
+func TestParseSeconds(t *testing.T) {
+	tests := []struct {
+		in     any
+		want   float64
+		wantOk bool
+	}{
+		{int64(10), 10, true},
+		{"1.5", 1.5, true},
+		{"30s", 30, true},
+		{"10m", 600, true},
+		{"1h", 3600, true},
+		{"1d", 86400, true},
+		{"soon", 0, false},
+		{"", 0, false},
+	}
+	for _, tc := range tests {
+		got, ok := ParseSeconds(tc.in)
+		require.Equal(t, tc.wantOk, ok, "%v", tc.in)
+		require.Equal(t, tc.want, got, "%v", tc.in)
+	}
+}
+
+func TestParseStrings(t *testing.T) {
+	got, ok := ParseStrings("a b  c")
+	require.True(t, ok)
+	require.Equal(t, []string{"a", "b", "c"}, got)
+
+	got, ok = ParseStrings("a b, c")
+	require.True(t, ok)
+	require.Equal(t, []string{"a b", "c"}, got)
+
+	_, ok = ParseStrings([]any{"a", int64(1)})
+	require.False(t, ok)
+}
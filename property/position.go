@@ -0,0 +1,36 @@
+package property
+
+import "fmt"
+
+// Position of a property or section in the source text.
+// Lines and columns start at 1, and the file is only known
+// when the config was loaded from one.
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+// IsValid reports whether the position is known.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String in the form of `file:line:column`,
+// omitting the parts that are unknown.
+func (p Position) String() string {
+	s := p.File
+	if p.IsValid() {
+		if s != "" {
+			s += ":"
+		}
+		s += fmt.Sprintf("%d", p.Line)
+		if p.Column > 0 {
+			s += fmt.Sprintf(":%d", p.Column)
+		}
+	}
+	if s == "" {
+		s = "-"
+	}
+	return s
+}
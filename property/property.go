@@ -14,6 +14,9 @@ type Properties []Property
 type Property struct {
 	Key   string
 	Value any
+	// Pos where the property was defined when parsed.
+	// It is ignored by Properties.Equal.
+	Pos Position
 }
 
 // AsMap output.
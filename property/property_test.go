@@ -27,4 +27,17 @@ func TestProperties_Equal(t *testing.T) {
 
 	require.False(t, p1.Equal(p2))
 	require.False(t, p2.Equal(p1))
+
+	parsed := Properties{
+		{Key: "key1", Value: []any{"value1"}, Pos: Position{Line: 3, Column: 5}},
+	}
+	require.True(t, p1.Equal(parsed), "positions are ignored")
+}
+
+func TestPosition_String(t *testing.T) {
+	require.Equal(t, "-", Position{}.String())
+	require.Equal(t, "3", Position{Line: 3}.String())
+	require.Equal(t, "3:5", Position{Line: 3, Column: 5}.String())
+	require.Equal(t, "fluent-bit.conf:3:5", Position{File: "fluent-bit.conf", Line: 3, Column: 5}.String())
+	require.Equal(t, "fluent-bit.conf", Position{File: "fluent-bit.conf"}.String())
 }
@@ -52,6 +52,8 @@ func (pp *Properties) UnmarshalYAML(node *yaml.Node) error {
 			return fmt.Errorf("yaml decode property key: %w", err)
 		}
 
+		prop.Pos = Position{Line: keyNode.Line, Column: keyNode.Column}
+
 		valueNode := node.Content[i+1]
 		if err := valueNode.Decode(&prop.Value); err != nil {
 			return fmt.Errorf("yaml decode property value: %w", err)
@@ -0,0 +1,155 @@
+package fluentbitconfig
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+// RedactedValue replaces the values of sensitive properties by default.
+const RedactedValue = "******"
+
+// RedactOptions for Config.Redact.
+type RedactOptions struct {
+	// Schema used to detect sensitive properties from their description.
+	// DefaultSchema is used when nil.
+	Schema *Schema
+	// Keys of additional properties to redact, case-insensitively.
+	Keys []string
+	// Mask replacing sensitive values. Defaults to RedactedValue.
+	Mask string
+}
+
+var (
+	// reSensitiveKey matches keys of properties holding credentials.
+	// For example: http_passwd, api_key, client_secret or splunk_token.
+	reSensitiveKey = regexp.MustCompile(`(?i)passw|secret|token|api_?key|access_key|private_key|shared_key|credentials?$|auth_header`)
+	// reNotSensitiveKey matches keys that look sensitive
+	// but hold paths, commands or settings instead.
+	// For example: kube_token_file or store_token_in_metadata.
+	reNotSensitiveKey = regexp.MustCompile(`(?i)(_file|_path|_command|_ttl|_metadata|token_key|_credentials_file)$|^empty_`)
+	// reSensitiveDescription matches schema descriptions of properties
+	// holding credentials.
+	reSensitiveDescription = regexp.MustCompile(`(?i)\b(passwords?|secrets?|api key|credentials|tokens?)\b`)
+	// reNotSensitiveDescription matches descriptions of properties
+	// referencing credentials by path, command or record key.
+	reNotSensitiveDescription = regexp.MustCompile(`(?i)\b(path|file|location|username|command|record key)\b`)
+)
+
+// Redact returns a copy of the config with the values of sensitive
+// properties replaced with a mask, so it can be dumped safely.
+//
+// Sensitive properties are detected from their key, like http_passwd
+// or api_key, from their schema description, from the given keys,
+// and from their value when it holds a PEM encoded key or certificate.
+// Env, service, plugin, processor and upstream node properties are redacted.
+//
+// `{{ secrets.x }}` and `{{ files.x }}` cloud variables and `${VAR}` references
+// are kept, so only literal values are masked.
+func (c Config) Redact(opts RedactOptions) Config {
+	schema := DefaultSchema
+	if opts.Schema != nil {
+		schema = *opts.Schema
+	}
+
+	mask := opts.Mask
+	if mask == "" {
+		mask = RedactedValue
+	}
+
+	out := c.clone()
+
+	isSensitive := func(kind SectionKind, name string, p property.Property) bool {
+		for _, key := range opts.Keys {
+			if strings.EqualFold(key, p.Key) {
+				return true
+			}
+		}
+
+		if isSensitiveKey(p.Key) || isPEM(p.Value) {
+			return true
+		}
+
+		section, ok := schema.findSection(kind, name)
+		if !ok {
+			return false
+		}
+
+		o, ok := section.findOptions(p.Key)
+		return ok && o.Type == "string" &&
+			reSensitiveDescription.MatchString(o.Description) &&
+			!reNotSensitiveDescription.MatchString(o.Description)
+	}
+
+	redact := func(kind SectionKind, props property.Properties) {
+		name := Name(props)
+		for i, p := range props {
+			if isSensitive(kind, name, p) {
+				props[i].Value = redactValue(p.Value, mask)
+			}
+		}
+	}
+
+	redact("env", out.Env)
+	for _, ref := range out.propertiesRefs() {
+		redact(ref.Kind, *ref.Props)
+	}
+
+	return out
+}
+
+func isSensitiveKey(key string) bool {
+	return reSensitiveKey.MatchString(key) && !reNotSensitiveKey.MatchString(key)
+}
+
+func isPEM(v any) bool {
+	s, ok := v.(string)
+	return ok && strings.Contains(s, "-----BEGIN ")
+}
+
+// reVariable matches the references kept when redacting.
+var reVariable = regexp.MustCompile(reEnvVariable.String() + `|` + reCloudVariable.String())
+
+// redactValue masks the literal parts of a value,
+// keeping variable references and recursing into lists and maps.
+func redactValue(v any, mask string) any {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = redactValue(item, mask)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			out[key] = redactValue(item, mask)
+		}
+		return out
+	case string:
+		var sb strings.Builder
+		last := 0
+		for _, loc := range reVariable.FindAllStringIndex(v, -1) {
+			if strings.TrimSpace(v[last:loc[0]]) != "" {
+				sb.WriteString(mask)
+			} else {
+				sb.WriteString(v[last:loc[0]])
+			}
+			sb.WriteString(v[loc[0]:loc[1]])
+			last = loc[1]
+		}
+
+		if strings.TrimSpace(v[last:]) != "" {
+			sb.WriteString(mask)
+		} else {
+			sb.WriteString(v[last:])
+		}
+
+		return sb.String()
+	}
+
+	return mask
+}
@@ -0,0 +1,144 @@
+package fluentbitconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_Redact(t *testing.T) {
+	cfg, err := ParseAs(configLiteral(`
+		env:
+			db_password: hunter2
+			region: us-east-1
+		service:
+			http_server: on
+		pipeline:
+			inputs:
+				- name: http
+				  tls.key_passwd: changeme
+				  tls.key_file: /etc/tls/key.pem
+				  processors:
+					logs:
+						- name: content_modifier
+						  action: insert
+						  key: api_token
+						  value: abc
+						- name: sql
+						  query: SELECT 1
+						  shared_key: s3cr3t
+			outputs:
+				- name: http
+				  match: '*'
+				  http_user: admin
+				  http_passwd: ${HTTP_PASSWD}
+				  header: Authorization Bearer abc123
+				- name: td
+				  match: '*'
+				  api: xyz
+				  database: app
+				- name: splunk
+				  match: '*'
+				  splunk_token: prefix-{{ secrets.splunk }}
+				  splunk_token_key: token
+				  tls.crt: "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----"
+				- name: es
+				  match: '*'
+				  cloud_auth:
+					- user:pass
+					- '{{ files.esauth }}'
+	`), FormatYAML)
+	require.NoError(t, err)
+
+	got := cfg.Redact(RedactOptions{Keys: []string{"Header"}})
+	require.Equal(t, configLiteral(`
+		env:
+		    db_password: '******'
+		    region: us-east-1
+		service:
+		    http_server: "on"
+		pipeline:
+		    inputs:
+		        - name: http
+		          tls.key_passwd: '******'
+		          tls.key_file: /etc/tls/key.pem
+		          processors:
+		            logs:
+		                - name: content_modifier
+		                  action: insert
+		                  key: api_token
+		                  value: abc
+		                - name: sql
+		                  query: SELECT 1
+		                  shared_key: '******'
+		    outputs:
+		        - name: http
+		          match: '*'
+		          http_user: admin
+		          http_passwd: ${HTTP_PASSWD}
+		          header: '******'
+		        - name: td
+		          match: '*'
+		          api: '******'
+		          database: app
+		        - name: splunk
+		          match: '*'
+		          splunk_token: '******{{ secrets.splunk }}'
+		          splunk_token_key: token
+		          tls.crt: '******'
+		        - name: es
+		          match: '*'
+		          cloud_auth:
+		            - '******'
+		            - '{{ files.esauth }}'
+	`), mustDumpYAML(t, got))
+
+	v, _ := cfg.Pipeline.Inputs[0].Properties.Get("tls.key_passwd")
+	require.Equal(t, "changeme", v, "original not modified")
+
+	got = cfg.Redact(RedactOptions{Mask: "x"})
+	v, _ = got.Env.Get("db_password")
+	require.Equal(t, "x", v)
+}
+
+func TestConfig_Redact_upstream(t *testing.T) {
+	cfg, err := ParseAs(configLiteral(`
+		[UPSTREAM]
+			name forward-balancing
+
+		[NODE]
+			name       node-1
+			host       127.0.0.1
+			port       43000
+			shared_key s3cr3t
+	`), FormatClassic)
+	require.NoError(t, err)
+
+	got := cfg.Redact(RedactOptions{})
+	v, _ := got.Upstreams[0].Nodes[0].Properties.Get("shared_key")
+	require.Equal(t, RedactedValue, v)
+
+	v, _ = got.Upstreams[0].Nodes[0].Properties.Get("host")
+	require.Equal(t, "127.0.0.1", v)
+}
+
+func TestRedactValue(t *testing.T) {
+	tests := []struct {
+		in   any
+		want any
+	}{
+		{"secret", RedactedValue},
+		{"${PASSWORD}", "${PASSWORD}"},
+		{"{{ secrets.token }}", "{{ secrets.token }}"},
+		{"user:${PASSWORD}", RedactedValue + "${PASSWORD}"},
+		{"${USER}:secret", "${USER}" + RedactedValue},
+		{"${USER} ${PASSWORD}", "${USER} ${PASSWORD}"},
+		{int64(1234), RedactedValue},
+		{nil, nil},
+		{[]any{"a", "${B}"}, []any{RedactedValue, "${B}"}},
+		{map[string]any{"a": "b"}, map[string]any{"a": RedactedValue}},
+	}
+	for _, tc := range tests {
+		require.Equal(t, tc.want, redactValue(tc.in, RedactedValue), "%v", tc.in)
+	}
+}
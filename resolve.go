@@ -0,0 +1,236 @@
+package fluentbitconfig
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+// ErrVariableNotFound is returned by a VariableResolver
+// when the variable is not defined.
+var ErrVariableNotFound = errors.New("variable not found")
+
+// VariableKind of a Calyptia cloud variable.
+type VariableKind string
+
+const (
+	// VariableKindSecret for `{{ secrets.name }}` references.
+	VariableKindSecret VariableKind = "secrets"
+	// VariableKindFile for `{{ files.name }}` references.
+	// Files resolve to the path of the file, as plugins read them from disk.
+	VariableKindFile VariableKind = "files"
+)
+
+// VariableResolver resolves Calyptia cloud variables.
+// See Config.ResolveVariables.
+type VariableResolver interface {
+	// ResolveVariable returns the value of the variable,
+	// or ErrVariableNotFound when it is not defined.
+	ResolveVariable(kind VariableKind, name string) (string, error)
+}
+
+// MapResolver resolves variables from memory.
+type MapResolver struct {
+	Secrets map[string]string
+	Files   map[string]string
+}
+
+func (r MapResolver) ResolveVariable(kind VariableKind, name string) (string, error) {
+	var values map[string]string
+	switch kind {
+	case VariableKindSecret:
+		values = r.Secrets
+	case VariableKindFile:
+		values = r.Files
+	}
+
+	v, ok := values[name]
+	if !ok {
+		return "", ErrVariableNotFound
+	}
+
+	return v, nil
+}
+
+// DirResolver resolves variables from a directory
+// laid out as `secrets/<name>` and `files/<name>`.
+// Secrets resolve to the contents of their file,
+// without the trailing newline.
+// Files resolve to their path, that must exist.
+type DirResolver string
+
+func (r DirResolver) ResolveVariable(kind VariableKind, name string) (string, error) {
+	if strings.ContainsAny(name, `/\`) || name == ".." {
+		return "", ErrVariableNotFound
+	}
+
+	path := filepath.Join(string(r), string(kind), name)
+	if kind == VariableKindFile {
+		if _, err := os.Stat(path); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return "", ErrVariableNotFound
+			}
+			return "", err
+		}
+
+		return path, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", ErrVariableNotFound
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// EnvResolver resolves variables from the process environment.
+// Variables are looked up uppercased as `<Prefix><KIND>_<NAME>`
+// with dashes replaced by underscores.
+// For example: `{{ secrets.api-key }}` as SECRETS_API_KEY.
+type EnvResolver struct {
+	Prefix string
+}
+
+func (r EnvResolver) ResolveVariable(kind VariableKind, name string) (string, error) {
+	key := strings.ToUpper(strings.ReplaceAll(fmt.Sprintf("%s%s_%s", r.Prefix, kind, name), "-", "_"))
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return "", ErrVariableNotFound
+	}
+
+	return v, nil
+}
+
+// ResolveVariables resolves with the default schema.
+// See Config.ResolveVariablesWithSchema.
+func (c Config) ResolveVariables(resolver VariableResolver) (Config, error) {
+	return c.ResolveVariablesWithSchema(resolver, DefaultSchema)
+}
+
+// ResolveVariablesWithSchema returns a copy of the config with
+// the `{{ secrets.name }}` and `{{ files.name }}` references
+// on property values replaced, and validates the result against the schema.
+// Unresolvable references are left as is and reported
+// as UnresolvedVariableError, joined on the returned error
+// along with the validation one.
+// The resolved config is returned even when there are errors.
+func (c Config) ResolveVariablesWithSchema(resolver VariableResolver, schema Schema) (Config, error) {
+	out := c.clone()
+
+	var errs []error
+	for _, ref := range out.propertiesRefs() {
+		for i, p := range *ref.Props {
+			var unresolved []string
+			(*ref.Props)[i].Value = resolveValue(p.Value, func(kind VariableKind, name string) (string, bool) {
+				v, err := resolver.ResolveVariable(kind, name)
+				if err == nil {
+					return v, true
+				}
+
+				variable := string(kind) + "." + name
+				if !slices.Contains(unresolved, variable) {
+					unresolved = append(unresolved, variable)
+					errs = append(errs, &UnresolvedVariableError{
+						Name:    variable,
+						Section: ref.String(),
+						Key:     p.Key,
+						Pos:     p.Pos,
+						Err:     err,
+					})
+				}
+				return "", false
+			})
+		}
+	}
+
+	// The name itself might have been a reference.
+	out.refreshPlugins()
+
+	if err := out.ValidateWithSchema(schema); err != nil {
+		errs = append(errs, err)
+	}
+
+	return out, errors.Join(errs...)
+}
+
+// CloudVariableRef is a reference to a cloud variable
+// found on a property value.
+type CloudVariableRef struct {
+	Kind VariableKind `json:"kind"`
+	Name string       `json:"name"`
+	// Section referencing the variable. For example: input:tail:tail.0
+	Section string            `json:"section"`
+	Key     string            `json:"key"`
+	Pos     property.Position `json:"-"`
+}
+
+func (ref CloudVariableRef) String() string {
+	return fmt.Sprintf("%s: %s: %s.%s", ref.Section, ref.Key, ref.Kind, ref.Name)
+}
+
+// CloudVariables returns the `{{ secrets.name }}` and `{{ files.name }}`
+// references found on property values, in the same order they are written,
+// so they can be provided before deploying the config.
+// Service, plugins, processors, parsers, multiline parsers
+// and upstream nodes are included.
+func (c Config) CloudVariables() []CloudVariableRef {
+	var out []CloudVariableRef
+	for _, ref := range c.propertiesRefs() {
+		for _, p := range *ref.Props {
+			resolveValue(p.Value, func(kind VariableKind, name string) (string, bool) {
+				variable := CloudVariableRef{Kind: kind, Name: name, Section: ref.String(), Key: p.Key, Pos: p.Pos}
+				if !slices.Contains(out, variable) {
+					out = append(out, variable)
+				}
+				return "", false
+			})
+		}
+	}
+
+	return out
+}
+
+// reCloudVariable matches both `{{ secrets.name }}` and `{{ files.name }}`.
+var reCloudVariable = regexp.MustCompile(reCloudSecretVariable.String() + `|` + reCloudFileVariable.String())
+
+// resolveValue returns a copy of the value with the cloud variable
+// references on strings replaced, recursing into lists and maps.
+// References fn cannot resolve are kept.
+func resolveValue(v any, fn func(kind VariableKind, name string) (string, bool)) any {
+	switch v := v.(type) {
+	case string:
+		return reCloudVariable.ReplaceAllStringFunc(v, func(ref string) string {
+			kind, name, _ := strings.Cut(strings.Trim(ref, "{} \t"), ".")
+			if resolved, ok := fn(VariableKind(kind), name); ok {
+				return resolved
+			}
+			return ref
+		})
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = resolveValue(item, fn)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			out[key] = resolveValue(item, fn)
+		}
+		return out
+	}
+
+	return v
+}
@@ -0,0 +1,190 @@
+package fluentbitconfig
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+func TestConfig_ResolveVariables(t *testing.T) {
+	cfg, err := ParseAs(configLiteral(`
+		[INPUT]
+			Name            tail
+			Path            /var/log/app.log
+			DB              {{ files.db }}
+			Buffer_Max_Size {{ secrets.size }}
+
+		[OUTPUT]
+			Name        http
+			Match       *
+			Http_User   admin
+			Http_Passwd {{ secrets.password }}
+			Header      X-Token {{ secrets.token }}
+	`), FormatClassic)
+	require.NoError(t, err)
+
+	resolver := MapResolver{
+		Secrets: map[string]string{"size": "5M", "password": "hunter2", "token": "abc"},
+		Files:   map[string]string{"db": "/var/lib/fluent-bit/tail.db"},
+	}
+
+	t.Run("resolved", func(t *testing.T) {
+		got, err := cfg.ResolveVariables(resolver)
+		require.NoError(t, err)
+
+		require.Equal(t, property.Properties{
+			{Key: "Name", Value: "tail", Pos: property.Position{Line: 2, Column: 5}},
+			{Key: "Path", Value: "/var/log/app.log", Pos: property.Position{Line: 3, Column: 5}},
+			{Key: "DB", Value: "/var/lib/fluent-bit/tail.db", Pos: property.Position{Line: 4, Column: 5}},
+			{Key: "Buffer_Max_Size", Value: "5M", Pos: property.Position{Line: 5, Column: 5}},
+		}, got.Pipeline.Inputs[0].Properties)
+
+		v, _ := got.Pipeline.Outputs[0].Properties.Get("header")
+		require.Equal(t, "X-Token abc", v)
+
+		// The original config is left untouched.
+		v, _ = cfg.Pipeline.Outputs[0].Properties.Get("http_passwd")
+		require.Equal(t, "{{ secrets.password }}", v)
+	})
+
+	t.Run("unresolved", func(t *testing.T) {
+		_, err := cfg.ResolveVariables(MapResolver{Secrets: map[string]string{"size": "5M"}})
+		require.EqualError(t, err, "input:tail:tail.0: DB: undefined variable \"files.db\"\n"+
+			"output:http:http.0: Http_Passwd: undefined variable \"secrets.password\"\n"+
+			"output:http:http.0: Header: undefined variable \"secrets.token\"")
+
+		var unresolvedErr *UnresolvedVariableError
+		require.True(t, errors.As(err, &unresolvedErr))
+		require.Equal(t, "input:tail:tail.0", unresolvedErr.Section)
+		require.Equal(t, "DB", unresolvedErr.Key)
+		require.Equal(t, property.Position{Line: 4, Column: 5}, unresolvedErr.Pos)
+		require.ErrorIs(t, err, ErrVariableNotFound)
+	})
+
+	t.Run("name", func(t *testing.T) {
+		cfg, err := ParseAs(configLiteral(`
+			[INPUT]
+				Name {{ secrets.input }}
+			[INPUT]
+				Name  cpu
+				Alias {{ secrets.alias }}
+		`), FormatClassic)
+		require.NoError(t, err)
+
+		got, err := cfg.ResolveVariables(MapResolver{Secrets: map[string]string{"input": "mem", "alias": "host_cpu"}})
+		require.NoError(t, err)
+		require.Equal(t, []string{"input:mem:mem.0", "input:cpu:host_cpu"}, got.IDs(true))
+	})
+
+	t.Run("validate_resolved", func(t *testing.T) {
+		require.NoError(t, cfg.Validate(), "unresolved references are not validated")
+
+		resolver.Secrets["size"] = "big"
+		_, err := cfg.ResolveVariables(resolver)
+		require.EqualError(t, err, `5:5: input: tail: expected "Buffer_Max_Size" to be a valid size, got big`)
+	})
+}
+
+func TestDirResolver(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "secrets"), 0o755))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "files"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "secrets", "token"), []byte("abc\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "files", "parsers"), []byte("[PARSER]\n"), 0o600))
+
+	resolver := DirResolver(dir)
+
+	v, err := resolver.ResolveVariable(VariableKindSecret, "token")
+	require.NoError(t, err)
+	require.Equal(t, "abc", v)
+
+	v, err = resolver.ResolveVariable(VariableKindFile, "parsers")
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, "files", "parsers"), v)
+
+	_, err = resolver.ResolveVariable(VariableKindSecret, "parsers")
+	require.ErrorIs(t, err, ErrVariableNotFound)
+
+	_, err = resolver.ResolveVariable(VariableKindSecret, "..")
+	require.ErrorIs(t, err, ErrVariableNotFound)
+}
+
+func TestEnvResolver(t *testing.T) {
+	t.Setenv("CALYPTIA_SECRETS_API_KEY", "abc")
+
+	v, err := EnvResolver{Prefix: "calyptia_"}.ResolveVariable(VariableKindSecret, "api-key")
+	require.NoError(t, err)
+	require.Equal(t, "abc", v)
+
+	_, err = EnvResolver{}.ResolveVariable(VariableKindSecret, "api-key")
+	require.ErrorIs(t, err, ErrVariableNotFound)
+
+	cfg, err := ParseAs(configLiteral(`
+		[OUTPUT]
+			Name   http
+			Match  *
+			Header X-Api-Key {{ secrets.api-key }}
+	`), FormatClassic)
+	require.NoError(t, err)
+
+	got, err := cfg.ResolveVariables(EnvResolver{Prefix: "calyptia_"})
+	require.NoError(t, err)
+
+	header, _ := got.Pipeline.Outputs[0].Properties.Get("header")
+	require.Equal(t, "X-Api-Key abc", header)
+}
+
+func TestConfig_CloudVariables(t *testing.T) {
+	cfg, err := ParseAs(configLiteral(`
+		service:
+			http_passwd: '{{ secrets.admin }}'
+		parsers:
+			- name: custom
+			  format: regex
+			  regex: '{{ secrets.regex }}'
+		pipeline:
+			inputs:
+				- name: tail
+				  path: /var/log/app.log
+				  parsers_file: '{{ files.parsers }}'
+				  processors:
+					logs:
+						- name: content_modifier
+						  action: insert
+						  key: token
+						  value: '{{ secrets.token }}'
+			outputs:
+				- name: http
+				  match: '*'
+				  header:
+					- X-Token {{ secrets.token }}
+					- X-Env {{ secrets.env }}
+	`), FormatYAML)
+	require.NoError(t, err)
+
+	var got []string
+	for _, ref := range cfg.CloudVariables() {
+		got = append(got, ref.String())
+	}
+	require.Equal(t, []string{
+		"service: http_passwd: secrets.admin",
+		"input:tail:tail.0: parsers_file: files.parsers",
+		"input:tail:tail.0/logs:content_modifier:content_modifier.0: value: secrets.token",
+		"output:http:http.0: header: secrets.token",
+		"output:http:http.0: header: secrets.env",
+		"parser:custom:custom.0: regex: secrets.regex",
+	}, got)
+
+	require.Equal(t, CloudVariableRef{
+		Kind:    VariableKindFile,
+		Name:    "parsers",
+		Section: "input:tail:tail.0",
+		Key:     "parsers_file",
+		Pos:     property.Position{Line: 11, Column: 11},
+	}, cfg.CloudVariables()[1])
+}
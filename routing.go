@@ -0,0 +1,395 @@
+package fluentbitconfig
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+// RouteGraph is how records flow from inputs, through filters,
+// to outputs and stream tasks, according to their tags
+// and the `Match` or `Match_Regex` properties.
+// See Config.RouteGraph.
+type RouteGraph struct {
+	Nodes  []RouteNode  `json:"nodes"`
+	Edges  []RouteEdge  `json:"edges"`
+	Issues []RouteIssue `json:"issues,omitempty"`
+}
+
+// RouteNode is an input, filter, output or stream task.
+type RouteNode struct {
+	// ID namespaced. For example: input:tail:tail.0
+	// Stream tasks are namespaced by their name: stream_task:<name>
+	ID   string      `json:"id"`
+	Kind SectionKind `json:"kind"`
+	// Tags of the records emitted by inputs, stream tasks
+	// and filters that re-emit records, like rewrite_tag.
+	// Tags can have `*` wildcards when not known in advance.
+	Tags []string `json:"tags,omitempty"`
+	// Match pattern of filters and outputs,
+	// or the tag pattern stream tasks read from.
+	Match      string            `json:"match,omitempty"`
+	MatchRegex string            `json:"match_regex,omitempty"`
+	Pos        property.Position `json:"-"`
+}
+
+// RouteEdge from one node to the next one
+// for records with the given tag.
+type RouteEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Tag  string `json:"tag"`
+}
+
+// RouteIssueKind of a RouteIssue.
+type RouteIssueKind string
+
+const (
+	// RouteIssueUnroutedInput is an input whose records
+	// do not reach any output.
+	RouteIssueUnroutedInput RouteIssueKind = "unrouted_input"
+	// RouteIssueUnmatchedOutput is an output that matches no tag.
+	RouteIssueUnmatchedOutput RouteIssueKind = "unmatched_output"
+	// RouteIssueUnusedFilter is a filter that matches no tag,
+	// so it never runs.
+	RouteIssueUnusedFilter RouteIssueKind = "unused_filter"
+)
+
+// RouteIssue is a likely misconfiguration found on the route graph.
+type RouteIssue struct {
+	Kind RouteIssueKind `json:"kind"`
+	// ID of the node. For example: input:tail:tail.0
+	ID  string            `json:"id"`
+	Pos property.Position `json:"-"`
+}
+
+func (i RouteIssue) String() string {
+	switch i.Kind {
+	case RouteIssueUnroutedInput:
+		return fmt.Sprintf("%s: records do not reach any output", i.ID)
+	case RouteIssueUnmatchedOutput, RouteIssueUnusedFilter:
+		return fmt.Sprintf("%s: does not match any tag", i.ID)
+	}
+
+	return fmt.Sprintf("%s: %s", i.ID, i.Kind)
+}
+
+// RouteGraph builds the route graph of the config.
+//
+// Input tags default to the input instance name, like tail.0.
+// Tags and match patterns use Fluent Bit `*` wildcards,
+// and a tag with wildcards, like those of the tail input,
+// is routed to every pattern that could match it.
+// Records re-emitted by rewrite_tag filters and `CREATE STREAM`
+// stream tasks go through the pipeline again with their new tag.
+func (c Config) RouteGraph() RouteGraph {
+	b := routeBuilder{edges: map[RouteEdge]bool{}, seen: map[[2]string]bool{}}
+
+	inputNames := map[string]string{}
+	for i, input := range c.Pipeline.Inputs {
+		instance := fmt.Sprintf("%s.%d", input.Name, i)
+		tag := instance
+		if v, ok := input.Properties.Get("tag"); ok && stringFromAny(v) != "" {
+			tag = stringFromAny(v)
+		}
+
+		node := RouteNode{
+			ID:   fmt.Sprintf("%s:%s:%s", SectionKindInput, input.Name, input.ID),
+			Kind: SectionKindInput,
+			Tags: []string{tag},
+			Pos:  input.Pos,
+		}
+		b.nodes = append(b.nodes, node)
+		b.inputs = append(b.inputs, node)
+		b.queue = append(b.queue, routeSource{node: node.ID, tag: tag})
+
+		inputNames[node.ID] = instance
+		if alias := Alias(input.Properties); alias != "" {
+			inputNames[node.ID] = alias
+		}
+	}
+
+	for _, filter := range c.Pipeline.Filters {
+		node := routeTarget(SectionKindFilter, filter)
+		node.Tags = rewrittenTags(filter)
+		b.nodes = append(b.nodes, node)
+		b.filters = append(b.filters, node)
+	}
+
+	for _, output := range c.Pipeline.Outputs {
+		node := routeTarget(SectionKindOutput, output)
+		b.nodes = append(b.nodes, node)
+		b.outputs = append(b.outputs, node)
+	}
+
+	for _, task := range c.StreamTasks {
+		node := RouteNode{
+			ID:   fmt.Sprintf("%s:%s", SectionKindStreamTask, task.Name),
+			Kind: SectionKindStreamTask,
+			Pos:  task.Pos,
+		}
+
+		var stream string
+		if q, err := task.Query(); err == nil {
+			if tag, ok := q.ReadTag(); ok {
+				node.Match = tag
+			} else if q.Source.Kind == StreamSourceStream {
+				stream = q.Source.Name
+			}
+
+			if tag, ok := q.EmittedTag(); ok {
+				node.Tags = []string{tag}
+			}
+		}
+
+		b.nodes = append(b.nodes, node)
+		b.tasks = append(b.tasks, routeTask{node: node, stream: stream})
+	}
+
+	for len(b.queue) != 0 {
+		src := b.queue[0]
+		b.queue = b.queue[1:]
+		b.route(src, inputNames)
+	}
+
+	return b.graph()
+}
+
+type routeSource struct {
+	node string
+	tag  string
+}
+
+type routeTask struct {
+	node RouteNode
+	// stream is the input instance the task reads from,
+	// when it does not read by tag.
+	stream string
+}
+
+type routeBuilder struct {
+	nodes   []RouteNode
+	inputs  []RouteNode
+	filters []RouteNode
+	outputs []RouteNode
+	tasks   []routeTask
+
+	queue     []routeSource
+	seen      map[[2]string]bool
+	edges     map[RouteEdge]bool
+	edgesList []RouteEdge
+}
+
+// route the records emitted by a node with the given tag.
+func (b *routeBuilder) route(src routeSource, inputNames map[string]string) {
+	key := [2]string{src.node, src.tag}
+	if b.seen[key] {
+		return
+	}
+	b.seen[key] = true
+
+	last := src.node
+	for _, filter := range b.filters {
+		if !routeMatches(filter, src.tag) {
+			continue
+		}
+
+		b.addEdge(RouteEdge{From: last, To: filter.ID, Tag: src.tag})
+		last = filter.ID
+
+		for _, tag := range filter.Tags {
+			b.queue = append(b.queue, routeSource{node: filter.ID, tag: tag})
+		}
+	}
+
+	for _, output := range b.outputs {
+		if routeMatches(output, src.tag) {
+			b.addEdge(RouteEdge{From: last, To: output.ID, Tag: src.tag})
+		}
+	}
+
+	for _, task := range b.tasks {
+		reads := routeMatches(task.node, src.tag)
+		if name, ok := inputNames[src.node]; ok && task.stream != "" {
+			reads = task.stream == name
+		}
+		if !reads {
+			continue
+		}
+
+		b.addEdge(RouteEdge{From: last, To: task.node.ID, Tag: src.tag})
+		for _, tag := range task.node.Tags {
+			b.queue = append(b.queue, routeSource{node: task.node.ID, tag: tag})
+		}
+	}
+}
+
+func (b *routeBuilder) addEdge(e RouteEdge) {
+	if !b.edges[e] {
+		b.edges[e] = true
+		b.edgesList = append(b.edgesList, e)
+	}
+}
+
+func (b *routeBuilder) graph() RouteGraph {
+	out := RouteGraph{Nodes: b.nodes, Edges: b.edgesList}
+
+	next := map[string][]string{}
+	incoming := map[string]bool{}
+	for _, e := range b.edgesList {
+		next[e.From] = append(next[e.From], e.To)
+		incoming[e.To] = true
+	}
+
+	isOutput := map[string]bool{}
+	for _, output := range b.outputs {
+		isOutput[output.ID] = true
+	}
+
+	for _, input := range b.inputs {
+		if !reaches(input.ID, next, isOutput) {
+			out.Issues = append(out.Issues, RouteIssue{Kind: RouteIssueUnroutedInput, ID: input.ID, Pos: input.Pos})
+		}
+	}
+
+	for _, filter := range b.filters {
+		if !incoming[filter.ID] {
+			out.Issues = append(out.Issues, RouteIssue{Kind: RouteIssueUnusedFilter, ID: filter.ID, Pos: filter.Pos})
+		}
+	}
+
+	for _, output := range b.outputs {
+		if !incoming[output.ID] {
+			out.Issues = append(out.Issues, RouteIssue{Kind: RouteIssueUnmatchedOutput, ID: output.ID, Pos: output.Pos})
+		}
+	}
+
+	return out
+}
+
+// reaches tells whether any of the targets can be reached from the given node.
+func reaches(from string, next map[string][]string, targets map[string]bool) bool {
+	visited := map[string]bool{from: true}
+	queue := []string{from}
+	for len(queue) != 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, to := range next[id] {
+			if targets[to] {
+				return true
+			}
+
+			if !visited[to] {
+				visited[to] = true
+				queue = append(queue, to)
+			}
+		}
+	}
+
+	return false
+}
+
+func routeTarget(kind SectionKind, plugin Plugin) RouteNode {
+	node := RouteNode{
+		ID:   fmt.Sprintf("%s:%s:%s", kind, plugin.Name, plugin.ID),
+		Kind: kind,
+		Pos:  plugin.Pos,
+	}
+
+	if v, ok := plugin.Properties.Get("match"); ok {
+		node.Match = stringFromAny(v)
+	}
+
+	if v, ok := plugin.Properties.Get("match_regex"); ok {
+		node.MatchRegex = stringFromAny(v)
+	}
+
+	return node
+}
+
+// reTagTemplate matches record accessors and `$TAG` references
+// on the new tag of rewrite_tag rules.
+var reTagTemplate = regexp.MustCompile(`\$\{[^}]*\}|\$[A-Za-z0-9_]+(?:\[[^\]]*\])*`)
+
+// rewrittenTags returns the tags a rewrite_tag filter emits records with,
+// with templated parts as `*` wildcards.
+// The rules are written as: $key regex new_tag keep
+func rewrittenTags(filter Plugin) []string {
+	if filter.Name != "rewrite_tag" {
+		return nil
+	}
+
+	var out []string
+	for _, v := range classicValues(filter.Properties, "rule") {
+		tokens, err := splitQuotedTokens(stringFromAny(v))
+		if err != nil || len(tokens) < 3 {
+			continue
+		}
+
+		out = append(out, reTagTemplate.ReplaceAllString(tokens[2], "*"))
+	}
+
+	return out
+}
+
+// routeMatches tells whether a filter, output or stream task
+// selects records with the given tag.
+// As in Fluent Bit, Match takes precedence over Match_Regex.
+// Regular expressions cannot be fully checked against tags with wildcards,
+// so they are assumed to match unless an anchored literal prefix differs.
+func routeMatches(node RouteNode, tag string) bool {
+	if node.Match != "" {
+		return globsIntersect(node.Match, tag)
+	}
+
+	if node.MatchRegex != "" {
+		re, err := regexp.Compile(node.MatchRegex)
+		if err != nil {
+			return false
+		}
+
+		before, _, wildcard := strings.Cut(tag, "*")
+		if !wildcard {
+			return re.MatchString(tag)
+		}
+
+		prefix, _ := re.LiteralPrefix()
+		return !strings.HasPrefix(node.MatchRegex, "^") ||
+			strings.HasPrefix(before, prefix) || strings.HasPrefix(prefix, before)
+	}
+
+	return false
+}
+
+// globsIntersect tells whether there is a string matched by both patterns,
+// where `*` matches any sequence of characters.
+// Two literal tags intersect when equal.
+func globsIntersect(a, b string) bool {
+	memo := map[[2]int]bool{}
+	var match func(i, j int) bool
+	match = func(i, j int) bool {
+		key := [2]int{i, j}
+		if v, ok := memo[key]; ok {
+			return v
+		}
+
+		var ok bool
+		switch {
+		case i == len(a) && j == len(b):
+			ok = true
+		case i < len(a) && a[i] == '*':
+			ok = match(i+1, j) || (j < len(b) && match(i, j+1))
+		case j < len(b) && b[j] == '*':
+			ok = match(i, j+1) || (i < len(a) && match(i+1, j))
+		case i < len(a) && j < len(b):
+			ok = a[i] == b[j] && match(i+1, j+1)
+		}
+
+		memo[key] = ok
+		return ok
+	}
+
+	return match(0, 0)
+}
@@ -0,0 +1,108 @@
+package fluentbitconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_RouteGraph(t *testing.T) {
+	cfg, err := ParseAs(configLiteral(`
+		pipeline:
+			inputs:
+				- name: tail
+				  tag: kube.*
+				- name: cpu
+				- name: dummy
+				  tag: orphan
+			filters:
+				- name: grep
+				  match: kube.*
+				- name: rewrite_tag
+				  match: kube.*
+				  rule: $level ^error$ errors.$TAG false
+				- name: modify
+				  match_regex: ^cpu\.\d+$
+				- name: lua
+				  match: nothing
+			outputs:
+				- name: stdout
+				  match: '*'
+				  alias: all
+				- name: forward
+				  match: errors.*
+				- name: http
+				  match: audit
+				  match_regex: .*
+		stream_processor:
+			- name: cpu_avg
+			  exec: CREATE STREAM avg WITH (tag='cpu.avg') AS SELECT AVG(cpu_p) FROM STREAM:cpu.1;
+	`), FormatYAML)
+	require.NoError(t, err)
+
+	graph := cfg.RouteGraph()
+
+	require.Equal(t, []RouteEdge{
+		{From: "input:tail:tail.0", To: "filter:grep:grep.0", Tag: "kube.*"},
+		{From: "filter:grep:grep.0", To: "filter:rewrite_tag:rewrite_tag.1", Tag: "kube.*"},
+		{From: "filter:rewrite_tag:rewrite_tag.1", To: "output:stdout:all", Tag: "kube.*"},
+		{From: "input:cpu:cpu.1", To: "filter:modify:modify.2", Tag: "cpu.1"},
+		{From: "filter:modify:modify.2", To: "output:stdout:all", Tag: "cpu.1"},
+		{From: "filter:modify:modify.2", To: "stream_task:cpu_avg", Tag: "cpu.1"},
+		{From: "input:dummy:dummy.2", To: "output:stdout:all", Tag: "orphan"},
+		{From: "filter:rewrite_tag:rewrite_tag.1", To: "output:stdout:all", Tag: "errors.*"},
+		{From: "filter:rewrite_tag:rewrite_tag.1", To: "output:forward:forward.1", Tag: "errors.*"},
+		{From: "stream_task:cpu_avg", To: "output:stdout:all", Tag: "cpu.avg"},
+	}, graph.Edges)
+
+	require.Equal(t, RouteNode{
+		ID:    "filter:rewrite_tag:rewrite_tag.1",
+		Kind:  SectionKindFilter,
+		Tags:  []string{"errors.*"},
+		Match: "kube.*",
+		Pos:   cfg.Pipeline.Filters[1].Pos,
+	}, graph.Nodes[4])
+
+	var issues []string
+	for _, issue := range graph.Issues {
+		issues = append(issues, issue.String())
+	}
+	require.Equal(t, []string{
+		"filter:lua:lua.3: does not match any tag",
+		"output:http:http.2: does not match any tag",
+	}, issues)
+
+	t.Run("unrouted_input", func(t *testing.T) {
+		cfg.Pipeline.Outputs = cfg.Pipeline.Outputs[1:]
+		reassignIDs(cfg.Pipeline.Outputs)
+
+		var ids []string
+		for _, issue := range cfg.RouteGraph().Issues {
+			if issue.Kind == RouteIssueUnroutedInput {
+				ids = append(ids, issue.ID)
+			}
+		}
+		require.Equal(t, []string{"input:cpu:cpu.1", "input:dummy:dummy.2"}, ids)
+	})
+}
+
+func TestGlobsIntersect(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"*", "anything", true},
+		{"kube.*", "kube.var.log", true},
+		{"kube.*", "kube", false},
+		{"kube.*", "*.log", true},
+		{"a*b", "*c", false},
+		{"app", "app", true},
+		{"app", "App", false},
+		{"", "", true},
+		{"*", "", true},
+	}
+	for _, tc := range tests {
+		require.Equal(t, tc.want, globsIntersect(tc.a, tc.b), "%q %q", tc.a, tc.b)
+		require.Equal(t, tc.want, globsIntersect(tc.b, tc.a), "%q %q", tc.b, tc.a)
+	}
+}
@@ -0,0 +1,216 @@
+package fluentbitconfig
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+// SchemaChangeKind of a SchemaChange.
+type SchemaChangeKind string
+
+const (
+	SchemaPluginAdded    SchemaChangeKind = "plugin_added"
+	SchemaPluginRemoved  SchemaChangeKind = "plugin_removed"
+	SchemaOptionAdded    SchemaChangeKind = "option_added"
+	SchemaOptionRemoved  SchemaChangeKind = "option_removed"
+	SchemaTypeChanged    SchemaChangeKind = "type_changed"
+	SchemaDefaultChanged SchemaChangeKind = "default_changed"
+)
+
+// SchemaChange between two schemas.
+type SchemaChange struct {
+	Kind    SchemaChangeKind `json:"kind"`
+	Section SectionKind      `json:"section"`
+	Plugin  string           `json:"plugin"`
+	// Option name. Empty for added and removed plugins.
+	Option string `json:"option,omitempty"`
+	// From and To types or defaults,
+	// or the type of added and removed options.
+	From any `json:"from,omitempty"`
+	To   any `json:"to,omitempty"`
+}
+
+func (c SchemaChange) String() string {
+	switch c.Kind {
+	case SchemaPluginAdded:
+		return fmt.Sprintf("%s: added plugin %q", c.Section, c.Plugin)
+	case SchemaPluginRemoved:
+		return fmt.Sprintf("%s: removed plugin %q", c.Section, c.Plugin)
+	case SchemaOptionAdded:
+		return fmt.Sprintf("%s: %s: added option %q (%v)", c.Section, c.Plugin, c.Option, c.To)
+	case SchemaOptionRemoved:
+		return fmt.Sprintf("%s: %s: removed option %q (%v)", c.Section, c.Plugin, c.Option, c.From)
+	case SchemaTypeChanged:
+		return fmt.Sprintf("%s: %s: option %q type changed from %v to %v", c.Section, c.Plugin, c.Option, c.From, c.To)
+	case SchemaDefaultChanged:
+		return fmt.Sprintf("%s: %s: option %q default changed from %s to %s",
+			c.Section, c.Plugin, c.Option, schemaDefaultString(c.From), schemaDefaultString(c.To))
+	}
+
+	return fmt.Sprintf("%s: %s: %s %s", c.Section, c.Plugin, c.Kind, c.Option)
+}
+
+// SchemaChanges between two schemas as returned by DiffSchemas.
+// Use encoding/json for a JSON rendering.
+type SchemaChanges []SchemaChange
+
+// DiffSchemas returns the plugins and options added and removed,
+// and the options whose type or default changed, going from schema a to b.
+// Changes are sorted by section kind, plugin and option name,
+// and plugins and options are matched case-insensitively.
+func DiffSchemas(a, b Schema) SchemaChanges {
+	var out SchemaChanges
+	for _, kind := range []SectionKind{SectionKindCustom, SectionKindInput, SectionKindFilter, SectionKindOutput, SectionKindProcessor} {
+		from, _ := a.findSections(kind)
+		to, _ := b.findSections(kind)
+		if kind == SectionKindProcessor {
+			// findSections includes filters as processors.
+			from, to = a.Processors, b.Processors
+		}
+
+		out = append(out, diffSchemaSections(kind, from, to)...)
+	}
+
+	return out
+}
+
+func diffSchemaSections(kind SectionKind, from, to []SchemaSection) SchemaChanges {
+	names := map[string]bool{}
+	fromByName := map[string]SchemaSection{}
+	for _, s := range from {
+		fromByName[strings.ToLower(s.Name)] = s
+		names[strings.ToLower(s.Name)] = true
+	}
+	toByName := map[string]SchemaSection{}
+	for _, s := range to {
+		toByName[strings.ToLower(s.Name)] = s
+		names[strings.ToLower(s.Name)] = true
+	}
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	slices.Sort(sorted)
+
+	var out SchemaChanges
+	for _, name := range sorted {
+		a, inFrom := fromByName[name]
+		b, inTo := toByName[name]
+		switch {
+		case !inTo:
+			out = append(out, SchemaChange{Kind: SchemaPluginRemoved, Section: kind, Plugin: a.Name})
+		case !inFrom:
+			out = append(out, SchemaChange{Kind: SchemaPluginAdded, Section: kind, Plugin: b.Name})
+		default:
+			out = append(out, diffSchemaOptions(kind, b.Name, a.Properties.all(), b.Properties.all())...)
+		}
+	}
+
+	return out
+}
+
+func diffSchemaOptions(kind SectionKind, plugin string, from, to []SchemaOptions) SchemaChanges {
+	find := func(options []SchemaOptions, name string) (SchemaOptions, bool) {
+		i := slices.IndexFunc(options, func(o SchemaOptions) bool {
+			return strings.EqualFold(o.Name, name)
+		})
+		if i == -1 {
+			return SchemaOptions{}, false
+		}
+		return options[i], true
+	}
+
+	// Some plugins list the same option more than once,
+	// like TLS options on both options and network_tls.
+	// As on findOptions, the first one wins.
+	unique := func(options []SchemaOptions) []SchemaOptions {
+		var out []SchemaOptions
+		for _, o := range options {
+			if _, ok := find(out, o.Name); !ok {
+				out = append(out, o)
+			}
+		}
+		return out
+	}
+	from, to = unique(from), unique(to)
+
+	var out SchemaChanges
+	for _, a := range from {
+		b, ok := find(to, a.Name)
+		if !ok {
+			out = append(out, SchemaChange{Kind: SchemaOptionRemoved, Section: kind, Plugin: plugin, Option: a.Name, From: a.Type})
+			continue
+		}
+
+		if a.Type != b.Type {
+			out = append(out, SchemaChange{Kind: SchemaTypeChanged, Section: kind, Plugin: plugin, Option: b.Name, From: a.Type, To: b.Type})
+		}
+
+		if !sameDefault(a, b) {
+			out = append(out, SchemaChange{Kind: SchemaDefaultChanged, Section: kind, Plugin: plugin, Option: b.Name, From: a.Default, To: b.Default})
+		}
+	}
+
+	for _, b := range to {
+		if _, ok := find(from, b.Name); !ok {
+			out = append(out, SchemaChange{Kind: SchemaOptionAdded, Section: kind, Plugin: plugin, Option: b.Name, To: b.Type})
+		}
+	}
+
+	slices.SortStableFunc(out, func(x, y SchemaChange) int {
+		return cmp.Compare(strings.ToLower(x.Option), strings.ToLower(y.Option))
+	})
+
+	return out
+}
+
+func schemaDefaultString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "none"
+	case string:
+		return fmt.Sprintf("%q", v)
+	}
+
+	return stringFromAny(v)
+}
+
+// sameDefault tells whether both options have the same default value,
+// parsed by their type when it did not change.
+// For example, "true" and "on" are the same boolean.
+func sameDefault(a, b SchemaOptions) bool {
+	if reflect.DeepEqual(a.Default, b.Default) {
+		return true
+	}
+
+	if a.Type != b.Type || a.Default == nil || b.Default == nil {
+		return false
+	}
+
+	switch b.Type {
+	case "boolean":
+		return sameParsed(a.Default, b.Default, property.ParseBool)
+	case "integer":
+		return sameParsed(a.Default, b.Default, property.ParseInt)
+	case "double":
+		return sameParsed(a.Default, b.Default, property.ParseFloat)
+	case "size":
+		return sameParsed(a.Default, b.Default, property.ParseSize)
+	case "time":
+		return sameParsed(a.Default, b.Default, property.ParseSeconds)
+	}
+
+	return false
+}
+
+func sameParsed[T comparable](a, b any, parse func(any) (T, bool)) bool {
+	x, okA := parse(a)
+	y, okB := parse(b)
+	return okA && okB && x == y
+}
@@ -0,0 +1,108 @@
+package fluentbitconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffSchemas(t *testing.T) {
+	a := Schema{
+		Inputs: []SchemaSection{
+			{Name: "tail", Properties: SchemaProperties{Options: []SchemaOptions{
+				{Name: "path", Type: "string"},
+				{Name: "refresh_interval", Type: "integer", Default: "60"},
+				{Name: "rotate_wait", Type: "time", Default: "5"},
+				{Name: "old", Type: "boolean"},
+				{Name: "tls.verify", Type: "boolean", Default: "true"},
+				{Name: "buffer_max_size", Type: "size", Default: "1M"},
+			}}},
+			{Name: "dummy"},
+		},
+		Outputs: []SchemaSection{
+			{Name: "stdout", Properties: SchemaProperties{Options: []SchemaOptions{
+				{Name: "format", Type: "string"},
+			}}},
+		},
+	}
+	b := Schema{
+		Inputs: []SchemaSection{
+			{Name: "Tail", Properties: SchemaProperties{Options: []SchemaOptions{
+				{Name: "Path", Type: "string"},
+				{Name: "refresh_interval", Type: "time", Default: "60"},
+				{Name: "rotate_wait", Type: "time", Default: "10"},
+				{Name: "db", Type: "string"},
+				{Name: "tls.verify", Type: "boolean", Default: "on"},
+				{Name: "buffer_max_size", Type: "size", Default: "1024k"},
+			}}},
+			{Name: "cpu"},
+		},
+		Outputs: []SchemaSection{
+			{Name: "stdout", Properties: SchemaProperties{Options: []SchemaOptions{
+				{Name: "format", Type: "string", Default: "msgpack"},
+			}}},
+		},
+		Processors: []SchemaSection{
+			{Name: "sql"},
+		},
+	}
+
+	var got []string
+	changes := DiffSchemas(a, b)
+	require.NotEmpty(t, changes)
+	for _, change := range changes {
+		got = append(got, change.String())
+	}
+	require.Equal(t, []string{
+		`input: added plugin "cpu"`,
+		`input: removed plugin "dummy"`,
+		`input: Tail: added option "db" (string)`,
+		`input: Tail: removed option "old" (boolean)`,
+		`input: Tail: option "refresh_interval" type changed from integer to time`,
+		`input: Tail: option "rotate_wait" default changed from "5" to "10"`,
+		`output: stdout: option "format" default changed from none to "msgpack"`,
+		`processor: added plugin "sql"`,
+	}, got)
+
+	require.Equal(t, SchemaChange{
+		Kind:    SchemaTypeChanged,
+		Section: SectionKindInput,
+		Plugin:  "Tail",
+		Option:  "refresh_interval",
+		From:    "integer",
+		To:      "time",
+	}, DiffSchemas(a, b)[4])
+
+	require.Empty(t, DiffSchemas(DefaultSchema, DefaultSchema))
+}
+
+func TestDiffSchemas_normalizedDefaults(t *testing.T) {
+	a, err := GetSchema("24.9.2")
+	require.NoError(t, err)
+
+	b, err := GetSchema("24.10.0")
+	require.NoError(t, err)
+
+	for _, change := range DiffSchemas(a, b) {
+		if change.Kind == SchemaDefaultChanged {
+			require.NotEqual(t, "tls.verify", change.Option, change.String())
+		}
+	}
+}
+
+func TestDiffSchemas_embedded(t *testing.T) {
+	a, err := GetSchema("25.10.1")
+	require.NoError(t, err)
+
+	b, err := GetSchema("26.2.1")
+	require.NoError(t, err)
+
+	changes := DiffSchemas(a, b)
+	require.NotEmpty(t, changes)
+	for _, change := range changes {
+		require.NotEmpty(t, change.Plugin)
+		if change.Kind == SchemaTypeChanged || change.Kind == SchemaDefaultChanged {
+			require.NotEqual(t, change.From, change.To, change.String())
+		}
+	}
+}
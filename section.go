@@ -12,4 +12,10 @@ const (
 	SectionKindFilter    SectionKind = "filter"
 	SectionKindOutput    SectionKind = "output"
 	SectionKindProcessor SectionKind = "processor"
+
+	SectionKindMultilineParser SectionKind = "multiline_parser"
+	SectionKindPlugins         SectionKind = "plugins"
+	SectionKindUpstream        SectionKind = "upstream"
+	SectionKindNode            SectionKind = "node"
+	SectionKindStreamTask      SectionKind = "stream_task"
 )
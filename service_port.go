@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/calyptia/go-fluentbit-config/v2/networking"
+	"github.com/calyptia/go-fluentbit-config/v2/property"
 )
 
 type ServicePortGetter struct {
@@ -17,13 +18,17 @@ type ServicePortGetter struct {
 }
 
 func fromPort(p Plugin) (int32, bool) {
-	portVal, ok := p.Properties.Get("port")
-	if !ok {
+	return portFrom(p.Properties, "port")
+}
+
+// portFrom returns the property value as a port number.
+func portFrom(props property.Properties, key string) (int32, bool) {
+	i, err := props.GetInt(key)
+	if err != nil || i != int64(int32(i)) {
 		return 0, false
 	}
 
-	i, ok := int32FromAny(portVal)
-	return i, ok
+	return int32(i), true
 }
 
 var inputServicePorts = map[string]ServicePortGetter{
@@ -165,16 +170,14 @@ type ServicePorts []ServicePort
 func (c *Config) ServicePorts() ServicePorts {
 	var out ServicePorts
 
-	httpServerEnabled, ok := c.Service.Get("http_server")
-	if ok && (httpServerEnabled == true || strings.ToLower(stringFromAny(httpServerEnabled)) == "on") {
-		portVal, ok := c.Service.Get("http_port")
-		if !ok {
+	if enabled, _ := c.Service.GetBool("http_server"); enabled {
+		if !c.Service.Has("http_port") {
 			out = append(out, ServicePort{
 				Port:     2020,
 				Protocol: networking.ProtocolTCP,
 				Kind:     SectionKindService,
 			})
-		} else if i, ok := int32FromAny(portVal); ok {
+		} else if i, ok := portFrom(c.Service, "http_port"); ok {
 			out = append(out, ServicePort{
 				Port:     i,
 				Protocol: networking.ProtocolTCP,
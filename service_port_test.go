@@ -82,7 +82,7 @@ func TestConfig_ServicePorts(t *testing.T) {
 			expected(5170, networking.ProtocolTCP, SectionKindInput, "tcp", 12),
 			expected(5170, networking.ProtocolUDP, SectionKindInput, "udp", 13),
 			expected(2021, networking.ProtocolTCP, SectionKindOutput, "prometheus_exporter", 0),
-		}, config.ServicePorts())
+		}, withoutPositions(config.ServicePorts()))
 	})
 
 	t.Run("explicit", func(t *testing.T) {
@@ -160,7 +160,7 @@ func TestConfig_ServicePorts(t *testing.T) {
 			expected(15, networking.ProtocolTCP, SectionKindInput, "tcp", 13, property.Property{Key: "port", Value: int64(15)}),
 			expected(16, networking.ProtocolUDP, SectionKindInput, "udp", 14, property.Property{Key: "port", Value: int64(16)}),
 			expected(17, networking.ProtocolTCP, SectionKindOutput, "prometheus_exporter", 0, property.Property{Key: "port", Value: int64(17)}),
-		}, config.ServicePorts())
+		}, withoutPositions(config.ServicePorts()))
 	})
 
 	t.Run("skips", func(t *testing.T) {
@@ -187,4 +187,19 @@ func TestConfig_ServicePorts(t *testing.T) {
 		require.NoError(t, err)
 		require.Nil(t, config.ServicePorts())
 	})
+
+	t.Run("http_server", func(t *testing.T) {
+		config, err := ParseAs(`
+			[SERVICE]
+				http_server yes
+				http_port   2021
+		`, FormatClassic)
+		require.NoError(t, err)
+		require.Equal(t, ServicePorts{
+			{Port: 2021, Protocol: networking.ProtocolTCP, Kind: SectionKindService},
+		}, withoutPositions(config.ServicePorts()))
+
+		config.Service.Set("http_server", "off")
+		require.Nil(t, config.ServicePorts())
+	})
 }
@@ -0,0 +1,750 @@
+package fluentbitconfig
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+// StreamQueryKind is the kind of a stream processor statement.
+type StreamQueryKind string
+
+const (
+	StreamQuerySelect         StreamQueryKind = "select"
+	StreamQueryCreateStream   StreamQueryKind = "create_stream"
+	StreamQueryCreateSnapshot StreamQueryKind = "create_snapshot"
+	StreamQueryFlushSnapshot  StreamQueryKind = "flush_snapshot"
+)
+
+// StreamSourceKind tells where a query reads records from.
+type StreamSourceKind string
+
+const (
+	// StreamSourceStream reads the records of an input instance,
+	// referenced by its alias or name. For example: STREAM:cpu.0
+	StreamSourceStream StreamSourceKind = "stream"
+	// StreamSourceTag reads the records whose tag matches a pattern.
+	// For example: TAG:'apache.*'
+	StreamSourceTag StreamSourceKind = "tag"
+)
+
+// StreamQuery is a parsed stream processor SQL statement.
+// See https://docs.fluentbit.io/manual/stream-processing/getting-started/fluent-bit-sql
+type StreamQuery struct {
+	Kind StreamQueryKind
+	// Name of the stream or snapshot created or flushed.
+	Name string
+	// Properties from the `WITH (key='value', ...)` clause.
+	Properties property.Properties
+	// Keys selected, empty for `SELECT *`.
+	Keys    []StreamKey
+	Source  StreamSource
+	Window  *StreamWindow
+	Where   string
+	GroupBy []StreamKey
+	Limit   int
+}
+
+type StreamSource struct {
+	Kind StreamSourceKind
+	Name string
+}
+
+// StreamKey selected or grouped by.
+type StreamKey struct {
+	// Name of the record key, empty for functions without arguments.
+	Name string
+	// Subkeys of maps. For example: key['a']['b']
+	Subkeys []string
+	// Func is the aggregation or function applied to the key
+	// in upper case. For example: AVG.
+	Func  string
+	Alias string
+}
+
+// StreamWindow of aggregation queries.
+type StreamWindow struct {
+	// Type is either TUMBLING or HOPPING.
+	Type    string
+	Size    time.Duration
+	Advance time.Duration
+}
+
+// ReadTag returns the tag pattern the query reads from,
+// if it reads by tag instead of from an input stream.
+func (q StreamQuery) ReadTag() (string, bool) {
+	if q.Source.Kind != StreamSourceTag {
+		return "", false
+	}
+
+	return q.Source.Name, true
+}
+
+// EmittedTag returns the tag the results of the query
+// are ingested back into the pipeline with.
+// Only `CREATE STREAM` queries emit records, tagged with
+// their `tag` property, or the stream name when not set.
+func (q StreamQuery) EmittedTag() (string, bool) {
+	if q.Kind != StreamQueryCreateStream {
+		return "", false
+	}
+
+	if v, ok := q.Properties.Get("tag"); ok {
+		return stringFromAny(v), true
+	}
+
+	return q.Name, true
+}
+
+// SQLSyntaxError of a stream processor statement.
+// The position is relative to the statement.
+type SQLSyntaxError struct {
+	Pos property.Position
+	Msg string
+}
+
+func (e *SQLSyntaxError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// ParseStreamQuery parses a statement from the fluent-bit
+// stream processor SQL dialect.
+func ParseStreamQuery(sql string) (StreamQuery, error) {
+	tokens, err := lexStreamSQL(sql)
+	if err != nil {
+		return StreamQuery{}, err
+	}
+
+	p := &streamSQLParser{sql: sql, tokens: tokens}
+	return p.parse()
+}
+
+type sqlTokenKind int
+
+const (
+	sqlTokenEOF sqlTokenKind = iota
+	sqlTokenIdent
+	sqlTokenString
+	sqlTokenNumber
+	sqlTokenSymbol
+)
+
+type sqlToken struct {
+	Kind   sqlTokenKind
+	Value  string
+	Offset int
+}
+
+func (t sqlToken) String() string {
+	switch t.Kind {
+	case sqlTokenEOF:
+		return "end of statement"
+	case sqlTokenString:
+		return "'" + t.Value + "'"
+	}
+	return strconv.Quote(t.Value)
+}
+
+func lexStreamSQL(sql string) ([]sqlToken, error) {
+	var out []sqlToken
+
+	isIdentStart := func(c byte) bool {
+		return c == '_' || c == '@' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+	}
+	isIdent := func(c byte) bool {
+		return isIdentStart(c) || c == '.' || c == '-' || (c >= '0' && c <= '9')
+	}
+	isDigit := func(c byte) bool {
+		return c >= '0' && c <= '9'
+	}
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isIdentStart(c):
+			start := i
+			for i < len(sql) && isIdent(sql[i]) {
+				i++
+			}
+			out = append(out, sqlToken{Kind: sqlTokenIdent, Value: sql[start:i], Offset: start})
+		case isDigit(c) || (c == '-' && i+1 < len(sql) && isDigit(sql[i+1])):
+			start := i
+			i++
+			for i < len(sql) && (isDigit(sql[i]) || sql[i] == '.') {
+				i++
+			}
+			out = append(out, sqlToken{Kind: sqlTokenNumber, Value: sql[start:i], Offset: start})
+		case c == '\'' || c == '"':
+			start := i
+			var sb strings.Builder
+			i++
+			for {
+				if i >= len(sql) {
+					return nil, sqlSyntaxError(sql, start, "unterminated string")
+				}
+				if sql[i] == c {
+					// Quotes are escaped by doubling them.
+					if i+1 < len(sql) && sql[i+1] == c {
+						sb.WriteByte(c)
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				sb.WriteByte(sql[i])
+				i++
+			}
+			out = append(out, sqlToken{Kind: sqlTokenString, Value: sb.String(), Offset: start})
+		default:
+			start := i
+			for _, sym := range [...]string{"!=", "<>", "<=", ">=", "(", ")", ",", ";", "*", "=", "<", ">", ":", "[", "]"} {
+				if strings.HasPrefix(sql[i:], sym) {
+					out = append(out, sqlToken{Kind: sqlTokenSymbol, Value: sym, Offset: start})
+					i += len(sym)
+					break
+				}
+			}
+			if i == start {
+				return nil, sqlSyntaxError(sql, start, fmt.Sprintf("unexpected character %q", c))
+			}
+		}
+	}
+
+	return append(out, sqlToken{Kind: sqlTokenEOF, Offset: len(sql)}), nil
+}
+
+func sqlSyntaxError(sql string, offset int, msg string) *SQLSyntaxError {
+	line, col := 1, 1
+	for _, c := range sql[:offset] {
+		if c == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+
+	return &SQLSyntaxError{Pos: property.Position{Line: line, Column: col}, Msg: msg}
+}
+
+var (
+	streamSQLAggregates = []string{"AVG", "SUM", "COUNT", "MIN", "MAX"}
+	streamSQLFunctions  = []string{"RECORD_TAG", "RECORD_TIME", "NOW", "UNIX_TIMESTAMP"}
+	streamSQLTimeUnits  = map[string]time.Duration{
+		"SECOND": time.Second,
+		"MINUTE": time.Minute,
+		"HOUR":   time.Hour,
+	}
+)
+
+type streamSQLParser struct {
+	sql    string
+	tokens []sqlToken
+	pos    int
+}
+
+func (p *streamSQLParser) peek() sqlToken {
+	return p.tokens[p.pos]
+}
+
+func (p *streamSQLParser) next() sqlToken {
+	t := p.tokens[p.pos]
+	if t.Kind != sqlTokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *streamSQLParser) errorf(t sqlToken, format string, args ...any) error {
+	return sqlSyntaxError(p.sql, t.Offset, fmt.Sprintf(format, args...))
+}
+
+// isKeyword tells whether the next token is the given keyword.
+func (p *streamSQLParser) isKeyword(kw string) bool {
+	t := p.peek()
+	return t.Kind == sqlTokenIdent && strings.EqualFold(t.Value, kw)
+}
+
+func (p *streamSQLParser) isSymbol(sym string) bool {
+	t := p.peek()
+	return t.Kind == sqlTokenSymbol && t.Value == sym
+}
+
+func (p *streamSQLParser) keyword(kw string) error {
+	if !p.isKeyword(kw) {
+		return p.errorf(p.peek(), "expected %s, got %s", kw, p.peek())
+	}
+	p.next()
+	return nil
+}
+
+func (p *streamSQLParser) symbol(sym string) error {
+	if !p.isSymbol(sym) {
+		return p.errorf(p.peek(), "expected %q, got %s", sym, p.peek())
+	}
+	p.next()
+	return nil
+}
+
+func (p *streamSQLParser) ident() (string, error) {
+	t := p.peek()
+	if t.Kind != sqlTokenIdent {
+		return "", p.errorf(t, "expected identifier, got %s", t)
+	}
+	p.next()
+	return t.Value, nil
+}
+
+func (p *streamSQLParser) integer() (int, error) {
+	t := p.peek()
+	n, err := strconv.Atoi(t.Value)
+	if t.Kind != sqlTokenNumber || err != nil {
+		return 0, p.errorf(t, "expected integer, got %s", t)
+	}
+	p.next()
+	return n, nil
+}
+
+func (p *streamSQLParser) parse() (StreamQuery, error) {
+	var q StreamQuery
+
+	switch {
+	case p.isKeyword("CREATE"):
+		p.next()
+		switch {
+		case p.isKeyword("STREAM"):
+			q.Kind = StreamQueryCreateStream
+		case p.isKeyword("SNAPSHOT"):
+			q.Kind = StreamQueryCreateSnapshot
+		default:
+			return q, p.errorf(p.peek(), "expected STREAM or SNAPSHOT, got %s", p.peek())
+		}
+		p.next()
+
+		name, err := p.ident()
+		if err != nil {
+			return q, err
+		}
+		q.Name = name
+
+		if p.isKeyword("WITH") {
+			p.next()
+			if q.Properties, err = p.properties(); err != nil {
+				return q, err
+			}
+		}
+
+		if err := p.keyword("AS"); err != nil {
+			return q, err
+		}
+	case p.isKeyword("FLUSH"):
+		p.next()
+		q.Kind = StreamQueryFlushSnapshot
+		if err := p.keyword("SNAPSHOT"); err != nil {
+			return q, err
+		}
+
+		name, err := p.ident()
+		if err != nil {
+			return q, err
+		}
+		q.Name = name
+
+		if err := p.keyword("AS"); err != nil {
+			return q, err
+		}
+	default:
+		q.Kind = StreamQuerySelect
+	}
+
+	if err := p.selectStatement(&q); err != nil {
+		return q, err
+	}
+
+	if t := p.peek(); t.Kind != sqlTokenEOF {
+		return q, p.errorf(t, "unexpected %s after end of statement", t)
+	}
+
+	return q, nil
+}
+
+func (p *streamSQLParser) properties() (property.Properties, error) {
+	if err := p.symbol("("); err != nil {
+		return nil, err
+	}
+
+	var props property.Properties
+	for {
+		key, err := p.ident()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.symbol("="); err != nil {
+			return nil, err
+		}
+
+		t := p.next()
+		if t.Kind != sqlTokenString {
+			return nil, p.errorf(t, "expected quoted value for property %q, got %s", key, t)
+		}
+		props = append(props, property.Property{Key: key, Value: t.Value})
+
+		if !p.isSymbol(",") {
+			break
+		}
+		p.next()
+	}
+
+	return props, p.symbol(")")
+}
+
+func (p *streamSQLParser) selectStatement(q *StreamQuery) error {
+	if err := p.keyword("SELECT"); err != nil {
+		return err
+	}
+
+	if p.isSymbol("*") {
+		p.next()
+	} else {
+		keys, err := p.keys(true /* selecting */)
+		if err != nil {
+			return err
+		}
+		q.Keys = keys
+	}
+
+	if err := p.keyword("FROM"); err != nil {
+		return err
+	}
+
+	if err := p.source(q); err != nil {
+		return err
+	}
+
+	if p.isKeyword("WINDOW") {
+		p.next()
+		window, err := p.window()
+		if err != nil {
+			return err
+		}
+		q.Window = window
+	}
+
+	if p.isKeyword("WHERE") {
+		p.next()
+		start := p.peek().Offset
+		if err := p.condition(); err != nil {
+			return err
+		}
+		q.Where = strings.TrimSpace(p.sql[start:p.peek().Offset])
+	}
+
+	if p.isKeyword("GROUP") {
+		p.next()
+		if err := p.keyword("BY"); err != nil {
+			return err
+		}
+
+		keys, err := p.keys(false /* selecting */)
+		if err != nil {
+			return err
+		}
+		q.GroupBy = keys
+	}
+
+	if p.isKeyword("LIMIT") {
+		p.next()
+		limit, err := p.integer()
+		if err != nil {
+			return err
+		}
+		q.Limit = limit
+	}
+
+	return p.symbol(";")
+}
+
+func (p *streamSQLParser) source(q *StreamQuery) error {
+	t := p.peek()
+	switch {
+	case p.isKeyword("STREAM"):
+		p.next()
+		if err := p.symbol(":"); err != nil {
+			return err
+		}
+
+		name, err := p.ident()
+		if err != nil {
+			return err
+		}
+
+		q.Source = StreamSource{Kind: StreamSourceStream, Name: name}
+	case p.isKeyword("TAG"):
+		p.next()
+		if err := p.symbol(":"); err != nil {
+			return err
+		}
+
+		t := p.next()
+		if t.Kind != sqlTokenString {
+			return p.errorf(t, "expected quoted tag, got %s", t)
+		}
+
+		q.Source = StreamSource{Kind: StreamSourceTag, Name: t.Value}
+	default:
+		return p.errorf(t, "expected STREAM or TAG source, got %s", t)
+	}
+
+	return nil
+}
+
+func (p *streamSQLParser) keys(selecting bool) ([]StreamKey, error) {
+	var keys []StreamKey
+	for {
+		key, err := p.key(selecting)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+		if !p.isSymbol(",") {
+			return keys, nil
+		}
+		p.next()
+	}
+}
+
+func (p *streamSQLParser) key(selecting bool) (StreamKey, error) {
+	var key StreamKey
+
+	t := p.peek()
+	if t.Kind != sqlTokenIdent {
+		return key, p.errorf(t, "expected key, got %s", t)
+	}
+
+	fn := strings.ToUpper(t.Value)
+	isFunc := p.tokens[p.pos+1].Kind == sqlTokenSymbol && p.tokens[p.pos+1].Value == "("
+
+	switch {
+	case selecting && isFunc && slices.Contains(streamSQLAggregates, fn):
+		p.next()
+		p.next()
+		key.Func = fn
+		if p.isSymbol("*") && fn == "COUNT" {
+			p.next()
+		} else {
+			var err error
+			if key.Name, key.Subkeys, err = p.recordKey(); err != nil {
+				return key, err
+			}
+		}
+		if err := p.symbol(")"); err != nil {
+			return key, err
+		}
+	case selecting && isFunc && fn == "TIMESERIES_FORECAST":
+		p.next()
+		p.next()
+		key.Func = fn
+		var err error
+		if key.Name, key.Subkeys, err = p.recordKey(); err != nil {
+			return key, err
+		}
+		if err := p.symbol(","); err != nil {
+			return key, err
+		}
+		if _, err := p.integer(); err != nil {
+			return key, err
+		}
+		if err := p.symbol(")"); err != nil {
+			return key, err
+		}
+	case selecting && isFunc && slices.Contains(streamSQLFunctions, fn):
+		p.next()
+		p.next()
+		key.Func = fn
+		if err := p.symbol(")"); err != nil {
+			return key, err
+		}
+	case isFunc:
+		return key, p.errorf(t, "unknown function %s", t)
+	default:
+		var err error
+		if key.Name, key.Subkeys, err = p.recordKey(); err != nil {
+			return key, err
+		}
+	}
+
+	if selecting && p.isKeyword("AS") {
+		p.next()
+		alias, err := p.ident()
+		if err != nil {
+			return key, err
+		}
+		key.Alias = alias
+	}
+
+	return key, nil
+}
+
+// recordKey parses a key with optional map subkeys.
+// For example: key['a']['b']
+func (p *streamSQLParser) recordKey() (string, []string, error) {
+	name, err := p.ident()
+	if err != nil {
+		return "", nil, err
+	}
+
+	var subkeys []string
+	for p.isSymbol("[") {
+		p.next()
+		t := p.next()
+		if t.Kind != sqlTokenString {
+			return "", nil, p.errorf(t, "expected quoted subkey, got %s", t)
+		}
+		subkeys = append(subkeys, t.Value)
+
+		if err := p.symbol("]"); err != nil {
+			return "", nil, err
+		}
+	}
+
+	return name, subkeys, nil
+}
+
+func (p *streamSQLParser) window() (*StreamWindow, error) {
+	var window StreamWindow
+	switch {
+	case p.isKeyword("TUMBLING"):
+		window.Type = "TUMBLING"
+	case p.isKeyword("HOPPING"):
+		window.Type = "HOPPING"
+	default:
+		return nil, p.errorf(p.peek(), "expected TUMBLING or HOPPING window, got %s", p.peek())
+	}
+	p.next()
+
+	if err := p.symbol("("); err != nil {
+		return nil, err
+	}
+
+	size, err := p.duration()
+	if err != nil {
+		return nil, err
+	}
+	window.Size = size
+
+	if window.Type == "HOPPING" {
+		if err := p.symbol(","); err != nil {
+			return nil, err
+		}
+		if err := p.keyword("ADVANCE"); err != nil {
+			return nil, err
+		}
+		if err := p.keyword("BY"); err != nil {
+			return nil, err
+		}
+
+		advance, err := p.duration()
+		if err != nil {
+			return nil, err
+		}
+		window.Advance = advance
+	}
+
+	if err := p.symbol(")"); err != nil {
+		return nil, err
+	}
+
+	return &window, nil
+}
+
+func (p *streamSQLParser) duration() (time.Duration, error) {
+	n, err := p.integer()
+	if err != nil {
+		return 0, err
+	}
+
+	t := p.peek()
+	unit, ok := streamSQLTimeUnits[strings.ToUpper(t.Value)]
+	if t.Kind != sqlTokenIdent || !ok {
+		return 0, p.errorf(t, "expected SECOND, MINUTE or HOUR, got %s", t)
+	}
+	p.next()
+
+	return time.Duration(n) * unit, nil
+}
+
+// condition parses a WHERE condition:
+// comparisons joined by AND, OR and NOT, grouped by parenthesis.
+func (p *streamSQLParser) condition() error {
+	for {
+		for p.isKeyword("NOT") {
+			p.next()
+		}
+
+		if err := p.comparison(); err != nil {
+			return err
+		}
+
+		if !p.isKeyword("AND") && !p.isKeyword("OR") {
+			return nil
+		}
+		p.next()
+	}
+}
+
+func (p *streamSQLParser) comparison() error {
+	if p.isSymbol("(") {
+		p.next()
+		if err := p.condition(); err != nil {
+			return err
+		}
+		return p.symbol(")")
+	}
+
+	if p.isKeyword("@record.contains") {
+		p.next()
+		if err := p.symbol("("); err != nil {
+			return err
+		}
+		if _, _, err := p.recordKey(); err != nil {
+			return err
+		}
+		return p.symbol(")")
+	}
+
+	if _, _, err := p.recordKey(); err != nil {
+		return err
+	}
+
+	if p.isKeyword("IS") {
+		p.next()
+		if p.isKeyword("NOT") {
+			p.next()
+		}
+		return p.keyword("NULL")
+	}
+
+	t := p.next()
+	if t.Kind != sqlTokenSymbol || !slices.Contains([]string{"=", "!=", "<>", "<", "<=", ">", ">="}, t.Value) {
+		return p.errorf(t, "expected comparison operator, got %s", t)
+	}
+
+	t = p.next()
+	switch {
+	case t.Kind == sqlTokenNumber, t.Kind == sqlTokenString:
+	case t.Kind == sqlTokenIdent && slices.Contains([]string{"TRUE", "FALSE", "NULL"}, strings.ToUpper(t.Value)):
+	default:
+		return p.errorf(t, "expected value, got %s", t)
+	}
+
+	return nil
+}
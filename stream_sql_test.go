@@ -0,0 +1,152 @@
+package fluentbitconfig
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+func TestParseStreamQuery(t *testing.T) {
+	tt := []struct {
+		name string
+		sql  string
+		want StreamQuery
+	}{
+		{
+			name: "select_all_from_stream",
+			sql:  "SELECT * FROM STREAM:cpu.local;",
+			want: StreamQuery{
+				Kind:   StreamQuerySelect,
+				Source: StreamSource{Kind: StreamSourceStream, Name: "cpu.local"},
+			},
+		},
+		{
+			name: "create_stream_window",
+			sql:  "CREATE STREAM results WITH (tag='results', routable='true') AS SELECT AVG(cpu_p) AS avg, COUNT(*) FROM TAG:'cpu.*' WINDOW TUMBLING (5 SECOND);",
+			want: StreamQuery{
+				Kind: StreamQueryCreateStream,
+				Name: "results",
+				Properties: property.Properties{
+					{Key: "tag", Value: "results"},
+					{Key: "routable", Value: "true"},
+				},
+				Keys: []StreamKey{
+					{Name: "cpu_p", Func: "AVG", Alias: "avg"},
+					{Func: "COUNT"},
+				},
+				Source: StreamSource{Kind: StreamSourceTag, Name: "cpu.*"},
+				Window: &StreamWindow{Type: "TUMBLING", Size: 5 * time.Second},
+			},
+		},
+		{
+			name: "hopping_where_group_by",
+			sql: `select method, record_tag(), sum(size) from stream:apache
+				window hopping (1 minute, advance by 10 second)
+				where (method = 'POST' or method != 'GET') and not size is null and @record.contains(req['id'])
+				group by method;`,
+			want: StreamQuery{
+				Kind: StreamQuerySelect,
+				Keys: []StreamKey{
+					{Name: "method"},
+					{Func: "RECORD_TAG"},
+					{Name: "size", Func: "SUM"},
+				},
+				Source:  StreamSource{Kind: StreamSourceStream, Name: "apache"},
+				Window:  &StreamWindow{Type: "HOPPING", Size: time.Minute, Advance: 10 * time.Second},
+				Where:   "(method = 'POST' or method != 'GET') and not size is null and @record.contains(req['id'])",
+				GroupBy: []StreamKey{{Name: "method"}},
+			},
+		},
+		{
+			name: "snapshot",
+			sql:  "CREATE SNAPSHOT last WITH (tag='snap') AS SELECT * FROM TAG:'app.*' LIMIT 10;",
+			want: StreamQuery{
+				Kind:       StreamQueryCreateSnapshot,
+				Name:       "last",
+				Properties: property.Properties{{Key: "tag", Value: "snap"}},
+				Source:     StreamSource{Kind: StreamSourceTag, Name: "app.*"},
+				Limit:      10,
+			},
+		},
+		{
+			name: "flush_snapshot",
+			sql:  "FLUSH SNAPSHOT last AS SELECT * FROM STREAM:last WHERE level = 'error';",
+			want: StreamQuery{
+				Kind:   StreamQueryFlushSnapshot,
+				Name:   "last",
+				Source: StreamSource{Kind: StreamSourceStream, Name: "last"},
+				Where:  "level = 'error'",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseStreamQuery(tc.sql)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestParseStreamQuery_errors(t *testing.T) {
+	tt := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{name: "empty", sql: "", want: "1:1: expected SELECT, got end of statement"},
+		{name: "missing_from", sql: "SELECT * FORM STREAM:cpu;", want: `1:10: expected FROM, got "FORM"`},
+		{name: "missing_semicolon", sql: "SELECT * FROM STREAM:cpu", want: `1:25: expected ";", got end of statement`},
+		{name: "bad_source", sql: "SELECT * FROM cpu;", want: `1:15: expected STREAM or TAG source, got "cpu"`},
+		{name: "unquoted_tag", sql: "SELECT * FROM TAG:cpu;", want: `1:19: expected quoted tag, got "cpu"`},
+		{name: "unknown_function", sql: "SELECT FOO(x) FROM TAG:'cpu';", want: `1:8: unknown function "FOO"`},
+		{name: "bad_window_unit", sql: "SELECT * FROM TAG:'cpu'\nWINDOW TUMBLING (5 DAY);", want: `2:20: expected SECOND, MINUTE or HOUR, got "DAY"`},
+		{name: "unterminated_string", sql: "SELECT * FROM TAG:'cpu;", want: "1:19: unterminated string"},
+		{name: "trailing", sql: "SELECT * FROM TAG:'cpu'; SELECT", want: `1:26: unexpected "SELECT" after end of statement`},
+		{name: "bad_property", sql: "CREATE STREAM x WITH (tag=x) AS SELECT * FROM TAG:'cpu';", want: `1:27: expected quoted value for property "tag", got "x"`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseStreamQuery(tc.sql)
+			require.EqualError(t, err, tc.want)
+
+			var syntaxErr *SQLSyntaxError
+			require.True(t, errors.As(err, &syntaxErr))
+		})
+	}
+}
+
+func TestStreamQuery_tags(t *testing.T) {
+	q, err := ParseStreamQuery("CREATE STREAM results WITH (tag='out.results') AS SELECT * FROM TAG:'cpu.*';")
+	require.NoError(t, err)
+
+	tag, ok := q.ReadTag()
+	require.True(t, ok)
+	require.Equal(t, "cpu.*", tag)
+
+	tag, ok = q.EmittedTag()
+	require.True(t, ok)
+	require.Equal(t, "out.results", tag)
+
+	q, err = ParseStreamQuery("CREATE STREAM results AS SELECT * FROM STREAM:cpu.0;")
+	require.NoError(t, err)
+
+	_, ok = q.ReadTag()
+	require.False(t, ok)
+
+	tag, ok = q.EmittedTag()
+	require.True(t, ok)
+	require.Equal(t, "results", tag)
+
+	q, err = ParseStreamQuery("SELECT * FROM STREAM:cpu.0;")
+	require.NoError(t, err)
+
+	_, ok = q.EmittedTag()
+	require.False(t, ok)
+}
@@ -0,0 +1,81 @@
+package fluentbitconfig
+
+import (
+	"fmt"
+
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+type StreamTasks []StreamTask
+
+func (tasks StreamTasks) Equal(target StreamTasks) bool {
+	return slices.EqualFunc(tasks, target, func(a, b StreamTask) bool {
+		return a.Equal(b)
+	})
+}
+
+// Validate that tasks have unique names
+// and valid SQL statements.
+func (tasks StreamTasks) Validate() error {
+	seen := map[string]bool{}
+	for _, task := range tasks {
+		if task.Name == "" {
+			return withPos(fmt.Errorf("%s: %w", SectionKindStreamTask, ErrMissingName), task.Pos)
+		}
+
+		if seen[task.Name] {
+			return withPos(fmt.Errorf("%s: duplicated name %q", SectionKindStreamTask, task.Name), task.Pos)
+		}
+		seen[task.Name] = true
+
+		if _, err := task.Query(); err != nil {
+			return withPos(fmt.Errorf("%s: %s: %w", SectionKindStreamTask, task.Name, err), task.Pos)
+		}
+	}
+
+	return nil
+}
+
+// StreamTask of the stream processor.
+// On classic format it is written as a `[STREAM_TASK]` section.
+type StreamTask struct {
+	Name string `json:"name" yaml:"name"`
+	// Exec is the stream processor SQL statement.
+	Exec string `json:"exec" yaml:"exec"`
+	// Pos where the task section starts when parsed.
+	Pos property.Position `json:"-" yaml:"-"`
+}
+
+// streamTaskFields is used to decode tasks without recursion.
+type streamTaskFields StreamTask
+
+func (t *StreamTask) UnmarshalYAML(node *yaml.Node) error {
+	var dest streamTaskFields
+	if err := node.Decode(&dest); err != nil {
+		return err
+	}
+
+	*t = StreamTask(dest)
+	t.Pos = property.Position{Line: node.Line, Column: node.Column}
+	return nil
+}
+
+// Query parses the task SQL statement.
+func (t StreamTask) Query() (StreamQuery, error) {
+	return ParseStreamQuery(t.Exec)
+}
+
+func (t StreamTask) Equal(target StreamTask) bool {
+	return t.Name == target.Name && t.Exec == target.Exec
+}
+
+// classicProperties of the `[STREAM_TASK]` section.
+func (t StreamTask) classicProperties() property.Properties {
+	return property.Properties{
+		{Key: "Name", Value: t.Name},
+		{Key: "Exec", Value: t.Exec},
+	}
+}
@@ -0,0 +1,97 @@
+package fluentbitconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+func TestConfig_StreamTasks(t *testing.T) {
+	cfg, err := ParseAs(configLiteral(`
+		[STREAM_TASK]
+			Name results
+			Exec SELECT * FROM TAG:'cpu.*';
+	`), FormatClassic)
+	require.NoError(t, err)
+	require.Equal(t, StreamTasks{{
+		Name: "results",
+		Exec: "SELECT * FROM TAG:'cpu.*';",
+		Pos:  property.Position{Line: 1, Column: 1},
+	}}, cfg.StreamTasks)
+}
+
+func TestStreamTasks_Validate(t *testing.T) {
+	cfg, err := ParseAs(configLiteral(`
+		[STREAM_TASK]
+			Name ok
+			Exec SELECT * FROM TAG:'cpu.*';
+		[STREAM_TASK]
+			Name broken
+			Exec SELECT * FROM cpu;
+	`), FormatClassic)
+	require.NoError(t, err)
+
+	err = cfg.Validate()
+	require.EqualError(t, err, `4:1: stream_task: broken: 1:15: expected STREAM or TAG source, got "cpu"`)
+
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	require.Equal(t, property.Position{Line: 4, Column: 1}, verr.Pos)
+
+	var syntaxErr *SQLSyntaxError
+	require.ErrorAs(t, err, &syntaxErr)
+	require.Equal(t, property.Position{Line: 1, Column: 15}, syntaxErr.Pos)
+
+	cfg.StreamTasks[1] = StreamTask{Name: "ok", Exec: "SELECT * FROM STREAM:cpu.0;"}
+	require.EqualError(t, cfg.Validate(), `stream_task: duplicated name "ok"`)
+}
+
+func TestClassicDocument_SetConfig_topLevelSections(t *testing.T) {
+	const text = `[SERVICE]
+    Flush 1
+
+[INPUT]
+    Name cpu
+`
+
+	doc, err := ParseClassicDocument([]byte(text))
+	require.NoError(t, err)
+
+	cfg := doc.Config()
+	cfg.ExternalPlugins = []string{"/opt/plugins/out_gstdout.so"}
+	cfg.StreamTasks = StreamTasks{{Name: "results", Exec: "SELECT * FROM STREAM:cpu.0;"}}
+	require.NoError(t, doc.SetConfig(cfg))
+	require.Equal(t, `[SERVICE]
+    Flush 1
+
+[PLUGINS]
+    Path /opt/plugins/out_gstdout.so
+
+[INPUT]
+    Name cpu
+
+[STREAM_TASK]
+    Name results
+    Exec SELECT * FROM STREAM:cpu.0;
+`, doc.String())
+
+	cfg.ExternalPlugins = append(cfg.ExternalPlugins, "/opt/plugins/in_gdummy.so")
+	cfg.StreamTasks[0].Exec = "SELECT * FROM STREAM:cpu.1;"
+	require.NoError(t, doc.SetConfig(cfg))
+	require.Equal(t, `[SERVICE]
+    Flush 1
+
+[PLUGINS]
+    Path /opt/plugins/out_gstdout.so
+    Path /opt/plugins/in_gdummy.so
+
+[INPUT]
+    Name cpu
+
+[STREAM_TASK]
+    Name results
+    Exec SELECT * FROM STREAM:cpu.1;
+`, doc.String())
+}
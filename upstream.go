@@ -0,0 +1,164 @@
+package fluentbitconfig
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+type Upstreams []Upstream
+
+func (upstreams Upstreams) Equal(target Upstreams) bool {
+	return slices.EqualFunc(upstreams, target, func(a, b Upstream) bool {
+		return a.Equal(b)
+	})
+}
+
+// FindByName returns the upstream with the given name.
+func (upstreams Upstreams) FindByName(name string) (Upstream, bool) {
+	for _, upstream := range upstreams {
+		if upstream.Name == name {
+			return upstream, true
+		}
+	}
+	return Upstream{}, false
+}
+
+// Validate that upstreams have unique names and at least one node,
+// and that all nodes are named.
+func (upstreams Upstreams) Validate() error {
+	seen := map[string]bool{}
+	for _, upstream := range upstreams {
+		if upstream.Name == "" {
+			return withPos(errors.New("upstream: missing name property"), upstream.Pos)
+		}
+
+		if seen[upstream.Name] {
+			return withPos(fmt.Errorf("upstream: duplicated name %q", upstream.Name), upstream.Pos)
+		}
+		seen[upstream.Name] = true
+
+		if len(upstream.Nodes) == 0 {
+			return withPos(fmt.Errorf("upstream: %s: no nodes defined", upstream.Name), upstream.Pos)
+		}
+
+		for _, node := range upstream.Nodes {
+			if node.Name == "" {
+				return withPos(fmt.Errorf("upstream: %s: node: %w", upstream.Name, ErrMissingName), node.Pos)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Upstream servers used by outputs with high-availability.
+// On classic format it is written as an `[UPSTREAM]` section
+// followed by one `[NODE]` section per node.
+type Upstream struct {
+	Name string `json:"name" yaml:"name"`
+	// Pos where the upstream section starts when parsed.
+	Pos   property.Position `json:"-" yaml:"-"`
+	Nodes Plugins           `json:"nodes,omitempty" yaml:"nodes,omitempty"`
+}
+
+// upstreamFields is used to decode upstreams without recursion.
+type upstreamFields Upstream
+
+func (u *Upstream) UnmarshalYAML(node *yaml.Node) error {
+	var dest upstreamFields
+	if err := node.Decode(&dest); err != nil {
+		return err
+	}
+
+	*u = Upstream(dest)
+	u.Pos = property.Position{Line: node.Line, Column: node.Column}
+	return nil
+}
+
+func (u Upstream) Equal(target Upstream) bool {
+	return u.Name == target.Name && u.Nodes.Equal(target.Nodes)
+}
+
+// upstreamName from the upstream section properties.
+// Unlike plugin names, its case is kept
+// as outputs reference upstreams by name.
+func upstreamName(props property.Properties) string {
+	v, _ := props.Get("name")
+	return strings.TrimSpace(stringFromAny(v))
+}
+
+// upstreamFileRef tells whether the `upstream` property of the given output
+// references an upstream file instead of a defined upstream.
+// Classic configs always reference files while YAML configs
+// reference upstream_servers by name; when the source is unknown,
+// values are names only if the config defines upstreams at all.
+func (c Config) upstreamFileRef(output Plugin, ref string) bool {
+	if _, ok := c.Upstreams.FindByName(ref); ok {
+		return false
+	}
+
+	if isUpstreamFile(ref) {
+		return true
+	}
+
+	if output.Pos.File != "" {
+		return formatFromPath(output.Pos.File) == FormatClassic
+	}
+
+	return len(c.Upstreams) == 0
+}
+
+// isUpstreamFile tells whether the `upstream` property of an output
+// looks like a file path.
+func isUpstreamFile(s string) bool {
+	if strings.ContainsAny(s, `/\`) {
+		return true
+	}
+
+	switch strings.ToLower(path.Ext(s)) {
+	case ".conf", ".yaml", ".yml", ".json":
+		return true
+	}
+
+	return false
+}
+
+// addNode appends a node with its positional ID.
+func (u *Upstream) addNode(props property.Properties, pos property.Position) {
+	name := Name(props)
+	if name == "" {
+		return
+	}
+
+	u.Nodes = append(u.Nodes, Plugin{
+		ID:         fmt.Sprintf("%s.%d", name, len(u.Nodes)),
+		Name:       name,
+		Pos:        pos,
+		Properties: props,
+	})
+}
+
+// classicSections returns the upstream and its nodes
+// as the properties of their classic sections.
+func (u Upstream) classicSections() ([]SectionKind, []property.Properties, error) {
+	kinds := []SectionKind{SectionKindUpstream}
+	sections := []property.Properties{{{Key: "Name", Value: u.Name}}}
+	for _, node := range u.Nodes {
+		props, err := allPluginProperties(node, true /* classic */)
+		if err != nil {
+			return nil, nil, fmt.Errorf("upstream %q: node %q: %w", u.Name, node.ID, err)
+		}
+
+		kinds = append(kinds, SectionKindNode)
+		sections = append(sections, props)
+	}
+
+	return kinds, sections, nil
+}
@@ -0,0 +1,179 @@
+package fluentbitconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
+)
+
+func TestConfig_Upstreams(t *testing.T) {
+	t.Run("classic", func(t *testing.T) {
+		cfg, err := ParseAs(configLiteral(`
+			[UPSTREAM]
+				name Balancing
+			[NODE]
+				name node-1
+				host 127.0.0.1
+			[NODE]
+				name node-1
+				host 127.0.0.2
+		`), FormatClassic)
+		require.NoError(t, err)
+		require.Equal(t, Upstreams{{
+			Name: "Balancing",
+			Pos:  property.Position{Line: 1, Column: 1},
+			Nodes: Plugins{
+				{
+					ID:   "node-1.0",
+					Name: "node-1",
+					Pos:  property.Position{Line: 3, Column: 1},
+					Properties: property.Properties{
+						{Key: "name", Value: "node-1", Pos: property.Position{Line: 4, Column: 5}},
+						{Key: "host", Value: "127.0.0.1", Pos: property.Position{Line: 5, Column: 5}},
+					},
+				},
+				{
+					ID:   "node-1.1",
+					Name: "node-1",
+					Pos:  property.Position{Line: 6, Column: 1},
+					Properties: property.Properties{
+						{Key: "name", Value: "node-1", Pos: property.Position{Line: 7, Column: 5}},
+						{Key: "host", Value: "127.0.0.2", Pos: property.Position{Line: 8, Column: 5}},
+					},
+				},
+			},
+		}}, cfg.Upstreams)
+	})
+
+	t.Run("classic_node_without_upstream", func(t *testing.T) {
+		_, err := ParseAs(configLiteral(`
+			[INPUT]
+				name cpu
+			[NODE]
+				name orphan
+			[UPSTREAM]
+				name balancing
+		`), FormatClassic)
+		require.EqualError(t, err, "3: node section without a preceding upstream section")
+
+		var cfg Config
+		err = cfg.AddSection(SectionKindNode, property.Properties{{Key: "name", Value: "orphan"}})
+		require.EqualError(t, err, "node section without a preceding upstream section")
+	})
+
+	t.Run("json_positions", func(t *testing.T) {
+		cfg, err := ParseAs(configLiteral(`
+			{
+				"upstream_servers": [{
+					"name": "balancing",
+					"nodes": [{"name": "node-1"}]
+				}]
+			}
+		`), FormatJSON)
+		require.NoError(t, err)
+		require.Equal(t, property.Position{Line: 2, Column: 26}, cfg.Upstreams[0].Pos)
+		require.Equal(t, property.Position{Line: 4, Column: 19}, cfg.Upstreams[0].Nodes[0].Pos)
+		require.Equal(t, property.Position{Line: 4, Column: 20}, cfg.Upstreams[0].Nodes[0].Properties[0].Pos)
+	})
+}
+
+func TestUpstreams_FindByName(t *testing.T) {
+	upstreams := Upstreams{{Name: "a"}, {Name: "B"}}
+
+	got, ok := upstreams.FindByName("B")
+	require.True(t, ok)
+	require.Equal(t, Upstream{Name: "B"}, got)
+
+	_, ok = upstreams.FindByName("b")
+	require.False(t, ok)
+}
+
+func TestUpstreams_Validate(t *testing.T) {
+	cfg, err := ParseAs(configLiteral(`
+		[UPSTREAM]
+			name balancing
+		[NODE]
+			host 127.0.0.1
+		[NODE]
+			name node-2
+	`), FormatClassic)
+	require.NoError(t, err)
+	require.Equal(t, []string{"node-2.0"}, cfg.Upstreams[0].Nodes.IDs(), "nodes without name are dropped")
+	require.NoError(t, cfg.Upstreams.Validate())
+
+	cfg.Upstreams[0].Nodes[0].Name = ""
+	err = cfg.Upstreams.Validate()
+	require.EqualError(t, err, "5:1: upstream: balancing: node: missing name property")
+
+	var verr *ValidationError
+	require.ErrorAs(t, err, &verr)
+	require.Equal(t, property.Position{Line: 5, Column: 1}, verr.Pos)
+}
+
+func TestClassicDocument_SetConfig_upstreams(t *testing.T) {
+	const text = `[OUTPUT]
+    Name     forward
+    Upstream balancing
+
+# Balanced nodes.
+[UPSTREAM]
+    Name balancing
+
+[NODE]
+    Name node-1
+    # Primary.
+    Host 127.0.0.1
+
+[NODE]
+    Name node-2
+    Host 127.0.0.2
+`
+
+	edit := func(t *testing.T, fn func(cfg *Config)) string {
+		t.Helper()
+
+		doc, err := ParseClassicDocument([]byte(text))
+		require.NoError(t, err)
+
+		cfg := doc.Config()
+		fn(&cfg)
+		require.NoError(t, doc.SetConfig(cfg))
+
+		got, err := ParseClassicDocument(doc.Bytes())
+		require.NoError(t, err)
+		require.True(t, cfg.Equal(got.Config()), "edited document does not match config:\n%s", doc.String())
+
+		return doc.String()
+	}
+
+	t.Run("untouched", func(t *testing.T) {
+		require.Equal(t, text, edit(t, func(cfg *Config) {}))
+	})
+
+	t.Run("remove_node", func(t *testing.T) {
+		got := edit(t, func(cfg *Config) {
+			cfg.Upstreams[0].Nodes = cfg.Upstreams[0].Nodes[:1]
+		})
+		require.Equal(t, replaceOnce(t, text,
+			"    Host 127.0.0.1\n\n[NODE]\n    Name node-2\n    Host 127.0.0.2\n", "    Host 127.0.0.1\n",
+		), got)
+	})
+
+	t.Run("add_upstream", func(t *testing.T) {
+		got := edit(t, func(cfg *Config) {
+			cfg.Upstreams = append(cfg.Upstreams, Upstream{
+				Name: "other",
+				Nodes: Plugins{{
+					Name: "node-3",
+					Properties: property.Properties{
+						{Key: "Name", Value: "node-3"},
+						{Key: "Host", Value: "127.0.0.3"},
+					},
+				}},
+			})
+		})
+		require.Equal(t, text+"\n[UPSTREAM]\n    Name other\n\n[NODE]\n    Name node-3\n    Host 127.0.0.3\n", got)
+	})
+}
@@ -101,59 +101,3 @@ func fmtFloat[F float32 | float64](f F) string {
 	s = strings.TrimRight(s, ".")
 	return s
 }
-
-func int32FromAny(v any) (int32, bool) {
-	if v == nil {
-		return 0, false
-	}
-
-	switch v := v.(type) {
-	case int:
-		if int(int32(v)) == v {
-			return int32(v), true
-		}
-	case int8:
-		if int8(int32(v)) == v {
-			return int32(v), true
-		}
-	case int16:
-		if int16(int32(v)) == v {
-			return int32(v), true
-		}
-	case int32:
-		return v, true
-	case int64:
-		if int64(int32(v)) == v {
-			return int32(v), true
-		}
-	case uint:
-		if uint(int32(v)) == v {
-			return int32(v), true
-		}
-	case uint16:
-		if uint16(int32(v)) == v {
-			return int32(v), true
-		}
-	case uint32:
-		if uint32(int32(v)) == v {
-			return int32(v), true
-		}
-	case uint64:
-		if uint64(int32(v)) == v {
-			return int32(v), true
-		}
-	case float32:
-		if float32(int32(v)) == v {
-			return int32(v), true
-		}
-	case float64:
-		if float64(int32(v)) == v {
-			return int32(v), true
-		}
-	case string:
-		if i, err := strconv.ParseInt(v, 10, 32); err == nil {
-			return int32(i), true
-		}
-	}
-	return 0, false
-}
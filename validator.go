@@ -1,9 +1,9 @@
 package fluentbitconfig
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
-	"strconv"
 	"strings"
 
 	"github.com/calyptia/go-fluentbit-config/v2/property"
@@ -19,11 +19,31 @@ func (c Config) Validate() error {
 // That is if the schema says the the input plugin "cpu"
 // has a property named "pid" that is of integer type,
 // it must be a valid integer.
+// Errors found on parsed configs are wrapped in a ValidationError
+// with the position of the offending section or property.
+//
+// `${VAR}` references are expanded with the config env before validating
+// the values, and those not defined there are reported
+// as UndefinedVariableError.
+// Use Config.Expand first to also take the process environment.
 func (c Config) ValidateWithSchema(schema Schema) error {
-	validate := func(kind SectionKind, plugins Plugins) error {
+	c, err := c.Expand(nil)
+	if undefined := (*UndefinedVariableError)(nil); errors.As(err, &undefined) {
+		return withPos(undefined, undefined.Pos)
+	}
+
+	var validate func(kind SectionKind, plugins Plugins) error
+	validate = func(kind SectionKind, plugins Plugins) error {
 		for _, plugin := range plugins {
 			if err := ValidateSectionWithSchema(kind, plugin.Properties, schema); err != nil {
-				return err
+				return withPos(err, plugin.Pos)
+			}
+
+			for _, signal := range processorSignals {
+				processors, _ := plugin.Processors.Signal(signal)
+				if err := validate(SectionKindProcessor, *processors); err != nil {
+					return err
+				}
 			}
 		}
 
@@ -39,9 +59,9 @@ func (c Config) ValidateWithSchema(schema Schema) error {
 			case float64:
 			case string:
 			default:
-				return fmt.Errorf("invalid type (%T) for service setting: %s",
+				return withPos(fmt.Errorf("invalid type (%T) for service setting: %s",
 					property.Value,
-					property.Key)
+					property.Key), property.Pos)
 			}
 		}
 		return nil
@@ -67,10 +87,103 @@ func (c Config) ValidateWithSchema(schema Schema) error {
 		return err
 	}
 
+	if err := c.validateIDs(); err != nil {
+		return err
+	}
+
+	if err := c.Upstreams.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.StreamTasks.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.validateUpstreamReferences(); err != nil {
+		return err
+	}
+
 	// valid by default
 	return nil
 }
 
+// validateIDs checks that explicit IDs and aliases are unique across the config,
+// and that plugin IDs are unique within their section kind and name.
+func (c Config) validateIDs() error {
+	explicit := map[string]bool{}
+	aliases := map[string]bool{}
+	ids := map[string]bool{}
+	for _, ref := range c.pluginRefs() {
+		if id := ExplicitID(ref.Plugin.Properties); id != "" {
+			if explicit[id] {
+				return withPos(fmt.Errorf("%s: %s: duplicated ID %q", ref.Kind, ref.Plugin.Name, id), ref.Plugin.Pos)
+			}
+			explicit[id] = true
+		}
+
+		if alias := Alias(ref.Plugin.Properties); alias != "" {
+			if aliases[alias] {
+				return withPos(fmt.Errorf("%s: %s: duplicated alias %q", ref.Kind, ref.Plugin.Name, alias), ref.Plugin.Pos)
+			}
+			aliases[alias] = true
+		}
+
+		if ids[ref.ID] {
+			return withPos(fmt.Errorf("%s: %s: duplicated ID %q", ref.Kind, ref.Plugin.Name, ref.Plugin.ID), ref.Plugin.Pos)
+		}
+		ids[ref.ID] = true
+	}
+
+	return nil
+}
+
+// validateUpstreamReferences checks that the `upstream` property of outputs
+// names a defined upstream.
+// Upstream files are not checked here as LoadFile already fails
+// when they cannot be loaded.
+func (c Config) validateUpstreamReferences() error {
+	for _, plugin := range c.Pipeline.Outputs {
+		for _, p := range plugin.Properties {
+			if !strings.EqualFold(p.Key, "upstream") {
+				continue
+			}
+
+			name, ok := p.Value.(string)
+			if !ok || isCloudVariable(name) || c.upstreamFileRef(plugin, name) {
+				continue
+			}
+
+			if _, ok := c.Upstreams.FindByName(name); !ok {
+				return withPos(fmt.Errorf("%s: %s: upstream %q not defined", SectionKindOutput, plugin.Name, name), p.Pos)
+			}
+		}
+	}
+
+	return nil
+}
+
+// unexpandedVariable returns an UndefinedVariableError
+// for the first `${VAR}` reference found on the property value.
+func unexpandedVariable(kind SectionKind, name string, p property.Property) error {
+	var found string
+	expandValue(p.Value, func(variable string) string {
+		if found == "" {
+			found = variable
+		}
+		return ""
+	})
+	if found == "" {
+		return nil
+	}
+
+	return withPos(&UndefinedVariableError{
+		Name:    found,
+		Section: fmt.Sprintf("%s:%s", kind, name),
+		Key:     p.Key,
+		Pos:     p.Pos,
+	}, p.Pos)
+}
+
 func ValidateSection(kind SectionKind, props property.Properties) error {
 	return ValidateSectionWithSchema(kind, props, DefaultSchema)
 }
@@ -95,30 +208,37 @@ func ValidateSectionWithSchema(kind SectionKind, props property.Properties, sche
 		return nil
 	}
 
+	// `${VAR}` references must be expanded with Config.Expand first.
+	for _, p := range props {
+		if err := unexpandedVariable(kind, name, p); err != nil {
+			return err
+		}
+	}
+
 	section, ok := schema.findSection(kind, name)
 	if !ok {
 		return NewUnknownPluginError(kind, name)
 	}
 
 	for _, p := range props {
-		if isCommonProperty(p.Key) || isCloudVariable(p.Key) || isCloudVariable(p.Value) || isCoreProperty(p.Key) {
+		if isCommonProperty(p.Key) || strings.EqualFold(p.Key, IDKey) || isCloudVariable(p.Key) || isCloudVariable(p.Value) || isCoreProperty(p.Key) {
 			continue
 		}
 
 		if areProcessors(p.Key) {
 			if err := validateProcessors(p.Value); err != nil {
-				return err
+				return withPos(err, p.Pos)
 			}
 			continue
 		}
 
 		opts, ok := section.findOptions(p.Key)
 		if !ok {
-			return fmt.Errorf("%s: %s: unknown property %q", kind, name, p.Key)
+			return withPos(fmt.Errorf("%s: %s: unknown property %q", kind, name, p.Key), p.Pos)
 		}
 
 		if !valid(opts, p.Value) {
-			return fmt.Errorf("%s: %s: expected %q to be a valid %s, got %v", kind, name, p.Key, opts.Type, p.Value)
+			return withPos(fmt.Errorf("%s: %s: expected %q to be a valid %s, got %v", kind, name, p.Key, opts.Type, p.Value), p.Pos)
 		}
 	}
 
@@ -135,8 +255,8 @@ func isCommonProperty(name string) bool {
 }
 
 var (
-	// reCloudSecretVariable matches `{{ secrets.example }}`
-	reCloudSecretVariable = regexp.MustCompile(`{{\s*secrets\.\w+\s*}}`)
+	// reCloudSecretVariable matches `{{ secrets.example }}` or `{{ secrets.api-key }}`
+	reCloudSecretVariable = regexp.MustCompile(`{{\s*secrets\.[\w-]+\s*}}`)
 	// reCloudFileVariable matches `{{ files.example }}`
 	reCloudFileVariable = regexp.MustCompile(`{{\s*files\.[0-9A-Za-z]+(?:-[A-Za-z]{3,4}|)+\s*}}`)
 )
@@ -162,39 +282,23 @@ func isCoreProperty(key string) bool {
 	return strings.HasPrefix(key, "core.")
 }
 
-func validateProcessorsSectionMaps(sectionMaps []interface{}) error {
-	for _, section := range sectionMaps {
-		props := property.Properties{}
-		for key, val := range section.(map[string]interface{}) {
-			props.Set(key, val)
-		}
-		if err := ValidateSection(SectionKindProcessor, props); err != nil {
-			return err
-		}
-
+// validateProcessors validates a raw `processors` property value,
+// as found on properties not decoded into a Plugin.
+func validateProcessors(value any) error {
+	processors, err := processorsFromValue(value)
+	if err != nil {
+		return err
 	}
-	return nil
-}
 
-func validateProcessors(processors any) error {
-	if procMap, ok := processors.(map[string]interface{}); !ok {
-		return fmt.Errorf("not a list of processors")
-	} else {
-		for key, sections := range procMap {
-			sectionMaps, ok := sections.([]interface{})
-			if !ok {
-				return fmt.Errorf("not a list of processors")
-			}
-			switch key {
-			case "logs", "metrics", "traces":
-				if err := validateProcessorsSectionMaps(sectionMaps); err != nil {
-					return err
-				}
-			default:
-				return fmt.Errorf("unknown processor type: %s", key)
+	for _, signal := range processorSignals {
+		plugins, _ := processors.Signal(signal)
+		for _, plugin := range *plugins {
+			if err := ValidateSection(SectionKindProcessor, plugin.Properties); err != nil {
+				return err
 			}
 		}
 	}
+
 	return nil
 }
 
@@ -261,78 +365,28 @@ func valid(opts SchemaOptions, val any) bool {
 }
 
 func validBoolean(val any) bool {
-	if s, ok := val.(string); ok {
-		return strings.EqualFold(s, "true") ||
-			strings.EqualFold(s, "false") ||
-			strings.EqualFold(s, "on") ||
-			strings.EqualFold(s, "off")
-	}
-
-	_, ok := val.(bool)
+	_, ok := property.ParseBool(val)
 	return ok
 }
 
 func validInteger(val any) bool {
-	if s, ok := val.(string); ok {
-		if _, err := strconv.ParseInt(s, 10, 64); err == nil {
-			return true
-		}
-
-		_, err := strconv.ParseUint(s, 10, 64)
-		return err == nil
-	}
-
-	switch val.(type) {
-	case int,
-		int8, int16, int32, int64,
-		uint8, uint16, uint32, uint64:
-		return true
-	}
-
-	return false
+	_, ok := property.ParseInt(val)
+	return ok
 }
 
 func validDouble(val any) bool {
-	if s, ok := val.(string); ok {
-		_, err := strconv.ParseFloat(s, 64)
-		return err == nil
-	}
-
-	switch val.(type) {
-	case float32, float64:
-		return true
-	}
-
-	return false
+	_, ok := property.ParseFloat(val)
+	return ok
 }
 
 func validTime(val any) bool {
-	if validInteger(val) || validDouble(val) {
-		return true
-	}
-
-	// TODO: stricter time validator once we know the full rules.
-	if s, ok := val.(string); ok {
-		return s != ""
-	}
-
-	return false
+	_, ok := property.ParseSeconds(val)
+	return ok
 }
 
-// reByteSize example -45.32kB
-var reByteSize = regexp.MustCompile(`^(?:\+|-)?[0-9]+(?:\.?[0-9]*)\s?(?:(?:k|K)|(?:m|M)|(?:g|G))?(?:b|B)?$`)
-
 func validByteSize(val any) bool {
-	// numeric bytes without unit.
-	if validInteger(val) || validDouble(val) {
-		return true
-	}
-
-	if s, ok := val.(string); ok {
-		return reByteSize.MatchString(s)
-	}
-
-	return false
+	_, ok := property.ParseSize(val)
+	return ok
 }
 
 func validSpaceDelimitedString(val any, min int) bool {
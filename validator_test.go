@@ -2,10 +2,13 @@ package fluentbitconfig
 
 import (
 	_ "embed"
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v3"
+
+	"github.com/calyptia/go-fluentbit-config/v2/property"
 )
 
 func TestConfig_Validate(t *testing.T) {
@@ -31,7 +34,7 @@ func TestConfig_Validate(t *testing.T) {
 					Name cpu
 					pid  3.4
 			`,
-			want: `input: cpu: expected "pid" to be a valid integer, got 3.4`,
+			want: `4:6: input: cpu: expected "pid" to be a valid integer, got 3.4`,
 		},
 		{
 			name: "input_cpu_pid_bool",
@@ -40,7 +43,7 @@ func TestConfig_Validate(t *testing.T) {
 					Name cpu
 					pid  true
 			`,
-			want: `input: cpu: expected "pid" to be a valid integer, got true`,
+			want: `4:6: input: cpu: expected "pid" to be a valid integer, got true`,
 		},
 		{
 			name: "input_cpu_pid_bytes",
@@ -49,7 +52,7 @@ func TestConfig_Validate(t *testing.T) {
 					Name cpu
 					pid  1024k
 			`,
-			want: `input: cpu: expected "pid" to be a valid integer, got 1024k`,
+			want: `4:6: input: cpu: expected "pid" to be a valid integer, got 1024k`,
 		},
 		{
 			name: "input_cpu_pid_string",
@@ -58,7 +61,7 @@ func TestConfig_Validate(t *testing.T) {
 					Name cpu
 					pid  foo
 			`,
-			want: `input: cpu: expected "pid" to be a valid integer, got foo`,
+			want: `4:6: input: cpu: expected "pid" to be a valid integer, got foo`,
 		},
 		{
 			name: "input_cpu_pid_integer",
@@ -75,7 +78,7 @@ func TestConfig_Validate(t *testing.T) {
 					Name        tail
 					docker_mode foo
 			`,
-			want: `input: tail: expected "docker_mode" to be a valid boolean, got foo`,
+			want: `4:6: input: tail: expected "docker_mode" to be a valid boolean, got foo`,
 		},
 		{
 			name: "input_tail_docker_mode_integer",
@@ -84,7 +87,7 @@ func TestConfig_Validate(t *testing.T) {
 					Name        tail
 					docker_mode 5
 			`,
-			want: `input: tail: expected "docker_mode" to be a valid boolean, got 5`,
+			want: `4:6: input: tail: expected "docker_mode" to be a valid boolean, got 5`,
 		},
 		{
 			name: "input_tail_docker_mode_boolean",
@@ -101,7 +104,6 @@ func TestConfig_Validate(t *testing.T) {
 					Name throttle
 					rate 5
 			`,
-			want: `filter: throttle: expected "rate" to be a valid double, got 5`,
 		},
 		{
 			name: "filter_throttle_rate_string",
@@ -110,7 +112,7 @@ func TestConfig_Validate(t *testing.T) {
 					Name throttle
 					rate foo
 			`,
-			want: `filter: throttle: expected "rate" to be a valid double, got foo`,
+			want: `4:6: filter: throttle: expected "rate" to be a valid double, got foo`,
 		},
 		{
 			name: "filter_throttle_rate_double",
@@ -127,7 +129,7 @@ func TestConfig_Validate(t *testing.T) {
 					Name syslog
 					syslog_maxsize foo
 			`,
-			want: `output: syslog: expected "syslog_maxsize" to be a valid size, got foo`,
+			want: `4:6: output: syslog: expected "syslog_maxsize" to be a valid size, got foo`,
 		},
 		{
 			name: "output_syslog_syslog_maxsize_bool",
@@ -136,7 +138,7 @@ func TestConfig_Validate(t *testing.T) {
 					Name syslog
 					syslog_maxsize true
 			`,
-			want: `output: syslog: expected "syslog_maxsize" to be a valid size, got true`,
+			want: `4:6: output: syslog: expected "syslog_maxsize" to be a valid size, got true`,
 		},
 		{
 			name: "output_syslog_syslog_maxsize_no_unit",
@@ -169,7 +171,7 @@ func TestConfig_Validate(t *testing.T) {
 					Name syslog
 					syslog_maxsize 5 bytes
 			`,
-			want: `output: syslog: expected "syslog_maxsize" to be a valid size, got 5 bytes`,
+			want: `4:6: output: syslog: expected "syslog_maxsize" to be a valid size, got 5 bytes`,
 		},
 		{
 			name: "filter_record_modifier_record_space_delimited_strings_2",
@@ -178,7 +180,7 @@ func TestConfig_Validate(t *testing.T) {
 					Name record_modifier
 					record foo
 			`,
-			want: `filter: record_modifier: expected "record" to be a valid space delimited strings (minimum 2), got foo`,
+			want: `4:6: filter: record_modifier: expected "record" to be a valid space delimited strings (minimum 2), got foo`,
 		},
 		{
 			name: "filter_record_modifier_record_space_delimited_strings_2",
@@ -245,7 +247,7 @@ func TestConfig_Validate(t *testing.T) {
 				[INPUT]
 					Name nope
 			`,
-			want: `input: unknown plugin "nope"`,
+			want: `2:5: input: unknown plugin "nope"`,
 		},
 		{
 			name: "lts_gsuite_reporter_unknown_property",
@@ -254,7 +256,7 @@ func TestConfig_Validate(t *testing.T) {
 					Name gsuite-reporter
 					nope test
 			`,
-			want: `input: gsuite-reporter: unknown property "nope"`,
+			want: `4:6: input: gsuite-reporter: unknown property "nope"`,
 		},
 		{
 			name: "lts_gsuite_reporter_pull_interval",
@@ -343,7 +345,7 @@ func TestConfig_Validate(t *testing.T) {
 					Name sqldb
 					nope test
 			`,
-			want: `input: sqldb: unknown property "nope"`,
+			want: `4:6: input: sqldb: unknown property "nope"`,
 		},
 		{
 			name: "in_sqldb_ok",
@@ -355,6 +357,125 @@ func TestConfig_Validate(t *testing.T) {
 					query SELECT 'hello from sqldb' AS message
 			`,
 		},
+		{
+			name: "out_forward_upstream_ok",
+			ini: `
+				[OUTPUT]
+					Name     forward
+					Upstream balancing
+				[UPSTREAM]
+					Name balancing
+				[NODE]
+					Name node-1
+					Host 127.0.0.1
+			`,
+		},
+		{
+			name: "out_forward_upstream_file",
+			ini: `
+				[OUTPUT]
+					Name     forward
+					Upstream upstream.conf
+			`,
+		},
+		{
+			name: "out_forward_upstream_relative_file",
+			ini: `
+				[OUTPUT]
+					Name     forward
+					Upstream upstream_nodes
+			`,
+		},
+		{
+			name: "out_forward_upstream_not_defined",
+			ini: `
+				[OUTPUT]
+					Name     forward
+					Upstream nope
+				[UPSTREAM]
+					Name balancing
+				[NODE]
+					Name node-1
+					Host 127.0.0.1
+			`,
+			want: `4:6: output: forward: upstream "nope" not defined`,
+		},
+		{
+			name: "upstream_without_nodes",
+			ini: `
+				[UPSTREAM]
+					Name balancing
+			`,
+			want: `2:5: upstream: balancing: no nodes defined`,
+		},
+		{
+			name: "upstream_duplicated",
+			ini: `
+				[UPSTREAM]
+					Name balancing
+				[NODE]
+					Name node-1
+				[UPSTREAM]
+					Name balancing
+				[NODE]
+					Name node-2
+			`,
+			want: `6:5: upstream: duplicated name "balancing"`,
+		},
+		{
+			name: "duplicated_alias",
+			ini: `
+				[INPUT]
+					Name  dummy
+					Alias source
+				[OUTPUT]
+					Name  stdout
+					Alias source
+			`,
+			want: `5:5: output: stdout: duplicated alias "source"`,
+		},
+		{
+			name: "duplicated_id",
+			ini: `
+				[INPUT]
+					Name  dummy
+					Alias dummy.1
+				[INPUT]
+					Name  dummy
+			`,
+			want: `5:5: input: dummy: duplicated ID "dummy.1"`,
+		},
+		{
+			name: "duplicated_explicit_id",
+			ini: `
+				[INPUT]
+					Name dummy
+					$id  source
+				[OUTPUT]
+					Name stdout
+					$id  source
+			`,
+			want: `5:5: output: stdout: duplicated ID "source"`,
+		},
+		{
+			name: "in_tail_boolean_yes",
+			ini: `
+				[INPUT]
+					Name            tail
+					Path            /var/log/app.log
+					Skip_Long_Lines yes
+			`,
+		},
+		{
+			name: "in_tail_time_invalid",
+			ini: `
+				[INPUT]
+					Name        tail
+					Path        /var/log/app.log
+					Rotate_Wait soon
+			`,
+			want: `5:6: input: tail: expected "Rotate_Wait" to be a valid time, got soon`,
+		},
 		{
 			name: "custom_core_property",
 			ini: `
@@ -411,7 +532,7 @@ func TestConfig_Validate_Schema_YAML(t *testing.T) {
 						- one
 						- two
 			`),
-			want: "invalid type ([]interface {}) for service setting: parsers",
+			want: "2:5: invalid type ([]interface {}) for service setting: parsers",
 		},
 		// fluent-bit does not support using mappings under
 		// the service section under any circumstance.
@@ -422,7 +543,7 @@ func TestConfig_Validate_Schema_YAML(t *testing.T) {
 					headers:
 						one: two
 			`),
-			want: "invalid type (map[string]interface {}) for service setting: headers",
+			want: "2:5: invalid type (map[string]interface {}) for service setting: headers",
 		},
 		// it does support booleans
 		{
@@ -484,7 +605,7 @@ func TestConfig_Validate_Schema_YAML(t *testing.T) {
 					- name: aws_kinesis_stream
 					  incorrect_key: foo
 			`),
-			want: "input: aws_kinesis_stream: unknown property \"incorrect_key\"",
+			want: "4:11: input: aws_kinesis_stream: unknown property \"incorrect_key\"",
 		},
 		{
 			name: "input_azeventgrid_correct",
@@ -507,7 +628,7 @@ func TestConfig_Validate_Schema_YAML(t *testing.T) {
 					- name: azeventgrid
 					  incorrect_key: foo
 			`),
-			want: "input: azeventgrid: unknown property \"incorrect_key\"",
+			want: "4:11: input: azeventgrid: unknown property \"incorrect_key\"",
 		},
 		{
 			name: "input_azure_blob_input_correct",
@@ -532,7 +653,7 @@ func TestConfig_Validate_Schema_YAML(t *testing.T) {
 					- name: azure-blob-input
 					  incorrect_key: foo
 			`),
-			want: "input: azure-blob-input: unknown property \"incorrect_key\"",
+			want: "4:11: input: azure-blob-input: unknown property \"incorrect_key\"",
 		},
 		{
 			name: "input_cloudflare_correct",
@@ -567,7 +688,7 @@ func TestConfig_Validate_Schema_YAML(t *testing.T) {
 					- name: cloudflare
 					  incorrect_key: foo
 			`),
-			want: "input: cloudflare: unknown property \"incorrect_key\"",
+			want: "4:11: input: cloudflare: unknown property \"incorrect_key\"",
 		},
 		{
 			name: "input_datagen_correct",
@@ -588,7 +709,7 @@ func TestConfig_Validate_Schema_YAML(t *testing.T) {
 					- name: datagen
 					  incorrect_key: foo
 			`),
-			want: "input: datagen: unknown property \"incorrect_key\"",
+			want: "4:11: input: datagen: unknown property \"incorrect_key\"",
 		},
 		{
 			name: "input_gdummy_correct",
@@ -607,7 +728,7 @@ func TestConfig_Validate_Schema_YAML(t *testing.T) {
 					- name: gdummy
 					  incorrect_key: foo
 			`),
-			want: "input: gdummy: unknown property \"incorrect_key\"",
+			want: "4:11: input: gdummy: unknown property \"incorrect_key\"",
 		},
 		{
 			name: "input_go-s3-replay-plugin_correct",
@@ -638,7 +759,7 @@ func TestConfig_Validate_Schema_YAML(t *testing.T) {
 					- name: go-s3-replay-plugin
 					  incorrect_key: foo
 			`),
-			want: "input: go-s3-replay-plugin: unknown property \"incorrect_key\"",
+			want: "4:11: input: go-s3-replay-plugin: unknown property \"incorrect_key\"",
 		},
 		{
 			name: "input_gsuite-reporter_correct",
@@ -665,7 +786,7 @@ func TestConfig_Validate_Schema_YAML(t *testing.T) {
 					- name: gsuite-reporter
 					  incorrect_key: foo
 			`),
-			want: "input: gsuite-reporter: unknown property \"incorrect_key\"",
+			want: "4:11: input: gsuite-reporter: unknown property \"incorrect_key\"",
 		},
 		{
 			name: "input_http_loader_correct",
@@ -718,7 +839,7 @@ func TestConfig_Validate_Schema_YAML(t *testing.T) {
 					- name: http_loader
 					  incorrect_key: foo
 			`),
-			want: "input: http_loader: unknown property \"incorrect_key\"",
+			want: "4:11: input: http_loader: unknown property \"incorrect_key\"",
 		},
 		{
 			name: "input_http_scraper_correct",
@@ -753,7 +874,7 @@ func TestConfig_Validate_Schema_YAML(t *testing.T) {
 					- name: http_scraper
 					  incorrect_key: foo
 			`),
-			want: "input: http_scraper: unknown property \"incorrect_key\"",
+			want: "4:11: input: http_scraper: unknown property \"incorrect_key\"",
 		},
 		{
 			name: "input_s3_sqs_correct",
@@ -794,7 +915,7 @@ func TestConfig_Validate_Schema_YAML(t *testing.T) {
 					- name: s3_sqs
 					  incorrect_key: foo
 			`),
-			want: "input: s3_sqs: unknown property \"incorrect_key\"",
+			want: "4:11: input: s3_sqs: unknown property \"incorrect_key\"",
 		},
 
 		{
@@ -822,7 +943,7 @@ func TestConfig_Validate_Schema_YAML(t *testing.T) {
 					- name: sqldb
 					  incorrect_key: foo
 			`),
-			want: "input: sqldb: unknown property \"incorrect_key\"",
+			want: "4:11: input: sqldb: unknown property \"incorrect_key\"",
 		},
 	}
 	for _, tc := range tt {
@@ -854,6 +975,28 @@ func TestConfig_Validate_Schema_YAML(t *testing.T) {
 	}
 }
 
+func TestConfig_Validate_JSONNumbers(t *testing.T) {
+	cfg, err := ParseAs(`{"pipeline": {
+		"inputs": [{"name": "cpu", "interval_sec": 5}],
+		"filters": [{"name": "throttle", "rate": 5}]
+	}}`, FormatJSON)
+	require.NoError(t, err)
+	require.NoError(t, cfg.Validate())
+
+	// Numbers decoded by encoding/json into any are always float64.
+	var values map[string]any
+	err = json.Unmarshal([]byte(`{"interval_sec": 5, "rate": 2.5, "pid": 3.4}`), &values)
+	require.NoError(t, err)
+
+	var decoded Config
+	decoded.AddSection(SectionKindInput, property.Properties{{Key: "name", Value: "cpu"}, {Key: "interval_sec", Value: values["interval_sec"]}})
+	decoded.AddSection(SectionKindFilter, property.Properties{{Key: "name", Value: "throttle"}, {Key: "rate", Value: values["rate"]}})
+	require.NoError(t, decoded.Validate())
+
+	decoded.AddSection(SectionKindInput, property.Properties{{Key: "name", Value: "cpu"}, {Key: "pid", Value: values["pid"]}})
+	require.EqualError(t, decoded.Validate(), `input: cpu: expected "pid" to be a valid integer, got 3.4`)
+}
+
 func TestConfig_Validate_SchemaWithVersion(t *testing.T) {
 	// Default Schema
 	t.Run("default_schema", func(t *testing.T) {